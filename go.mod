@@ -20,6 +20,7 @@ go 1.26.1
 // LINT.ThenChange(scripts/tests.sh:goversion, .github/workflows/test.yml:test_goversion, .github/workflows/test.yml:lint_goversion, .github/workflows/test.yml:codeql_goversion, scripts/generate.sh:goversion)
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/go-git/go-git/v5 v5.19.1
 	github.com/google/go-cmp v0.7.0
 	github.com/mattn/go-colorable v0.1.13
@@ -30,6 +31,7 @@ require (
 	go.starlark.net v0.0.0-20250804182900-3c9dc17c5f2e
 	golang.org/x/mod v0.34.0
 	golang.org/x/sync v0.20.0
+	golang.org/x/sys v0.43.0
 	golang.org/x/tools v0.43.0
 	google.golang.org/protobuf v1.36.11
 )
@@ -43,7 +45,6 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0 // indirect
 	github.com/klauspost/compress v1.18.0 // indirect
 	golang.org/x/net v0.53.0 // indirect
-	golang.org/x/sys v0.43.0 // indirect
 	golang.org/x/text v0.36.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20260406210006-6f92a3bedf2d // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20260406210006-6f92a3bedf2d // indirect
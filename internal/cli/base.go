@@ -16,6 +16,7 @@ package cli
 
 import (
 	"errors"
+	"time"
 
 	flag "github.com/spf13/pflag"
 	"go.fuchsia.dev/shac-project/shac/internal/engine"
@@ -26,18 +27,43 @@ type commandBase struct {
 	allFiles   bool
 	entryPoint string
 	noRecurse  bool
+	noCache    bool
+	pkgCache   string
+	base       string
+	mergeBase  bool
+	timeout    time.Duration
+	keepGoing  bool
+	shardIndex int
+	shardCount int
 	allowList  []string
 	denyList   []string
+	allowTags  []string
+	denyTags   []string
 	vars       stringMapFlag
+	verbose    bool
+}
+
+func (c *commandBase) setVerbose(v bool) {
+	c.verbose = v
 }
 
 func (c *commandBase) SetFlags(f *flag.FlagSet) {
 	f.StringVarP(&c.cwd, "cwd", "C", ".", "directory in which to run shac")
 	f.BoolVar(&c.allFiles, "all", false, "checks all the files instead of guess the upstream to diff against")
 	f.BoolVar(&c.noRecurse, "no-recurse", false, "do not look for shac.star files recursively")
+	f.BoolVar(&c.noCache, "no-cache", false, "do not use the check results cache configured in shac.textproto")
+	f.StringVar(&c.pkgCache, "pkg-cache", "", "directory of the persistent package cache; defaults to $SHAC_PKG_CACHE or the user cache directory")
+	f.StringVar(&c.base, "base", "", "revision to compute the affected files against; defaults to the upstream")
+	f.BoolVar(&c.mergeBase, "merge-base", false, "compute the affected files against the merge base of HEAD and the base")
+	f.DurationVar(&c.timeout, "timeout", 0, "maximum duration of each check that doesn't set its own timeout; 0 means no timeout")
+	f.BoolVar(&c.keepGoing, "keep-going", false, "run all checks to completion even if some fail abnormally, then report every failure")
+	f.IntVar(&c.shardIndex, "shard-index", 0, "index of the shard to run, in [0, --shard-count)")
+	f.IntVar(&c.shardCount, "shard-count", 0, "number of shards to partition the checks and files into; 0 disables sharding")
 	f.StringVar(&c.entryPoint, "entrypoint", engine.DefaultEntryPoint, "basename of Starlark files to run")
-	f.StringSliceVar(&c.allowList, "only", nil, "comma-separated allowlist of checks to run; by default all checks are run")
-	f.StringSliceVar(&c.denyList, "skip", nil, "comma-separated denylist of checks to skip; by default all checks are run")
+	f.StringSliceVar(&c.allowList, "only", nil, "comma-separated allowlist of checks or glob patterns of checks to run; by default all checks are run")
+	f.StringSliceVar(&c.denyList, "skip", nil, "comma-separated denylist of checks or glob patterns of checks to skip; by default all checks are run")
+	f.StringSliceVar(&c.allowTags, "only-tag", nil, "comma-separated tags of checks to run, in addition to the checks selected with --only")
+	f.StringSliceVar(&c.denyTags, "skip-tag", nil, "comma-separated tags of checks to skip")
 	c.vars = stringMapFlag{}
 	f.Var(&c.vars, "var", "runtime variables to set, of the form key=value")
 }
@@ -46,16 +72,30 @@ func (c *commandBase) options(files []string) (engine.Options, error) {
 	if c.allFiles && len(files) > 0 {
 		return engine.Options{}, errors.New("--all cannot be set together with positional file arguments")
 	}
+	if c.timeout < 0 {
+		return engine.Options{}, errors.New("--timeout must not be negative")
+	}
 	return engine.Options{
-		Dir:        c.cwd,
-		AllFiles:   c.allFiles,
-		Files:      files,
-		Recurse:    !c.noRecurse,
-		Vars:       c.vars,
-		EntryPoint: c.entryPoint,
+		Dir:          c.cwd,
+		AllFiles:     c.allFiles,
+		Files:        files,
+		Recurse:      !c.noRecurse,
+		Vars:         c.vars,
+		EntryPoint:   c.entryPoint,
+		NoCache:      c.noCache,
+		PackageCache: c.pkgCache,
+		Base:         c.base,
+		MergeBase:    c.mergeBase,
+		Timeout:      c.timeout,
+		KeepGoing:    c.keepGoing,
+		ShardIndex:   c.shardIndex,
+		ShardCount:   c.shardCount,
+		Verbose:      c.verbose,
 		Filter: engine.CheckFilter{
 			AllowList: c.allowList,
 			DenyList:  c.denyList,
+			AllowTags: c.allowTags,
+			DenyTags:  c.denyTags,
 		},
 	}, nil
 }
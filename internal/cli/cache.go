@@ -0,0 +1,61 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
+)
+
+type cacheCmd struct {
+	pkgCache string
+	maxAge   time.Duration
+}
+
+func (*cacheCmd) Name() string {
+	return "cache"
+}
+
+func (*cacheCmd) Description() string {
+	return "Manage the persistent package cache: gc removes the packages not used recently."
+}
+
+func (c *cacheCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.pkgCache, "pkg-cache", "", "directory of the persistent package cache; defaults to $SHAC_PKG_CACHE or the user cache directory")
+	f.DurationVar(&c.maxAge, "max-age", 30*24*time.Hour, "remove the packages not used for this long")
+}
+
+func (c *cacheCmd) Execute(ctx context.Context, args []string) error {
+	if len(args) != 1 || args[0] != "gc" {
+		return errors.New("cache requires a command: gc")
+	}
+	if c.maxAge < 0 {
+		return errors.New("--max-age must not be negative")
+	}
+	dir := c.pkgCache
+	if dir == "" {
+		var err error
+		if dir, err = engine.DefaultPackageCache(); err != nil {
+			return fmt.Errorf("no default package cache: %w", err)
+		}
+	}
+	return engine.GCPackageCache(dir, c.maxAge, os.Stderr)
+}
@@ -17,6 +17,8 @@ package cli
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 
 	flag "github.com/spf13/pflag"
@@ -26,7 +28,14 @@ import (
 
 type checkCmd struct {
 	commandBase
-	jsonOutput string
+	jsonOutput         string
+	trace              string
+	watch              bool
+	baseline           string
+	writeBaseline      string
+	unusedSuppressions engine.Level
+	failOn             string
+	minLevel           engine.Level
 }
 
 func (*checkCmd) Name() string {
@@ -40,33 +49,120 @@ func (*checkCmd) Description() string {
 func (c *checkCmd) SetFlags(f *flag.FlagSet) {
 	c.commandBase.SetFlags(f)
 	f.StringVar(&c.jsonOutput, "json-output", "", "path to write SARIF output to")
+	f.StringVar(&c.trace, "trace", "", "path to write a Chrome trace event JSON of the run to, to open in chrome://tracing or Perfetto")
+	f.BoolVar(&c.watch, "watch", false, "run checks again every time files are modified")
+	f.StringVar(&c.baseline, "baseline", "", "path to a baseline file of known findings to not report")
+	f.StringVar(&c.writeBaseline, "write-baseline", "", "path to write a baseline file of all the findings to")
+	f.Var(&c.unusedSuppressions, "unused-suppressions", "level of the finding to emit for shac:ignore directives that suppress nothing: notice, warning or error; by default they are not flagged")
+	f.StringVar(&c.failOn, "fail-on", "error", "lowest level of finding that makes shac exit with an error: warning, error or never")
+	c.minLevel = engine.Notice
+	f.Var(&c.minLevel, "min-level", "lowest level of finding to print: notice, warning or error; the SARIF output still contains all the findings")
+}
+
+// checkOptions returns the options shared by check and check --watch.
+func (c *checkCmd) checkOptions(files []string) (engine.Options, error) {
+	o, err := c.options(files)
+	if err != nil {
+		return o, err
+	}
+	o.UnusedSuppressions = c.unusedSuppressions
+	switch c.failOn {
+	case "warning", "error":
+		o.FailOn = engine.Level(c.failOn)
+	case "never":
+	default:
+		return o, fmt.Errorf("invalid --fail-on value %q", c.failOn)
+	}
+	if c.baseline != "" {
+		if o.Baseline, err = engine.LoadBaseline(c.baseline); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
 }
 
 func (c *checkCmd) Execute(ctx context.Context, files []string) error {
+	if c.watch {
+		return c.executeWatch(ctx, files)
+	}
 	var buf bytes.Buffer
 
-	r, err := reporting.Get(ctx)
+	r, err := reporting.Get(ctx, c.minLevel)
 	if err != nil {
 		return err
 	}
 	r.Reporters = append(r.Reporters, &reporting.SarifReport{Out: &buf})
 
-	o, err := c.options(files)
+	o, err := c.checkOptions(files)
 	if err != nil {
 		return err
 	}
 	o.Report = r
+	var traceBuf bytes.Buffer
+	if c.trace != "" {
+		o.Trace = &traceBuf
+	}
+	if c.writeBaseline != "" {
+		o.RecordBaseline = &engine.Baseline{}
+	}
 
 	err = engine.Run(ctx, &o)
 	if err2 := r.Close(); err == nil {
 		err = err2
 	}
+	if n := r.Suppressed(); n != 0 {
+		fmt.Fprintf(os.Stderr, "%d findings suppressed by shac:ignore directives\n", n)
+	}
+	if o.Baseline != nil {
+		st := o.Baseline.Stats()
+		fmt.Fprintf(os.Stderr, "baseline: %d findings suppressed, %d entries fixed, %d entries stale\n", st.Suppressed, st.Fixed, st.Stale)
+	}
+	// Only save a baseline if all the checks ran to completion, otherwise it
+	// would be missing findings.
+	if c.writeBaseline != "" && (err == nil || errors.Is(err, engine.ErrCheckFailed)) {
+		if err2 := o.RecordBaseline.Save(c.writeBaseline); err == nil {
+			err = err2
+		}
+	}
 
 	if c.jsonOutput != "" {
 		if err2 := os.WriteFile(c.jsonOutput, buf.Bytes(), 0o600); err == nil {
 			err = err2
 		}
 	}
+	if c.trace != "" {
+		if err2 := os.WriteFile(c.trace, traceBuf.Bytes(), 0o600); err == nil {
+			err = err2
+		}
+	}
+	if c.failOn == "never" && errors.Is(err, engine.ErrCheckFailed) {
+		err = nil
+	}
+	return err
+}
 
+func (c *checkCmd) executeWatch(ctx context.Context, files []string) error {
+	if c.jsonOutput != "" {
+		return errors.New("--json-output cannot be set together with --watch")
+	}
+	if c.trace != "" {
+		return errors.New("--trace cannot be set together with --watch")
+	}
+	if c.writeBaseline != "" {
+		return errors.New("--write-baseline cannot be set together with --watch")
+	}
+	r, err := reporting.Get(ctx, c.minLevel)
+	if err != nil {
+		return err
+	}
+	o, err := c.checkOptions(files)
+	if err != nil {
+		return err
+	}
+	o.Report = r
+	err = engine.Watch(ctx, &o, os.Stderr)
+	if err2 := r.Close(); err == nil {
+		err = err2
+	}
 	return err
 }
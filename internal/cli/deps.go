@@ -0,0 +1,100 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
+)
+
+type depsCmd struct {
+	cwd      string
+	alias    string
+	force    bool
+	pkgCache string
+}
+
+func (*depsCmd) Name() string {
+	return "deps"
+}
+
+func (*depsCmd) Description() string {
+	return "Manage the packages in shac.textproto: add <url>@<version>, update, verify or graph."
+}
+
+func (c *depsCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVarP(&c.cwd, "cwd", "C", ".", "directory in the repository")
+	f.StringVar(&c.alias, "alias", "", "alias of the package to add")
+	f.BoolVar(&c.force, "force", false, "accept packages whose digest changed since they were pinned")
+	f.StringVar(&c.pkgCache, "pkg-cache", "", "directory of the persistent package cache; defaults to $SHAC_PKG_CACHE or the user cache directory")
+}
+
+func (c *depsCmd) Execute(ctx context.Context, args []string) (err error) {
+	if len(args) == 0 {
+		return errors.New("deps requires a command: add, update, verify or graph")
+	}
+	want := 0
+	if args[0] == "add" {
+		want = 1
+	}
+	switch args[0] {
+	case "add", "update", "verify", "graph":
+		if len(args)-1 != want {
+			return fmt.Errorf("deps %s expects %d arguments, got %d", args[0], want, len(args)-1)
+		}
+	default:
+		return fmt.Errorf("unknown deps command %q", args[0])
+	}
+	if c.alias != "" && args[0] != "add" {
+		return errors.New("--alias can only be used with deps add")
+	}
+	if c.force && args[0] != "add" && args[0] != "update" {
+		return errors.New("--force can only be used with deps add or deps update")
+	}
+	var url, version string
+	if args[0] == "add" {
+		i := strings.LastIndexByte(args[1], '@')
+		if i <= 0 || i == len(args[1])-1 {
+			return fmt.Errorf("expected <url>@<version>, got %q", args[1])
+		}
+		url, version = args[1][:i], args[1][i+1:]
+	}
+	d, err := engine.OpenDeps(ctx, c.cwd, c.pkgCache, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err2 := d.Close(); err == nil {
+			err = err2
+		}
+	}()
+	d.Force = c.force
+	switch args[0] {
+	case "add":
+		return d.Add(ctx, url, version, c.alias)
+	case "update":
+		return d.Update(ctx)
+	case "verify":
+		return d.Verify(ctx)
+	default:
+		return d.Graph(ctx, os.Stdout)
+	}
+}
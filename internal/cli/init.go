@@ -0,0 +1,54 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
+)
+
+type initCmd struct {
+	cwd      string
+	force    bool
+	gitHook  bool
+	pkgCache string
+}
+
+func (*initCmd) Name() string {
+	return "init"
+}
+
+func (*initCmd) Description() string {
+	return "Create a starter shac.star and shac.textproto."
+}
+
+func (c *initCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVarP(&c.cwd, "cwd", "C", ".", "directory in the repository to initialize")
+	f.BoolVar(&c.force, "force", false, "overwrite an existing shac.star")
+	f.BoolVar(&c.gitHook, "git-hook", false, "install a git pre-push hook running shac check")
+	f.StringVar(&c.pkgCache, "pkg-cache", "", "directory of the persistent package cache; defaults to $SHAC_PKG_CACHE or the user cache directory")
+}
+
+func (c *initCmd) Execute(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errors.New("init does not accept positional arguments")
+	}
+	o := engine.InitOptions{Dir: c.cwd, Force: c.force, GitHook: c.gitHook, PackageCache: c.pkgCache}
+	return engine.Init(ctx, &o, os.Stderr)
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
+	"go.fuchsia.dev/shac-project/shac/internal/reporting"
+)
+
+type listCmd struct {
+	commandBase
+	json bool
+}
+
+func (*listCmd) Name() string {
+	return "list"
+}
+
+func (*listCmd) Description() string {
+	return "List the registered checks without running them."
+}
+
+func (c *listCmd) SetFlags(f *flag.FlagSet) {
+	c.commandBase.SetFlags(f)
+	f.BoolVar(&c.json, "json", false, "print the checks as JSON")
+}
+
+func (c *listCmd) Execute(ctx context.Context, files []string) error {
+	r, err := reporting.Get(ctx, engine.Notice)
+	if err != nil {
+		return err
+	}
+	o, err := c.options(files)
+	if err != nil {
+		return err
+	}
+	o.Report = r
+	checks, err := engine.List(ctx, &o)
+	if err2 := r.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	if c.json {
+		if checks == nil {
+			checks = []*engine.CheckInfo{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(checks)
+	}
+	return printChecks(os.Stdout, checks)
+}
+
+// printChecks prints the checks in a human readable form.
+func printChecks(w io.Writer, checks []*engine.CheckInfo) error {
+	var b strings.Builder
+	for _, c := range checks {
+		b.WriteString(c.Name)
+		if c.Formatter {
+			b.WriteString(" (formatter)")
+		}
+		fmt.Fprintf(&b, "\n  file: %s\n  impl: %s\n", c.File, c.Impl)
+		if len(c.Args) != 0 {
+			var args []string
+			for _, k := range slices.Sorted(maps.Keys(c.Args)) {
+				args = append(args, k+" = "+c.Args[k])
+			}
+			fmt.Fprintf(&b, "  args: %s\n", strings.Join(args, ", "))
+		}
+		if len(c.Tags) != 0 {
+			fmt.Fprintf(&b, "  tags: %s\n", strings.Join(c.Tags, ", "))
+		}
+		if c.Doc != "" {
+			for _, l := range strings.Split(c.Doc, "\n") {
+				if l == "" {
+					b.WriteString("\n")
+				} else {
+					fmt.Fprintf(&b, "    %s\n", l)
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
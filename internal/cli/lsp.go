@@ -0,0 +1,51 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"go.fuchsia.dev/shac-project/shac/internal/lsp"
+)
+
+type lspCmd struct {
+	commandBase
+}
+
+func (*lspCmd) Name() string {
+	return "lsp"
+}
+
+func (*lspCmd) Description() string {
+	return "Run a language server on stdin/stdout reporting findings as diagnostics."
+}
+
+func (c *lspCmd) SetFlags(f *flag.FlagSet) {
+	c.commandBase.SetFlags(f)
+}
+
+func (c *lspCmd) Execute(ctx context.Context, files []string) error {
+	if len(files) != 0 {
+		return errors.New("lsp does not accept positional arguments")
+	}
+	o, err := c.options(nil)
+	if err != nil {
+		return err
+	}
+	return lsp.Serve(ctx, &o, os.Stdin, os.Stdout)
+}
@@ -71,6 +71,12 @@ func Main(ctx context.Context, args []string) error {
 		&checkCmd{},
 		&fmtCmd{},
 		&fixCmd{},
+		&listCmd{},
+		&initCmd{},
+		&depsCmd{},
+		&cacheCmd{},
+		&lspCmd{},
+		&mergeSarifCmd{},
 		&docCmd{},
 		&versionCmd{},
 		&helpCmd{},
@@ -104,6 +110,8 @@ func Main(ctx context.Context, args []string) error {
 		}
 		if !a.verbose {
 			log.SetOutput(io.Discard)
+		} else if v, ok := s.(interface{ setVerbose(bool) }); ok {
+			v.setVerbose(true)
 		}
 		return s.Execute(ctx, a.fs.Args())
 	}
@@ -38,6 +38,12 @@ func TestMainHelp(t *testing.T) {
 		{[]string{"shac", "check", "--help"}, "Usage of shac check:\n"},
 		{[]string{"shac", "fix", "--help"}, "Usage of shac fix:\n"},
 		{[]string{"shac", "fmt", "--help"}, "Usage of shac fmt:\n"},
+		{[]string{"shac", "list", "--help"}, "Usage of shac list:\n"},
+		{[]string{"shac", "init", "--help"}, "Usage of shac init:\n"},
+		{[]string{"shac", "deps", "--help"}, "Usage of shac deps:\n"},
+		{[]string{"shac", "cache", "--help"}, "Usage of shac cache:\n"},
+		{[]string{"shac", "lsp", "--help"}, "Usage of shac lsp:\n"},
+		{[]string{"shac", "merge-sarif", "--help"}, "Usage of shac merge-sarif:\n"},
 		{[]string{"shac", "doc", "--help"}, "Usage of shac doc:\n"},
 		{[]string{"shac", "version", "--help"}, "Usage of shac version:\n"},
 	}
@@ -95,6 +101,74 @@ func TestMainErr(t *testing.T) {
 			return []string{"check", "--all", "foo.txt", "bar.txt"},
 				"--all cannot be set together with positional file arguments"
 		},
+		"negative --timeout": func(t *testing.T) ([]string, string) {
+			return []string{"check", "--timeout", "-1s"},
+				"--timeout must not be negative"
+		},
+		"--watch with --json-output": func(t *testing.T) ([]string, string) {
+			return []string{"check", "--watch", "--json-output", "out.json"},
+				"--json-output cannot be set together with --watch"
+		},
+		"--watch with --trace": func(t *testing.T) ([]string, string) {
+			return []string{"check", "--watch", "--trace", "trace.json"},
+				"--trace cannot be set together with --watch"
+		},
+		"--watch with --write-baseline": func(t *testing.T) ([]string, string) {
+			return []string{"check", "--watch", "--write-baseline", "baseline.json"},
+				"--write-baseline cannot be set together with --watch"
+		},
+		"invalid --fail-on": func(t *testing.T) ([]string, string) {
+			return []string{"check", "--fail-on", "notice"},
+				"invalid --fail-on value \"notice\""
+		},
+		"invalid --min-level": func(t *testing.T) ([]string, string) {
+			return []string{"check", "--min-level", "never"},
+				"invalid argument \"never\" for \"--min-level\" flag: invalid level value \"never\""
+		},
+		"merge-sarif without files": func(t *testing.T) ([]string, string) {
+			return []string{"merge-sarif"},
+				"merge-sarif requires at least one SARIF file"
+		},
+		"init with positional arguments": func(t *testing.T) ([]string, string) {
+			return []string{"init", "a.txt"},
+				"init does not accept positional arguments"
+		},
+		"deps without command": func(t *testing.T) ([]string, string) {
+			return []string{"deps"},
+				"deps requires a command: add, update, verify or graph"
+		},
+		"deps with unknown command": func(t *testing.T) ([]string, string) {
+			return []string{"deps", "tidy"},
+				"unknown deps command \"tidy\""
+		},
+		"deps add without version": func(t *testing.T) ([]string, string) {
+			return []string{"deps", "add", "example.com/foo"},
+				"expected <url>@<version>, got \"example.com/foo\""
+		},
+		"deps verify with arguments": func(t *testing.T) ([]string, string) {
+			return []string{"deps", "verify", "example.com/foo"},
+				"deps verify expects 0 arguments, got 1"
+		},
+		"deps update with --alias": func(t *testing.T) ([]string, string) {
+			return []string{"deps", "update", "--alias", "foo"},
+				"--alias can only be used with deps add"
+		},
+		"deps verify with --force": func(t *testing.T) ([]string, string) {
+			return []string{"deps", "verify", "--force"},
+				"--force can only be used with deps add or deps update"
+		},
+		"cache without command": func(t *testing.T) ([]string, string) {
+			return []string{"cache"},
+				"cache requires a command: gc"
+		},
+		"cache with negative --max-age": func(t *testing.T) ([]string, string) {
+			return []string{"cache", "gc", "--max-age", "-1h"},
+				"--max-age must not be negative"
+		},
+		"lsp with positional arguments": func(t *testing.T) ([]string, string) {
+			return []string{"lsp", "a.txt"},
+				"lsp does not accept positional arguments"
+		},
 		"--only flag without value": func(t *testing.T) ([]string, string) {
 			root := t.TempDir()
 			return []string{"check", "-C", root, "--only"},
@@ -0,0 +1,68 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"go.fuchsia.dev/shac-project/shac/internal/reporting"
+)
+
+type mergeSarifCmd struct {
+	output string
+}
+
+func (*mergeSarifCmd) Name() string {
+	return "merge-sarif"
+}
+
+func (*mergeSarifCmd) Description() string {
+	return "Merge the SARIF files written by the shards of a run."
+}
+
+func (c *mergeSarifCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVarP(&c.output, "output", "o", "", "path to write the merged SARIF output to; defaults to stdout")
+}
+
+func (c *mergeSarifCmd) Execute(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("merge-sarif requires at least one SARIF file")
+	}
+	var in []io.Reader
+	for _, p := range args {
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = append(in, f)
+	}
+	if c.output == "" {
+		return reporting.MergeSarif(os.Stdout, in...)
+	}
+	out, err := os.Create(c.output)
+	if err != nil {
+		return err
+	}
+	err = reporting.MergeSarif(out, in...)
+	if err2 := out.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,252 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Baseline is a set of known findings, e.g. the preexisting findings of a
+// check that was just enabled, that are neither reported nor fail the checks.
+//
+// Findings are matched by fingerprint, which doesn't include the line number
+// so that unrelated edits to a file don't invalidate the baseline. Commit
+// message findings are never part of a baseline.
+type Baseline struct {
+	Findings []*BaselineFinding `json:"findings"`
+
+	// mu guards Findings while a run records a baseline.
+	mu sync.Mutex
+	// stats are the results of the last Run() that used this baseline.
+	stats BaselineStats
+}
+
+// BaselineFinding is a finding in a baseline.
+type BaselineFinding struct {
+	// Fingerprint is a digest of the other fields and of the content of the
+	// line the finding starts on.
+	Fingerprint string `json:"fingerprint"`
+	Check       string `json:"check"`
+	// File is relative to the root, POSIX style. Empty for findings not
+	// associated with a file.
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+}
+
+// BaselineStats summarizes how a baseline matched the findings of a run.
+type BaselineStats struct {
+	// Suppressed is the number of findings that were not reported because
+	// they were in the baseline.
+	Suppressed int
+	// Fixed is the number of baseline entries of checks that ran on the file
+	// without emitting the finding again.
+	Fixed int
+	// Stale is the number of baseline entries referencing a check that is not
+	// registered anymore or a file that doesn't exist anymore.
+	Stale int
+}
+
+// LoadBaseline reads a baseline written by Baseline.Save().
+func LoadBaseline(p string) (*Baseline, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	out := &Baseline{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err = d.Decode(out); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", p, err)
+	}
+	for i, f := range out.Findings {
+		if f == nil || f.Fingerprint == "" || f.Check == "" {
+			return nil, fmt.Errorf("failed to parse baseline %s: finding #%d is incomplete", p, i+1)
+		}
+	}
+	return out, nil
+}
+
+// Save writes the baseline as JSON, sorted so that the file diffs well.
+func (b *Baseline) Save(p string) error {
+	b.mu.Lock()
+	findings := slices.Clone(b.Findings)
+	b.mu.Unlock()
+	slices.SortFunc(findings, func(x, y *BaselineFinding) int {
+		return cmp.Or(
+			strings.Compare(x.Check, y.Check),
+			strings.Compare(x.File, y.File),
+			strings.Compare(x.Message, y.Message),
+			strings.Compare(x.Fingerprint, y.Fingerprint))
+	})
+	if findings == nil {
+		findings = []*BaselineFinding{}
+	}
+	d, err := json.MarshalIndent(&Baseline{Findings: findings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(d, '\n'), 0o644)
+}
+
+// Stats returns how the baseline matched the findings of the last Run() it
+// was passed to.
+func (b *Baseline) Stats() BaselineStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats
+}
+
+// baselineMatcher suppresses the findings in Options.Baseline and records the
+// findings in Options.RecordBaseline.
+type baselineMatcher struct {
+	root   string
+	files  *fileLines
+	apply  *Baseline
+	record *Baseline
+
+	mu sync.Mutex
+	// unmatched are the indexes in apply.Findings of the entries not yet
+	// matched in this run, by fingerprint.
+	unmatched map[string][]int
+	// suppressed is the number of findings suppressed in this run.
+	suppressed int
+}
+
+// reset prepares for a new run of the checks.
+func (m *baselineMatcher) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unmatched = map[string][]int{}
+	if m.apply != nil {
+		for i, f := range m.apply.Findings {
+			m.unmatched[f.Fingerprint] = append(m.unmatched[f.Fingerprint], i)
+		}
+	}
+	m.suppressed = 0
+	m.files.reset()
+	if m.record != nil {
+		m.record.mu.Lock()
+		m.record.Findings = nil
+		m.record.mu.Unlock()
+	}
+}
+
+// suppress records the finding and returns true if it is in the baseline.
+//
+// file is relative to the root, POSIX style.
+func (m *baselineMatcher) suppress(check, file, message string, line int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := &BaselineFinding{
+		Fingerprint: fingerprint(check, file, message, m.files.line(file, line)),
+		Check:       check,
+		File:        file,
+		Message:     message,
+	}
+	if m.record != nil {
+		m.record.mu.Lock()
+		m.record.Findings = append(m.record.Findings, f)
+		m.record.mu.Unlock()
+	}
+	idx := m.unmatched[f.Fingerprint]
+	if len(idx) == 0 {
+		return false
+	}
+	m.unmatched[f.Fingerprint] = idx[1:]
+	m.suppressed++
+	return true
+}
+
+// finish computes the stats of the run once the checks completed.
+//
+// registered are the names of all the checks registered, including the ones
+// that were not selected to run.
+func (m *baselineMatcher) finish(ctx context.Context, shacStates []*shacState, ran []*registeredCheck, registered map[string]bool) error {
+	if m.apply == nil {
+		return nil
+	}
+	// Map each check that ran to the files it could see. Checks in different
+	// directories may share the same name.
+	type checkKey struct{ subdir, name string }
+	visible := map[checkKey]map[string]bool{}
+	for _, s := range shacStates {
+		var files []file
+		for _, c := range s.checks {
+			if !slices.Contains(ran, c) {
+				continue
+			}
+			if files == nil {
+				var err error
+				if files, err = s.scm.affectedFiles(ctx, fileFilter{}); err != nil {
+					return err
+				}
+			}
+			v := map[string]bool{"": true}
+			for _, f := range s.visibleFiles(c, files) {
+				v[f.rootedpath()] = true
+			}
+			visible[checkKey{s.subdir, c.name}] = v
+		}
+	}
+	stats := BaselineStats{}
+	m.mu.Lock()
+	stats.Suppressed = m.suppressed
+	for _, idx := range m.unmatched {
+		for _, i := range idx {
+			f := m.apply.Findings[i]
+			if !registered[f.Check] {
+				stats.Stale++
+				continue
+			}
+			if f.File != "" {
+				if _, err := os.Stat(filepath.Join(m.root, filepath.FromSlash(f.File))); errors.Is(err, fs.ErrNotExist) {
+					stats.Stale++
+					continue
+				}
+			}
+			for k, v := range visible {
+				if k.name == f.Check && v[f.File] {
+					stats.Fixed++
+					break
+				}
+			}
+		}
+	}
+	m.mu.Unlock()
+	m.apply.mu.Lock()
+	m.apply.stats = stats
+	m.apply.mu.Unlock()
+	return nil
+}
+
+// fingerprint returns the stable identifier of a finding.
+func fingerprint(check, file, message, line string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", check, file, message, strings.TrimSpace(line))
+	return hex.EncodeToString(h.Sum(nil))
+}
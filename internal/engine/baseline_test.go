@@ -0,0 +1,189 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun_Baseline(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "a.txt", "foo", "bar", "foo")
+	writeFile(t, root, "shac.star",
+		"def lint(ctx):",
+		"    for f in ctx.scm.all_files(glob = \"*.txt\"):",
+		"        for i, l in enumerate(str(ctx.io.read_file(f)).splitlines()):",
+		"            if \"foo\" in l:",
+		"                ctx.emit.finding(level = \"error\", message = \"no foo\", filepath = f, line = i + 1)",
+		"shac.register_check(lint)")
+
+	// Record the baseline.
+	rec := &Baseline{}
+	o := Options{Report: &reportEmitNoPrint{reportNoPrint: reportNoPrint{t: t}}, Dir: root, RecordBaseline: rec}
+	if err := Run(context.Background(), &o); !errors.Is(err, ErrCheckFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := filepath.Join(t.TempDir(), "baseline.json")
+	if err := rec.Save(p); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBaseline(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*BaselineFinding{
+		{Fingerprint: fingerprint("lint", "a.txt", "no foo", "foo"), Check: "lint", File: "a.txt", Message: "no foo"},
+		{Fingerprint: fingerprint("lint", "a.txt", "no foo", "foo"), Check: "lint", File: "a.txt", Message: "no foo"},
+	}
+	if diff := cmp.Diff(want, b.Findings); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// All the findings are known.
+	o = Options{Report: &reportNoPrint{t: t}, Dir: root, Baseline: b}
+	if err = Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(BaselineStats{Suppressed: 2}, b.Stats()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Moving a known finding doesn't make it new, but changing its line does.
+	writeFile(t, root, "a.txt", "new foo", "bar", "foo")
+	b.Findings = append(b.Findings,
+		&BaselineFinding{Fingerprint: "1", Check: "removed", Message: "gone"},
+		&BaselineFinding{Fingerprint: "2", Check: "lint", File: "deleted.txt", Message: "gone"})
+	r := &reportEmitNoPrint{reportNoPrint: reportNoPrint{t: t}}
+	o = Options{Report: r, Dir: root, Baseline: b}
+	if err = Run(context.Background(), &o); !errors.Is(err, ErrCheckFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantFindings := []finding{
+		{
+			Check:   "lint",
+			Level:   Error,
+			Message: "no foo",
+			Root:    root,
+			File:    "a.txt",
+			Span:    Span{Start: Cursor{Line: 1}},
+		},
+	}
+	if diff := cmp.Diff(wantFindings, r.findings); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(BaselineStats{Suppressed: 1, Fixed: 1, Stale: 2}, b.Stats()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Baseline_Cache(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.textproto", "cache_dir: \".cache\"\n")
+	writeFile(t, root, "a.txt", "foo", "bar", "foo")
+	writeFile(t, root, "shac.star",
+		"def lint(ctx):",
+		"    print(\"ran\")",
+		"    for f in ctx.scm.all_files(glob = \"*.txt\"):",
+		"        for i, l in enumerate(str(ctx.io.read_file(f)).splitlines()):",
+		"            if \"foo\" in l:",
+		"                ctx.emit.finding(level = \"error\", message = \"no foo\", filepath = f, line = i + 1)",
+		"shac.register_check(lint)")
+
+	// Populate the cache without a baseline.
+	o := Options{Report: &reportEmitPrint{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}, Dir: root}
+	if err := Run(context.Background(), &o); !errors.Is(err, ErrCheckFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tamperCache(t, root)
+
+	// The cached findings are recorded and matched against the baseline.
+	b := &Baseline{Findings: []*BaselineFinding{
+		{Fingerprint: fingerprint("lint", "a.txt", "no foo", "foo"), Check: "lint", File: "a.txt", Message: "no foo"},
+	}}
+	rec := &Baseline{}
+	r := &reportEmitPrint{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}
+	o = Options{Report: r, Dir: root, Baseline: b, RecordBaseline: rec}
+	if err := Run(context.Background(), &o); !errors.Is(err, ErrCheckFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantFindings := []finding{
+		{
+			Check:   "lint",
+			Level:   Error,
+			Message: "no foo",
+			Root:    root,
+			File:    "a.txt",
+			Span:    Span{Start: Cursor{Line: 3}},
+		},
+	}
+	if diff := cmp.Diff(wantFindings, r.findings); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("[//shac.star:2] cached ran\n", r.b.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(BaselineStats{Suppressed: 1}, b.Stats()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if len(rec.Findings) != 2 {
+		t.Fatalf("expected 2 recorded findings, got %d", len(rec.Findings))
+	}
+
+	// Once every finding is known, the replayed result doesn't fail.
+	b.Findings = append(b.Findings, b.Findings[0])
+	o = Options{Report: &reportPrint{reportNoPrint: reportNoPrint{t: t}}, Dir: root, Baseline: b}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(BaselineStats{Suppressed: 2}, b.Stats()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Baseline_SameNameInSubdirs(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "a.txt", "bar")
+	writeFile(t, root, "sub/b.txt", "bar")
+	lint := []string{
+		"def lint(ctx):",
+		"    for f in ctx.scm.all_files(glob = \"*.txt\"):",
+		"        if \"foo\" in str(ctx.io.read_file(f)):",
+		"            ctx.emit.finding(level = \"error\", message = \"no foo\", filepath = f)",
+		"shac.register_check(lint)",
+	}
+	writeFile(t, root, "shac.star", lint...)
+	writeFile(t, root, "sub/shac.star", lint...)
+
+	// Both entries are fixed, whichever shac.star registered the check.
+	b := &Baseline{Findings: []*BaselineFinding{
+		{Fingerprint: "1", Check: "lint", File: "a.txt", Message: "no foo"},
+		{Fingerprint: "2", Check: "lint", File: "sub/b.txt", Message: "no foo"},
+	}}
+	o := Options{Report: &reportNoPrint{t: t}, Dir: root, Recurse: true, Baseline: b}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(BaselineStats{Fixed: 2}, b.Stats()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
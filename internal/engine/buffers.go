@@ -65,3 +65,82 @@ func (i *buffersImpl) push(b *bytes.Buffer) {
 	i.b[b] = struct{}{}
 	i.mu.Unlock()
 }
+
+// streamBuffer is a pooled buffer that can be read line by line while a
+// subprocess is still writing to it.
+//
+// Lines read with readLine() are consumed, so that the memory used by a
+// long running subprocess that is streamed stays bounded.
+type streamBuffer struct {
+	mu   sync.Mutex
+	cond sync.Cond
+	b    *bytes.Buffer
+	// closed is set once the writer is done.
+	closed bool
+}
+
+func newStreamBuffer() *streamBuffer {
+	s := &streamBuffer{b: buffers.get()}
+	s.cond.L = &s.mu
+	return s
+}
+
+func (s *streamBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	n, err := s.b.Write(p)
+	s.mu.Unlock()
+	s.cond.Broadcast()
+	return n, err
+}
+
+// close signals readers that no more data will be written.
+func (s *streamBuffer) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// readLine blocks until a complete line is available or the writer is closed.
+//
+// It returns the line without its trailing newline. It returns false once the
+// writer is closed and all the data was consumed.
+func (s *streamBuffer) readLine() ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.closed && bytes.IndexByte(s.b.Bytes(), '\n') == -1 {
+		s.cond.Wait()
+	}
+	if s.b.Len() == 0 {
+		return nil, false
+	}
+	// If the writer is closed, the last line may not have a trailing newline.
+	line, _ := s.b.ReadBytes('\n')
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r")), true
+}
+
+func (s *streamBuffer) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Len()
+}
+
+func (s *streamBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+// Bytes returns a copy of the unread data.
+func (s *streamBuffer) Bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.b.Bytes())
+}
+
+// release returns the underlying buffer to the pool.
+func (s *streamBuffer) release() {
+	buffers.push(s.b)
+	s.b = nil
+}
@@ -18,6 +18,8 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestBuffers(t *testing.T) {
@@ -78,3 +80,37 @@ func TestBuffers(t *testing.T) {
 		buffers.push(b)
 	})
 }
+
+func TestStreamBuffer(t *testing.T) {
+	t.Parallel()
+	s := newStreamBuffer()
+	defer s.release()
+
+	done := make(chan struct{})
+	var got []string
+	go func() {
+		defer close(done)
+		for {
+			line, ok := s.readLine()
+			if !ok {
+				return
+			}
+			got = append(got, string(line))
+		}
+	}()
+	for _, w := range []string{"hello", ", world\nsecond", " line\r\n", "\n", "no newline"} {
+		if _, err := s.Write([]byte(w)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.close()
+	<-done
+
+	want := []string{"hello, world", "second line", "", "no newline"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if s.Len() != 0 {
+		t.Errorf("Expected all lines to be consumed, got %q", s.String())
+	}
+}
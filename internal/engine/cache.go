@@ -0,0 +1,445 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"go.starlark.net/starlark"
+)
+
+// Kinds of checkInput.
+const (
+	inputAffectedFiles = "affected_files"
+	inputAllFiles      = "all_files"
+	inputCommits       = "commits"
+	inputNewLines      = "new_lines"
+	inputReadFile      = "read_file"
+)
+
+// checkInput is a query made by a check that may affect its results.
+type checkInput struct {
+	// Kind is one of the input* constants.
+	Kind string `json:"kind"`
+	// IncludeDeleted, IncludeSymlinks and Glob are the arguments of
+	// ctx.scm.affected_files() and ctx.scm.all_files().
+	IncludeDeleted  bool     `json:"include_deleted,omitempty"`
+	IncludeSymlinks bool     `json:"include_symlinks,omitempty"`
+	Glob            []string `json:"glob,omitempty"`
+	// Path is the path relative to the root, POSIX style, for new_lines() and
+	// the absolute native path for ctx.io.read_file().
+	Path string `json:"path,omitempty"`
+	// Action is the action of the file for new_lines().
+	Action string `json:"action,omitempty"`
+	// Size is the size argument of ctx.io.read_file().
+	Size int64 `json:"size,omitempty"`
+	// Digest is the digest of the result of the query. It is only set for
+	// ctx.io.read_file() until the result of the check is cached.
+	Digest string `json:"digest,omitempty"`
+
+	// matcher is the compiled Glob, nil if Glob is empty.
+	matcher gitignore.Matcher
+}
+
+// matches returns true if the file f, relative to root and POSIX style, may
+// change the result of the query.
+func (i *checkInput) matches(root, f string) bool {
+	switch i.Kind {
+	case inputAffectedFiles, inputAllFiles:
+		return i.matcher == nil || i.matcher.Match(strings.Split(f, "/"), false)
+	case inputNewLines:
+		return i.Path == f
+	case inputReadFile:
+		return i.Path == filepath.Join(root, filepath.FromSlash(f))
+	default:
+		return false
+	}
+}
+
+// digest runs the query again and returns a digest of its result.
+func (i *checkInput) digest(ctx context.Context, s *shacState) (string, error) {
+	h := sha256.New()
+	switch i.Kind {
+	case inputAffectedFiles, inputAllFiles:
+		filter := fileFilter{includeDeleted: i.IncludeDeleted, includeSymlinks: i.IncludeSymlinks}
+		var files []file
+		var err error
+		if i.Kind == inputAffectedFiles {
+			files, err = s.scm.affectedFiles(ctx, filter)
+		} else {
+			files, err = s.scm.allFiles(ctx, filter)
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, f := range filterFilesByGlob(files, i.matcher) {
+			fmt.Fprintf(h, "%s\x00%s\x00", f.rootedpath(), f.action())
+			if f.action() != "D" {
+				// Errors, e.g. for a symlink to a directory, are folded in the
+				// digest.
+				_ = digestFile(h, filepath.Join(s.root, filepath.FromSlash(f.rootedpath())))
+			}
+		}
+	case inputCommits:
+		commits, err := s.scm.commits(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range commits {
+			fmt.Fprintf(h, "%s\x00%s\x00", c.hash, c.message)
+		}
+	case inputNewLines:
+		v, err := s.scm.newLines(ctx, &fileImpl{path: i.Path, a: i.Action})
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(v.String()))
+	case inputReadFile:
+		b, err := readFileImpl(i.Path, i.Size)
+		if err != nil {
+			return "", err
+		}
+		h.Write(b)
+	default:
+		return "", fmt.Errorf("unknown input kind %q", i.Kind)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digestFile writes the digest of the content of a file to h.
+func digestFile(h hash.Hash, p string) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		h.Write([]byte{0})
+		return err
+	}
+	d := sha256.Sum256(b)
+	h.Write([]byte{1})
+	h.Write(d[:])
+	return nil
+}
+
+// Kinds of cacheEvent.
+const (
+	eventPrint                = "print"
+	eventFinding              = "finding"
+	eventBaselineFinding      = "baseline_finding"
+	eventCommitMessageFinding = "commit_message_finding"
+	eventArtifact             = "artifact"
+	eventSuppressedFinding    = "suppressed_finding"
+)
+
+// cacheEvent is a call to Report made by a check.
+type cacheEvent struct {
+	// Kind is one of the event* constants. eventBaselineFinding is a finding
+	// that is matched against the baseline, if any, when replayed.
+	Kind          string            `json:"kind"`
+	Level         Level             `json:"level,omitempty"`
+	Message       string            `json:"message,omitempty"`
+	Root          string            `json:"root,omitempty"`
+	File          string            `json:"file,omitempty"`
+	Line          int               `json:"line,omitempty"`
+	Span          Span              `json:"span"`
+	Replacements  []string          `json:"replacements,omitempty"`
+	Properties    map[string]string `json:"properties,omitempty"`
+	CommitHash    string            `json:"commit_hash,omitempty"`
+	CommitMessage string            `json:"commit_message,omitempty"`
+	Content       []byte            `json:"content,omitempty"`
+	Justification string            `json:"justification,omitempty"`
+}
+
+// cacheEntry is the cached result of a check.
+type cacheEntry struct {
+	// Key is a digest of everything the check depends on, except its inputs.
+	Key    string        `json:"key"`
+	Inputs []*checkInput `json:"inputs"`
+	Events []cacheEvent  `json:"events"`
+}
+
+// checkCache is an on-disk cache of the results of checks, stored in
+// shac.textproto's cache_dir.
+type checkCache struct {
+	dir string
+	// sources is the digest of all the loaded Starlark sources.
+	sources string
+}
+
+// key returns a digest of everything the check depends on, except the queries
+// it made.
+func (c *checkCache) key(s *shacState, check *registeredCheck) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00", Version, c.sources, s.subdir, check.name)
+	// The kwargs are built from a map so their order is not stable.
+	kwargs := slices.SortedFunc(slices.Values(check.kwargs), func(a, b starlark.Tuple) int {
+		return strings.Compare(string(a[0].(starlark.String)), string(b[0].(starlark.String)))
+	})
+	for _, kv := range kwargs {
+		h.Write([]byte(kv.String()))
+	}
+	h.Write([]byte{0})
+	names := make([]string, 0, len(s.vars))
+	for k := range s.vars {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	for _, k := range names {
+		fmt.Fprintf(h, "%s=%s\x00", k, s.vars[k])
+	}
+	for _, e := range s.passthroughEnv {
+		v, ok := os.LookupEnv(e.Name)
+		fmt.Fprintf(h, "%s=%t:%s\x00", e.Name, ok, v)
+	}
+	// The limits may change the outcome of subprocesses.
+	fmt.Fprintf(h, "%+v\x00", s.execLimits)
+	// So does whether they can write to the checkout.
+	fmt.Fprintf(h, "writable_root=%t\x00", s.writableRoot)
+	// ctx.scm.base is visible to the check.
+	fmt.Fprintf(h, "%s\x00", scmBase(s.scm))
+	// The files of the shard are visible to the checks that shard their files.
+	if check.shardFiles {
+		fmt.Fprintf(h, "shard=%d/%d\x00", s.shard.index, s.shard.count)
+	}
+	// The checks block remaps levels and changes the files and vars visible to
+	// the check.
+	if check.config != nil {
+		fmt.Fprintf(h, "config=%s\x00", check.config.digest)
+	}
+	// Unused shac:ignore directives are flagged once the check completed.
+	fmt.Fprintf(h, "unused_suppressions=%s\x00", s.unusedSuppressions)
+	// So are the results published by the dependencies.
+	for _, d := range check.deps {
+		fmt.Fprintf(h, "%s=%v\x00", d.name, d.result)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the path of the cache entry for the check.
+func (c *checkCache) path(s *shacState, check *registeredCheck) string {
+	d := sha256.Sum256([]byte(s.subdir + "\x00" + check.name))
+	return filepath.Join(c.dir, hex.EncodeToString(d[:16])+".json")
+}
+
+// replay replays the cached result of the check through r, if the cache entry
+// is still valid.
+//
+// Returns false if the check must be run. Errors are only returned by r.
+func (c *checkCache) replay(ctx context.Context, s *shacState, check *registeredCheck, r Report) (bool, error) {
+	b, err := os.ReadFile(c.path(s, check))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("failed to read the cached result of %s: %s", check.name, err)
+		}
+		return false, nil
+	}
+	var e cacheEntry
+	if err = json.Unmarshal(b, &e); err != nil || e.Key != c.key(s, check) || len(e.Inputs) == 0 {
+		// Ignore corrupted or stale entries, they will be overwritten.
+		return false, nil
+	}
+	for _, i := range e.Inputs {
+		if i.matcher, err = newGlobMatcher(i.Glob); err != nil {
+			return false, nil
+		}
+		if d, err := i.digest(ctx, s); err != nil || d != i.Digest {
+			return false, nil
+		}
+	}
+	start := time.Now()
+	// The level is recomputed since the baseline may suppress findings that
+	// were reported when the entry was saved, or the other way around.
+	level := Nothing
+	raise := func(l Level) {
+		if level == "" || l == Error || (l == Warning && level != Error) {
+			level = l
+		}
+	}
+	for _, ev := range e.Events {
+		switch ev.Kind {
+		case eventPrint:
+			r.Print(ctx, check.name, ev.File, ev.Line, ev.Message)
+		case eventBaselineFinding:
+			if s.baseline != nil {
+				rooted := ev.File
+				if rooted != "" {
+					rooted = path.Join(s.subdir, rooted)
+				}
+				if s.baseline.suppress(check.name, rooted, ev.Message, ev.Span.Start.Line) {
+					continue
+				}
+			}
+			raise(ev.Level)
+			err = r.EmitFinding(ctx, check.name, ev.Level, ev.Message, ev.Root, ev.File, ev.Span, ev.Replacements, ev.Properties)
+		case eventFinding:
+			raise(ev.Level)
+			err = r.EmitFinding(ctx, check.name, ev.Level, ev.Message, ev.Root, ev.File, ev.Span, ev.Replacements, ev.Properties)
+		case eventCommitMessageFinding:
+			raise(ev.Level)
+			err = r.EmitCommitMessageFinding(ctx, check.name, ev.Level, ev.Message, ev.CommitHash, ev.CommitMessage, ev.Span, ev.Properties)
+		case eventArtifact:
+			err = r.EmitArtifact(ctx, check.name, "", ev.File, ev.Content)
+		case eventSuppressedFinding:
+			if sr, ok := r.(SuppressionReport); ok {
+				err = sr.EmitSuppressedFinding(ctx, check.name, ev.Level, ev.Message, ev.Root, ev.File, ev.Span, ev.Justification)
+			}
+		}
+		if err != nil {
+			return true, err
+		}
+	}
+	check.highestLevel = level
+	check.mu.Lock()
+	check.inputs = e.Inputs
+	check.mu.Unlock()
+	r.CheckCompleted(ctx, check.name, start, time.Since(start), check.highestLevel, nil)
+	return true, nil
+}
+
+// save saves the result of the check.
+func (c *checkCache) save(ctx context.Context, s *shacState, check *registeredCheck, events []cacheEvent) error {
+	check.mu.Lock()
+	inputs := slices.Clone(check.inputs)
+	check.mu.Unlock()
+	if len(inputs) == 0 {
+		// Nothing tells whether the result is still valid, e.g. if the check
+		// only runs subprocesses. Remove the entry of a previous run, if any.
+		if err := os.Remove(c.path(s, check)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	e := cacheEntry{
+		Key:    c.key(s, check),
+		Inputs: make([]*checkInput, 0, len(inputs)),
+		Events: events,
+	}
+	for _, i := range inputs {
+		i2 := *i
+		if i2.Kind != inputReadFile {
+			var err error
+			if i2.Digest, err = i2.digest(ctx, s); err != nil {
+				return err
+			}
+		}
+		e.Inputs = append(e.Inputs, &i2)
+	}
+	b, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a concurrent shac process never reads
+	// a partial entry.
+	f, err := os.CreateTemp(c.dir, "tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(s, check))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// cacheRecorder is a Report that records the calls made by each check so they
+// can be saved in the cache.
+type cacheRecorder struct {
+	Report
+
+	mu     sync.Mutex
+	events map[string][]cacheEvent
+}
+
+func (c *cacheRecorder) record(check string, ev cacheEvent) {
+	if check == "" {
+		return
+	}
+	c.mu.Lock()
+	c.events[check] = append(c.events[check], ev)
+	c.mu.Unlock()
+}
+
+// take returns the events recorded for the check.
+func (c *cacheRecorder) take(check string) []cacheEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.events[check]
+	delete(c.events, check)
+	return e
+}
+
+func (c *cacheRecorder) EmitFinding(ctx context.Context, check string, level Level, message, root, file string, s Span, replacements []string, props map[string]string) error {
+	c.record(check, cacheEvent{Kind: eventFinding, Level: level, Message: message, Root: root, File: file, Span: s, Replacements: replacements, Properties: props})
+	return c.Report.EmitFinding(ctx, check, level, message, root, file, s, replacements, props)
+}
+
+func (c *cacheRecorder) EmitCommitMessageFinding(ctx context.Context, check string, level Level, message string, commitHash string, commitMessage string, s Span, props map[string]string) error {
+	c.record(check, cacheEvent{Kind: eventCommitMessageFinding, Level: level, Message: message, CommitHash: commitHash, CommitMessage: commitMessage, Span: s, Properties: props})
+	return c.Report.EmitCommitMessageFinding(ctx, check, level, message, commitHash, commitMessage, s, props)
+}
+
+func (c *cacheRecorder) EmitSuppressedFinding(ctx context.Context, check string, level Level, message, root, file string, s Span, justification string) error {
+	c.record(check, cacheEvent{Kind: eventSuppressedFinding, Level: level, Message: message, Root: root, File: file, Span: s, Justification: justification})
+	if sr, ok := c.Report.(SuppressionReport); ok {
+		return sr.EmitSuppressedFinding(ctx, check, level, message, root, file, s, justification)
+	}
+	return nil
+}
+
+func (c *cacheRecorder) EmitArtifact(ctx context.Context, check, root, file string, content []byte) error {
+	if root != "" {
+		// The file may disappear after the call, read it now.
+		p := file
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		c.record(check, cacheEvent{Kind: eventArtifact, File: file, Content: b})
+	} else {
+		c.record(check, cacheEvent{Kind: eventArtifact, File: file, Content: content})
+	}
+	return c.Report.EmitArtifact(ctx, check, root, file, content)
+}
+
+func (c *cacheRecorder) Print(ctx context.Context, check, file string, line int, message string) {
+	c.record(check, cacheEvent{Kind: eventPrint, File: file, Line: line, Message: message})
+	c.Report.Print(ctx, check, file, line, message)
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun_Cache(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.textproto", "cache_dir: \".cache\"\n")
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(\" \".join(ctx.scm.affected_files()))",
+		"    for f in ctx.scm.affected_files(glob = \"*.txt\"):",
+		"        print(f + \": \" + str(ctx.io.read_file(f)))",
+		"        ctx.emit.finding(level = \"error\", message = \"found\", filepath = f)",
+		"shac.register_check(cb)")
+
+	run := func(noCache bool) string {
+		t.Helper()
+		r := reportEmitPrint{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}
+		o := Options{Report: &r, Dir: root, NoCache: noCache}
+		if err := Run(context.Background(), &o); err != ErrCheckFailed {
+			t.Fatalf("expected ErrCheckFailed, got %v", err)
+		}
+		want := []finding{
+			{Check: "cb", Level: Error, Message: "found", Root: root, File: "a.txt"},
+		}
+		if diff := cmp.Diff(want, r.findings); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+		return r.b.String()
+	}
+	// The cache directory is not reported as affected files.
+	want := "[//shac.star:2] a.txt shac.star shac.textproto\n" +
+		"[//shac.star:4] a.txt: a\n"
+	if diff := cmp.Diff(want, run(false)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	tamperCache(t, root)
+	want = "[//shac.star:2] cached a.txt shac.star shac.textproto\n" +
+		"[//shac.star:4] cached a.txt: a\n"
+	if diff := cmp.Diff(want, run(false)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// The cache is ignored when disabled.
+	want = "[//shac.star:2] a.txt shac.star shac.textproto\n" +
+		"[//shac.star:4] a.txt: a\n"
+	if diff := cmp.Diff(want, run(true)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Modifying a file read by the check invalidates the cache.
+	tamperCache(t, root)
+	writeFile(t, root, "a.txt", "b")
+	want = "[//shac.star:2] a.txt shac.star shac.textproto\n" +
+		"[//shac.star:4] a.txt: b\n"
+	if diff := cmp.Diff(want, run(false)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Modifying the Starlark code invalidates the cache.
+	tamperCache(t, root)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(\" \".join(ctx.scm.affected_files()))",
+		"    for f in ctx.scm.affected_files(glob = \"*.txt\"):",
+		"        print(f + \": \" + str(ctx.io.read_file(f)))",
+		"        ctx.emit.finding(level = \"error\", message = \"found\", filepath = f)",
+		"shac.register_check(cb)",
+		"")
+	if diff := cmp.Diff(want, run(false)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Cache_Kwargs(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.textproto", "cache_dir: \".cache\"\n")
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx, a = 0, b = 0, c = 0, d = 0):",
+		"    print(\"%s %d %d %d %d\" % (str(ctx.io.read_file(\"a.txt\")), a, b, c, d))",
+		"shac.register_check(shac.check(cb).with_args(a = 1, b = 2, c = 3, d = 4))")
+
+	run := func() string {
+		t.Helper()
+		r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+		o := Options{Report: &r, Dir: root}
+		if err := Run(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		return r.b.String()
+	}
+	if diff := cmp.Diff("[//shac.star:2] a 1 2 3 4\n", run()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	// The order of the kwargs doesn't affect the cache key.
+	tamperCache(t, root)
+	for range 10 {
+		if diff := cmp.Diff("[//shac.star:2] cached a 1 2 3 4\n", run()); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+// tamperCache modifies the cached print() messages of the only cache entry in
+// root, so it is visible when the cached result is used.
+func TestRun_Cache_WritableRoot(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.textproto", "cache_dir: \".cache\"\n")
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(str(ctx.io.read_file(\"a.txt\")))",
+		"shac.register_check(cb)")
+
+	run := func() string {
+		t.Helper()
+		r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+		o := Options{Report: &r, Dir: root}
+		if err := Run(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		return r.b.String()
+	}
+	if diff := cmp.Diff("[//shac.star:2] a\n", run()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	tamperCache(t, root)
+	if diff := cmp.Diff("[//shac.star:2] cached a\n", run()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	// Subprocesses may behave differently when they can write to the
+	// checkout.
+	writeFile(t, root, "shac.textproto", "cache_dir: \".cache\"\nwritable_root: true\n")
+	if diff := cmp.Diff("[//shac.star:2] a\n", run()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func tamperCache(t *testing.T, root string) {
+	t.Helper()
+	m, err := filepath.Glob(filepath.Join(root, ".cache", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("expected one cache entry, got %v", m)
+	}
+	var e cacheEntry
+	if err = json.Unmarshal([]byte(readFile(t, m[0])), &e); err != nil {
+		t.Fatal(err)
+	}
+	for i := range e.Events {
+		if e.Events[i].Kind == eventPrint {
+			e.Events[i].Message = "cached " + e.Events[i].Message
+		}
+	}
+	b, err := json.Marshal(&e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(m[0], b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun_Cache_NoInputs(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses cat")
+	}
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.textproto", "cache_dir: \".cache\"\n")
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(ctx.os.exec([\"cat\", \"a.txt\"]).wait().stdout)",
+		"shac.register_check(cb)")
+
+	run := func() string {
+		t.Helper()
+		r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+		o := Options{Report: &r, Dir: root}
+		if err := Run(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		return r.b.String()
+	}
+	if diff := cmp.Diff("[//shac.star:2] a\n", run()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	// The check didn't request any file so its result is not cached.
+	if m, err := filepath.Glob(filepath.Join(root, ".cache", "*.json")); err != nil || len(m) != 0 {
+		t.Fatalf("expected no cache entry, got %v, %v", m, err)
+	}
+	writeFile(t, root, "a.txt", "b")
+	if diff := cmp.Diff("[//shac.star:2] b\n", run()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -18,10 +18,20 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"net/url"
 	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"go.fuchsia.dev/shac-project/shac/internal/sandbox"
+	"go.starlark.net/starlark"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func (doc *Document) CheckVersion() error {
@@ -125,14 +135,223 @@ func (doc *Document) Validate() error {
 			return fmt.Errorf("vendor_path %s is not clean", doc.VendorPath)
 		}
 	}
+	vars := map[string]struct{}{}
 	for _, v := range doc.Vars {
 		if v.Name == "" {
 			return fmt.Errorf("vars cannot have empty names")
 		}
+		vars[v.Name] = struct{}{}
+	}
+	checks := map[string]struct{}{}
+	for i, c := range doc.Checks {
+		if err := c.validate(vars); err != nil {
+			return fmt.Errorf("checks block #%d: %w", i+1, err)
+		}
+		if _, ok := checks[c.Name]; ok {
+			return fmt.Errorf("checks block #%d: %s was already listed", i+1, c.Name)
+		}
+		checks[c.Name] = struct{}{}
+	}
+	return nil
+}
+
+// validate verifies a shac.textproto checks block is valid.
+func (c *CheckConfig) validate(vars map[string]struct{}) error {
+	if c.Name == "" {
+		return errors.New("name must be set")
+	}
+	from := map[string]struct{}{}
+	for _, l := range c.Levels {
+		if !Level(l.From).isValid() {
+			return fmt.Errorf("level %q is invalid", l.From)
+		}
+		if !Level(l.To).isValid() {
+			return fmt.Errorf("level %q is invalid", l.To)
+		}
+		if _, ok := from[l.From]; ok {
+			return fmt.Errorf("level %s was already remapped", l.From)
+		}
+		from[l.From] = struct{}{}
+	}
+	for _, p := range c.Include {
+		if p == "" {
+			return errors.New("include patterns cannot be empty")
+		}
+	}
+	for _, p := range c.Exclude {
+		if p == "" || strings.HasPrefix(p, "!") {
+			return fmt.Errorf("exclude pattern %q is invalid", p)
+		}
+	}
+	for _, v := range c.Vars {
+		if _, ok := vars[v.Name]; !ok {
+			return fmt.Errorf("var %q is not declared in vars", v.Name)
+		}
+	}
+	for _, a := range c.Args {
+		if a.Name == "" {
+			return errors.New("args cannot have empty names")
+		}
+	}
+	return nil
+}
+
+// checkConfigs returns the runtime form of the checks blocks, keyed by check
+// name.
+func (doc *Document) checkConfigs() (map[string]*checkConfig, error) {
+	out := make(map[string]*checkConfig, len(doc.Checks))
+	for _, c := range doc.Checks {
+		cfg, err := newCheckConfig(c)
+		if err != nil {
+			return nil, fmt.Errorf("checks block for %s: %w", c.Name, err)
+		}
+		out[c.Name] = cfg
+	}
+	return out, nil
+}
+
+// checkConfig is the runtime form of a CheckConfig.
+type checkConfig struct {
+	// digest identifies the configuration in the cache key.
+	digest   string
+	disabled bool
+	levels   map[Level]Level
+	// files is nil when all the files are visible to the check.
+	files  gitignore.Matcher
+	vars   map[string]string
+	kwargs []starlark.Tuple
+}
+
+func newCheckConfig(c *CheckConfig) (*checkConfig, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	out := &checkConfig{
+		digest:   base64.StdEncoding.EncodeToString(b),
+		disabled: c.Disabled,
+		levels:   map[Level]Level{},
+		vars:     map[string]string{},
+	}
+	for _, l := range c.Levels {
+		out.levels[Level(l.From)] = Level(l.To)
+	}
+	patterns := slices.Clone(c.Include)
+	for _, p := range c.Exclude {
+		patterns = append(patterns, "!"+p)
+	}
+	if out.files, err = newGlobMatcher(patterns); err != nil {
+		return nil, err
+	}
+	for _, v := range c.Vars {
+		out.vars[v.Name] = v.Value
+	}
+	for _, a := range c.Args {
+		v, err := protoToStarlark(a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("arg %s: %w", a.Name, err)
+		}
+		v.Freeze()
+		out.kwargs = append(out.kwargs, starlark.Tuple{starlark.String(a.Name), v})
+	}
+	return out, nil
+}
+
+// applyConfigs attaches the checks blocks of shac.textproto to the checks,
+// then removes the disabled checks along with the checks depending on them.
+//
+// It must be called after resolveDeps().
+func (s *shacState) applyConfigs(configs map[string]*checkConfig) error {
+	for _, c := range s.checks {
+		c.config = configs[c.name]
+		if c.config == nil || len(c.config.kwargs) == 0 {
+			continue
+		}
+		res, err := c.check.withArgs(c.config.kwargs)
+		if err != nil {
+			return fmt.Errorf("checks block for %s: %w", c.name, err)
+		}
+		c.check = res.(*check)
+	}
+	disabled := map[*registeredCheck]bool{}
+	var isDisabled func(c *registeredCheck) bool
+	isDisabled = func(c *registeredCheck) bool {
+		if v, ok := disabled[c]; ok {
+			return v
+		}
+		v := c.config != nil && c.config.disabled
+		for _, d := range c.deps {
+			v = isDisabled(d) || v
+		}
+		disabled[c] = v
+		return v
 	}
+	s.checks = slices.DeleteFunc(s.checks, isDisabled)
 	return nil
 }
 
+// protoToStarlark converts a google.protobuf.Value to a Starlark value.
+func protoToStarlark(v *structpb.Value) (starlark.Value, error) {
+	switch k := v.GetKind().(type) {
+	case nil, *structpb.Value_NullValue:
+		return starlark.None, nil
+	case *structpb.Value_BoolValue:
+		return starlark.Bool(k.BoolValue), nil
+	case *structpb.Value_NumberValue:
+		if f := k.NumberValue; f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return starlark.MakeInt64(int64(f)), nil
+		}
+		return starlark.Float(k.NumberValue), nil
+	case *structpb.Value_StringValue:
+		return starlark.String(k.StringValue), nil
+	case *structpb.Value_ListValue:
+		l := make([]starlark.Value, 0, len(k.ListValue.Values))
+		for _, item := range k.ListValue.Values {
+			x, err := protoToStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, x)
+		}
+		return starlark.NewList(l), nil
+	case *structpb.Value_StructValue:
+		d := starlark.NewDict(len(k.StructValue.Fields))
+		for _, name := range slices.Sorted(maps.Keys(k.StructValue.Fields)) {
+			x, err := protoToStarlark(k.StructValue.Fields[name])
+			if err != nil {
+				return nil, err
+			}
+			if err = d.SetKey(starlark.String(name), x); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// ignoreMatcher returns a matcher for the ignore patterns and the cache
+// directory, if it is within the root directory.
+//
+// It returns nil if there is nothing to ignore.
+func (doc *Document) ignoreMatcher() (gitignore.Matcher, error) {
+	patterns := make([]gitignore.Pattern, 0, len(doc.Ignore)+1)
+	for _, p := range doc.Ignore {
+		if p == "" {
+			return nil, errEmptyIgnore
+		}
+		patterns = append(patterns, gitignore.ParsePattern(p, nil))
+	}
+	if d := filepath.ToSlash(doc.CacheDir); d != "" && filepath.IsLocal(d) {
+		patterns = append(patterns, gitignore.ParsePattern("/"+path.Clean(d)+"/", nil))
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return gitignore.NewMatcher(patterns), nil
+}
+
 // Validate verifies a shac.textproto require block is valid.
 //
 // It allows fetching from a Gerrit pending CL or a GitHub pending PR.
@@ -142,7 +361,7 @@ func (d *Dependency) Validate() error {
 	if d.Url == "" {
 		return errors.New("url must be set")
 	}
-	if _, err := cleanURL(d.Url); err != nil {
+	if err := validateURL(d.Url); err != nil {
 		return fmt.Errorf("url is invalid: %w", err)
 	}
 	if isBadAlias(d.Alias) {
@@ -177,7 +396,7 @@ func (k *Known) Validate() error {
 	if k.Url == "" {
 		return errors.New("url must be set")
 	}
-	if _, err := cleanURL(k.Url); err != nil {
+	if err := validateURL(k.Url); err != nil {
 		return fmt.Errorf("url is invalid: %w", err)
 	}
 	if len(k.Seen) == 0 {
@@ -214,7 +433,7 @@ func (k *Known) Validate() error {
 
 // Digest returns the digest for the specified url and version.
 func (s *Sum) Digest(url, version string) string {
-	for _, k := range s.Known {
+	for _, k := range s.GetKnown() {
 		if k.Url == url {
 			for _, vd := range k.Seen {
 				if vd.Version == version {
@@ -227,6 +446,65 @@ func (s *Sum) Digest(url, version string) string {
 	return ""
 }
 
+// validateURL verifies the url of a dependency. It is either a local path, an
+// archive or a git repository.
+func validateURL(n string) error {
+	switch {
+	case isLocalPath(n):
+		if strings.Contains(n, "\\") {
+			return fmt.Errorf("local path %s must use forward slashes", n)
+		}
+		if c := path.Clean(n); c != n && "./"+c != n {
+			return fmt.Errorf("unclean local path %s", n)
+		}
+		return nil
+	case isArchive(n):
+		_, err := archiveURL(n)
+		return err
+	default:
+		_, err := cleanURL(n)
+		return err
+	}
+}
+
+// isLocalPath returns true if the url of a dependency is a local directory,
+// either absolute or relative to the root of the repository.
+func isLocalPath(n string) bool {
+	return strings.HasPrefix(n, "./") || strings.HasPrefix(n, "../") || path.IsAbs(n)
+}
+
+// isArchive returns true if the url of a dependency is an archive file.
+func isArchive(n string) bool {
+	return strings.HasSuffix(n, ".tar.gz") || strings.HasSuffix(n, ".tgz") || strings.HasSuffix(n, ".zip")
+}
+
+// archiveURL returns the URL to download an archive from. The scheme is
+// either https or file, and defaults to https.
+func archiveURL(n string) (string, error) {
+	u, err := url.Parse(n)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "":
+		return cleanURL(n)
+	case "https":
+		if u.Host == "" {
+			return "", fmt.Errorf("a hostname is required for %s", n)
+		}
+	case "file":
+		if u.Host != "" || !path.IsAbs(u.Path) {
+			return "", fmt.Errorf("an absolute path is required for %s", n)
+		}
+	default:
+		return "", fmt.Errorf("unexpected scheme for %s", n)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("unexpected query or fragment for %s", n)
+	}
+	return n, nil
+}
+
 // cleanURL converts a schemaless URI to a fully qualified URI. For now
 // assumes HTTPS.
 func cleanURL(n string) (string, error) {
@@ -269,3 +547,13 @@ func isBadAlias(s string) bool {
 func isBadVersion(s string) bool {
 	return strings.ContainsAny(s, "$^[]{}\"'\\:+*<>=") || strings.Contains(s, "..")
 }
+
+// limits returns the sandbox limits. It is safe to call on a nil ExecLimits.
+func (l *ExecLimits) limits() sandbox.Limits {
+	return sandbox.Limits{
+		MemoryMB:   l.GetMemoryMb(),
+		CPUSeconds: l.GetCpuSeconds(),
+		OpenFiles:  l.GetOpenFiles(),
+		Processes:  l.GetProcesses(),
+	}
+}
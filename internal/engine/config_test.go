@@ -461,6 +461,90 @@ func TestDocument_Validate(t *testing.T) {
 				"}\n",
 			"",
 		},
+		// CheckConfig.validate().
+		{
+			"checks {\n" +
+				"  disabled: true\n" +
+				"}\n",
+			"checks block #1: name must be set",
+		},
+		{
+			"checks {\n" +
+				"  name: \"lint\"\n" +
+				"  levels {\n" +
+				"    from: \"error\"\n" +
+				"    to: \"fatal\"\n" +
+				"  }\n" +
+				"}\n",
+			"checks block #1: level \"fatal\" is invalid",
+		},
+		{
+			"checks {\n" +
+				"  name: \"lint\"\n" +
+				"  levels {\n" +
+				"    from: \"error\"\n" +
+				"    to: \"warning\"\n" +
+				"  }\n" +
+				"  levels {\n" +
+				"    from: \"error\"\n" +
+				"    to: \"notice\"\n" +
+				"  }\n" +
+				"}\n",
+			"checks block #1: level error was already remapped",
+		},
+		{
+			"checks {\n" +
+				"  name: \"lint\"\n" +
+				"  exclude: \"!*.go\"\n" +
+				"}\n",
+			"checks block #1: exclude pattern \"!*.go\" is invalid",
+		},
+		{
+			"checks {\n" +
+				"  name: \"lint\"\n" +
+				"  vars {\n" +
+				"    name: \"foo\"\n" +
+				"    value: \"bar\"\n" +
+				"  }\n" +
+				"}\n",
+			"checks block #1: var \"foo\" is not declared in vars",
+		},
+		{
+			"checks {\n" +
+				"  name: \"lint\"\n" +
+				"  args {\n" +
+				"    value {\n" +
+				"      bool_value: true\n" +
+				"    }\n" +
+				"  }\n" +
+				"}\n",
+			"checks block #1: args cannot have empty names",
+		},
+		{
+			"checks {\n" +
+				"  name: \"lint\"\n" +
+				"}\n" +
+				"checks {\n" +
+				"  name: \"lint\"\n" +
+				"}\n",
+			"checks block #2: lint was already listed",
+		},
+		{
+			"vars {\n" +
+				"  name: \"foo\"\n" +
+				"}\n" +
+				"checks {\n" +
+				"  name: \"lint\"\n" +
+				"  disabled: true\n" +
+				"  include: \"*.go\"\n" +
+				"  exclude: \"*_test.go\"\n" +
+				"  vars {\n" +
+				"    name: \"foo\"\n" +
+				"    value: \"bar\"\n" +
+				"  }\n" +
+				"}\n",
+			"",
+		},
 	}
 	for i, l := range data {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
@@ -586,3 +670,40 @@ func TestCleanURL(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateURL(t *testing.T) {
+	t.Parallel()
+	data := []struct {
+		in  string
+		err string
+	}{
+		{"example.com/foo", ""},
+		{"./checks", ""},
+		{"../checks", ""},
+		{"/srv/checks", ""},
+		{"../checks/", "unclean local path ../checks/"},
+		{"./a/../checks", "unclean local path ./a/../checks"},
+		{"./a\\checks", "local path ./a\\checks must use forward slashes"},
+		{"example.com/foo-1.0.tar.gz", ""},
+		{"https://example.com/foo.tgz", ""},
+		{"file:///srv/foo.zip", ""},
+		{"file://host/srv/foo.zip", "an absolute path is required for file://host/srv/foo.zip"},
+		{"https:///foo.zip", "a hostname is required for https:///foo.zip"},
+		{"http://example.com/foo.zip", "unexpected scheme for http://example.com/foo.zip"},
+		{"https://example.com/foo?a=b.zip", "unexpected query or fragment for https://example.com/foo?a=b.zip"},
+		{"https://example.com/foo", "unexpected scheme for https://example.com/foo"},
+	}
+	for i := range data {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			err := validateURL(data[i].in)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(data[i].err, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+)
+
+// resolveDeps resolves the dependencies of the registered checks by name.
+//
+// Cycles were already rejected by shac.register_check().
+func (s *shacState) resolveDeps() error {
+	byName := make(map[string]*registeredCheck, len(s.checks))
+	for _, c := range s.checks {
+		byName[c.name] = c
+	}
+	for _, c := range s.checks {
+		c.deps = nil
+		for _, n := range c.check.deps {
+			d := byName[n]
+			if d == nil {
+				return fmt.Errorf("check %q depends on %q which is not registered", c.name, n)
+			}
+			d.hasDependents = true
+			c.deps = append(c.deps, d)
+		}
+	}
+	return nil
+}
+
+// withDeps returns checks plus all the checks they depend on, transitively,
+// ordered so that every check is after its dependencies. Otherwise the order of
+// all is kept.
+func withDeps(checks, all []*registeredCheck) []*registeredCheck {
+	need := map[*registeredCheck]bool{}
+	var add func(c *registeredCheck)
+	add = func(c *registeredCheck) {
+		if !need[c] {
+			need[c] = true
+			for _, d := range c.deps {
+				add(d)
+			}
+		}
+	}
+	for _, c := range checks {
+		add(c)
+	}
+	out := make([]*registeredCheck, 0, len(need))
+	seen := map[*registeredCheck]bool{}
+	var visit func(c *registeredCheck)
+	visit = func(c *registeredCheck) {
+		if !seen[c] {
+			seen[c] = true
+			for _, d := range c.deps {
+				visit(d)
+			}
+			out = append(out, c)
+		}
+	}
+	for _, c := range all {
+		if need[c] {
+			visit(c)
+		}
+	}
+	return out
+}
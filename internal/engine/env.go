@@ -16,11 +16,14 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"path"
+	"slices"
 	"strings"
 	"sync"
 
@@ -102,6 +105,8 @@ type loadedSource struct {
 	mu      sync.Mutex
 	globals starlark.StringDict
 	err     error
+	// digest is the SHA-256 of the source file content.
+	digest [sha256.Size]byte
 }
 
 // starlarkEnv is the running environment enabling to run multiple starlark
@@ -199,6 +204,7 @@ func (e *starlarkEnv) loadInner(th *starlark.Thread, sk sourceKey) (starlark.Str
 		if f, err := pkg.Open(sk.relpath); err == nil {
 			var d []byte
 			if d, err = io.ReadAll(f); err == nil {
+				source.digest = sha256.Sum256(d)
 				oldsk := th.Local("shac.pkg").(sourceKey)
 				th.SetLocal("shac.pkg", sk)
 				fp := syntax.FilePortion{Content: d, FirstLine: 1, FirstCol: 1}
@@ -240,3 +246,23 @@ func (e *starlarkEnv) loadInner(th *starlark.Thread, sk sourceKey) (starlark.Str
 	}
 	return source.globals, source.err
 }
+
+// sourcesDigest returns a digest of all the loaded sources.
+func (e *starlarkEnv) sourcesDigest() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	keys := make([]string, 0, len(e.sources))
+	for k := range e.sources {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		source := e.sources[k]
+		source.mu.Lock()
+		h.Write([]byte(k))
+		h.Write(source.digest[:])
+		source.mu.Unlock()
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
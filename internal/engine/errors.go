@@ -16,6 +16,8 @@ package engine
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"go.starlark.net/starlark"
 )
@@ -25,6 +27,10 @@ import (
 // The information will have been provided via the Report interface.
 var ErrCheckFailed = errors.New("a check failed")
 
+// ErrTimeout is wrapped by the error passed to Report.CheckCompleted() when a
+// check or one of its subprocesses exceeded its timeout.
+var ErrTimeout = errors.New("timed out")
+
 // BacktraceableError is an error that has a starlark backtrace attached to it.
 type BacktraceableError interface {
 	error
@@ -74,4 +80,44 @@ func (e *evalError) Backtrace() string {
 var (
 	_ BacktraceableError = (*failure)(nil)
 	_ BacktraceableError = (*evalError)(nil)
+	_ BacktraceableError = (*ChecksError)(nil)
 )
+
+// ChecksError is returned by Run() when Options.KeepGoing is set and one or
+// more checks failed abnormally.
+type ChecksError struct {
+	// Checks are the names of the checks that failed, in the order in which
+	// they were started.
+	Checks []string
+	// Errs are the corresponding errors.
+	Errs []error
+}
+
+func (e *ChecksError) Error() string {
+	var b strings.Builder
+	if len(e.Checks) == 1 {
+		b.WriteString("1 check failed:")
+	} else {
+		fmt.Fprintf(&b, "%d checks failed:", len(e.Checks))
+	}
+	for i, name := range e.Checks {
+		fmt.Fprintf(&b, "\n  %s: %s", name, e.Errs[i])
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the checks.
+func (e *ChecksError) Unwrap() []error {
+	return e.Errs
+}
+
+// Backtrace returns the backtraces of the checks that have one.
+func (e *ChecksError) Backtrace() string {
+	var b strings.Builder
+	for i, name := range e.Checks {
+		if err, ok := errors.AsType[BacktraceableError](e.Errs[i]); ok {
+			fmt.Fprintf(&b, "%s: %s", name, err.Backtrace())
+		}
+	}
+	return b.String()
+}
@@ -63,7 +63,9 @@ func runGitCmd(ctx context.Context, dir string, args ...string) (string, error)
 	b := buffers.get()
 	cmd.Stdout = b
 	cmd.Stderr = b
+	end := ctxTracer(ctx).begin("git", "git "+strings.Join(args[1:], " "), nil)
 	err := execsupport.Run(ctx, cmd)
+	end()
 	// Always make a copy of the output, since it could be persisted. Only reuse
 	// the temporary buffer.
 	out := b.String()
@@ -0,0 +1,333 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.starlark.net/syntax"
+)
+
+// InitOptions is the options for Init().
+type InitOptions struct {
+	// Dir is a directory in the repository to initialize. Defaults to the
+	// current directory.
+	Dir string
+	// Force overwrites an existing shac.star.
+	Force bool
+	// GitHook installs a git pre-push hook running `shac check`.
+	GitHook bool
+	// PackageCache is the directory of the persistent package cache, see
+	// Options.PackageCache.
+	PackageCache string
+}
+
+// language is a programming language that can be detected in a repository.
+type language struct {
+	name  string
+	globs []string
+	// suggestion is a check using a tool of the language, offered commented out
+	// since the tool may not be installed.
+	suggestion string
+}
+
+// languages are the languages Init() detects, in the order they are listed.
+var languages = []language{
+	{
+		name:  "Go",
+		globs: []string{"*.go"},
+		suggestion: `def gofmt(ctx):
+    """Flags the Go files not formatted with gofmt."""
+    files = [f for f in ctx.scm.affected_files(glob = "*.go")]
+    if not files:
+        return
+    out = ctx.os.exec(["gofmt", "-l"] + files).wait().stdout
+    for f in out.splitlines():
+        ctx.emit.finding(level = "error", message = "Run gofmt -w " + f, filepath = f)
+
+shac.register_check(gofmt)`,
+	},
+	{name: "Python", globs: []string{"*.py"}},
+	{name: "Starlark", globs: []string{"*.star", "*.bzl"}},
+	{
+		name:  "Shell",
+		globs: []string{"*.sh"},
+		suggestion: `def shellcheck(ctx):
+    """Lints the shell scripts with shellcheck."""
+    files = [f for f in ctx.scm.affected_files(glob = "*.sh")]
+    if not files:
+        return
+    res = ctx.os.exec(["shellcheck", "--format=gcc"] + files, ok_retcodes = [0, 1]).wait()
+    if res.retcode:
+        ctx.emit.finding(level = "error", message = res.stdout)
+
+shac.register_check(shellcheck)`,
+	},
+	{name: "JavaScript", globs: []string{"*.js", "*.mjs", "*.jsx"}},
+	{name: "TypeScript", globs: []string{"*.ts", "*.tsx"}},
+	{name: "Rust", globs: []string{"*.rs"}},
+	{name: "C/C++", globs: []string{"*.c", "*.cc", "*.cpp", "*.h", "*.hpp"}},
+	{name: "Java", globs: []string{"*.java"}},
+	{name: "Markdown", globs: []string{"*.md"}},
+}
+
+// Init writes a starter shac.star and, if missing, shac.textproto at the root
+// of the repository.
+//
+// The files of the repository not ignored by shac.textproto are used to detect
+// the languages to check. The public functions taking a ctx argument in the
+// packages declared by an existing shac.textproto are offered as checks to
+// enable. Progress is written to w.
+func Init(ctx context.Context, o *InitOptions, w io.Writer) error {
+	root, err := resolveRoot(ctx, o.Dir)
+	if err != nil {
+		return err
+	}
+	mainFile := filepath.Join(root, DefaultEntryPoint)
+	if _, err = os.Stat(mainFile); err == nil && !o.Force {
+		return fmt.Errorf("%s already exists", mainFile)
+	}
+	// Check the hook before writing anything, so a failure leaves the
+	// checkout untouched.
+	hook := ""
+	if o.GitHook {
+		if hook, err = gitHookPath(ctx, root); err != nil {
+			return err
+		}
+	}
+
+	doc := Document{}
+	configFile := filepath.Join(root, "shac.textproto")
+	configExists, err := readConfig(configFile, &doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+	var offered []string
+	if configExists {
+		if offered, err = packageChecks(ctx, root, o.PackageCache, &doc); err != nil {
+			return err
+		}
+	} else {
+		c := fmt.Sprintf("# Configuration of shac, see\n# https://github.com/shac-project/shac/blob/main/internal/engine/shac.proto\n\nmin_shac_version: %q\n", Version.String())
+		if err = os.WriteFile(configFile, []byte(c), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Wrote %s\n", configFile)
+	}
+
+	scm, err := getSCM(ctx, root, true, "", false)
+	if err != nil {
+		return err
+	}
+	matcher, err := doc.ignoreMatcher()
+	if err != nil {
+		return err
+	}
+	if matcher != nil {
+		scm = &filteredSCM{matcher: matcher, scm: scm}
+	}
+	files, err := scm.allFiles(ctx, fileFilter{})
+	if err != nil {
+		return err
+	}
+	var detected []language
+	for _, l := range languages {
+		if slices.ContainsFunc(files, func(f file) bool { return l.matches(f.rootedpath()) }) {
+			detected = append(detected, l)
+		}
+	}
+	if err = os.WriteFile(mainFile, []byte(genMainFile(detected, offered)), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %s\n", mainFile)
+
+	if hook != "" {
+		if err = installGitHook(hook); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Installed %s\n", hook)
+	}
+	return nil
+}
+
+// matches returns true if the file, POSIX style, is of this language.
+func (l *language) matches(f string) bool {
+	for _, g := range l.globs {
+		if ok, _ := path.Match(g, path.Base(f)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// genMainFile returns the content of a starter shac.star.
+func genMainFile(detected []language, offered []string) string {
+	var b strings.Builder
+	b.WriteString("\"\"\"Checks of this repository, generated by `shac init`.\n\n")
+	b.WriteString("See https://github.com/shac-project/shac/blob/main/doc/stdlib.md for the API.\n\"\"\"\n\n")
+	var names, globs []string
+	for _, l := range detected {
+		names = append(names, l.name)
+		globs = append(globs, l.globs...)
+	}
+	if len(names) != 0 {
+		fmt.Fprintf(&b, "# Languages detected: %s.\n", strings.Join(names, ", "))
+		b.WriteString("_GLOBS = [")
+		for i, g := range globs {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.Quote(g))
+		}
+		b.WriteString("]\n\n")
+	} else {
+		b.WriteString("_GLOBS = None\n\n")
+	}
+	b.WriteString(`def trailing_whitespace(ctx):
+    """Flags the modified lines ending with whitespace."""
+    for path, meta in ctx.scm.affected_files(glob = _GLOBS).items():
+        for num, line in meta.new_lines():
+            if line != line.rstrip():
+                ctx.emit.finding(
+                    level = "warning",
+                    message = "Remove the trailing whitespace.",
+                    filepath = path,
+                    line = num,
+                    col = len(line.rstrip()) + 1,
+                    end_col = len(line) + 1,
+                    replacements = [""],
+                )
+
+shac.register_check(trailing_whitespace)
+`)
+	for _, l := range detected {
+		if l.suggestion == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n# Suggested %s check, uncomment to enable:\n#\n", l.name)
+		for _, line := range strings.Split(l.suggestion, "\n") {
+			b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+		}
+	}
+	if len(offered) != 0 {
+		b.WriteString("\n# Checks available in the declared packages, uncomment to enable:\n")
+		for _, o := range offered {
+			b.WriteString("# " + o + "\n")
+		}
+	}
+	return b.String()
+}
+
+// packageChecks returns the load() and shac.register_check() statements of the
+// checks found in the direct dependencies declared in doc.
+//
+// Checks are the public functions whose first argument is named ctx.
+func packageChecks(ctx context.Context, root, pkgCache string, doc *Document) ([]string, error) {
+	if doc.Requirements == nil || len(doc.Requirements.Direct) == 0 {
+		return nil, nil
+	}
+	tmpdir, err := os.MkdirTemp("", "shac")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	pkgMgr, err := newCachingPackageManager(tmpdir, pkgCache)
+	if err != nil {
+		return nil, err
+	}
+	defer pkgMgr.Close()
+	packages, err := pkgMgr.RetrievePackages(ctx, root, doc)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, d := range doc.Requirements.Direct {
+		name := d.Url
+		if d.Alias != "" {
+			name = d.Alias
+		}
+		pkg := packages[name]
+		err = fs.WalkDir(pkg, ".", func(p string, e fs.DirEntry, err error) error {
+			if err != nil || e.IsDir() || !strings.HasSuffix(p, ".star") {
+				return err
+			}
+			b, err := fs.ReadFile(pkg, p)
+			if err != nil {
+				return err
+			}
+			f, err := starlarkOptions().Parse(p, b, 0)
+			if err != nil {
+				// Not a valid Starlark file, it can't be loaded anyway.
+				return nil
+			}
+			for _, s := range f.Stmts {
+				def, ok := s.(*syntax.DefStmt)
+				if !ok || strings.HasPrefix(def.Name.Name, "_") || len(def.Params) == 0 {
+					continue
+				}
+				if id, ok := def.Params[0].(*syntax.Ident); !ok || id.Name != "ctx" {
+					continue
+				}
+				out = append(out,
+					fmt.Sprintf("load(%q, %q)", "@"+name+"//"+p, def.Name.Name),
+					fmt.Sprintf("shac.register_check(%s)", def.Name.Name))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// gitHookPath returns the path of the pre-push hook to install. It fails if
+// the hook already exists.
+func gitHookPath(ctx context.Context, root string) (string, error) {
+	d, err := runGitCmd(ctx, root, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("a git hook can only be installed in a git repository: %w", err)
+	}
+	d = strings.TrimSpace(d)
+	if !filepath.IsAbs(d) {
+		d = filepath.Join(root, d)
+	}
+	p := filepath.Join(d, "pre-push")
+	if _, err = os.Stat(p); err == nil {
+		return "", fmt.Errorf("%s already exists", p)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	return p, nil
+}
+
+// installGitHook installs a pre-push hook running `shac check` at p.
+func installGitHook(p string) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// #nosec G306
+	return os.WriteFile(p, []byte("#!/bin/sh\n# Installed by `shac init`.\nexec shac check\n"), 0o755)
+}
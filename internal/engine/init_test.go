@@ -0,0 +1,174 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInit(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	initGit(t, root)
+	writeFile(t, root, "main.go", "package main")
+	writeFile(t, root, "tools/run.sh", "#!/bin/sh")
+	writeFile(t, root, "README.md", "# Title")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-m", "Initial commit")
+	writeFile(t, root, "LICENSE", "Copyright")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-m", "Second commit")
+
+	ctx := context.Background()
+	o := InitOptions{Dir: filepath.Join(root, "tools"), GitHook: true}
+	if err := Init(ctx, &o, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	doc := Document{}
+	if _, err := readConfig(filepath.Join(root, "shac.textproto"), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.MinShacVersion != Version.String() {
+		t.Fatalf("unexpected min_shac_version %q", doc.MinShacVersion)
+	}
+	b, err := os.ReadFile(filepath.Join(root, "shac.star"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Languages detected: Go, Shell, Markdown.\n",
+		"# Suggested Go check, uncomment to enable:\n",
+		"# Suggested Shell check, uncomment to enable:\n",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected %q in:\n%s", want, b)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(root, ".git", "hooks", "pre-push")); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm()&0o100 == 0 {
+		t.Errorf("the hook is not executable: %s", fi.Mode())
+	}
+
+	// The generated files run cleanly.
+	writeFile(t, root, "main.go", "package main  ")
+	r := &reportEmitNoPrint{reportNoPrint: reportNoPrint{t: t}}
+	if err = Run(ctx, &Options{Report: r, Dir: root}); err != nil {
+		t.Fatal(err)
+	}
+	want := []finding{
+		{
+			Check:        "trailing_whitespace",
+			Level:        Warning,
+			Message:      "Remove the trailing whitespace.",
+			Root:         root,
+			File:         "main.go",
+			Span:         Span{Start: Cursor{Line: 1, Col: 13}, End: Cursor{Line: 1, Col: 15}},
+			Replacements: []string{""},
+		},
+	}
+	if diff := cmp.Diff(want, r.findings); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// The files are not overwritten by default.
+	o = InitOptions{Dir: root}
+	if err = Init(ctx, &o, io.Discard); err == nil || err.Error() != filepath.Join(root, "shac.star")+" already exists" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.Force = true
+	if err = Init(ctx, &o, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInit_GitHookExists(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	initGit(t, root)
+	hook := filepath.Join(root, ".git", "hooks", "pre-push")
+	writeFile(t, root, ".git/hooks/pre-push", "#!/bin/sh")
+
+	o := InitOptions{Dir: root, GitHook: true}
+	if err := Init(context.Background(), &o, io.Discard); err == nil || err.Error() != hook+" already exists" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Nothing was written.
+	for _, f := range []string{"shac.star", "shac.textproto"} {
+		if _, err := os.Stat(filepath.Join(root, f)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %s to not exist: %v", f, err)
+		}
+	}
+}
+
+func TestInit_Packages(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "vendor/example.com/foo/checks.star",
+		"def lint(ctx, strict = False):",
+		"    pass",
+		"def _private(ctx):",
+		"    pass",
+		"def helper(x):",
+		"    pass")
+	digest, err := FSToDigest(os.DirFS(filepath.Join(root, "vendor", "example.com", "foo")), "example.com/foo@1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "shac.textproto",
+		"vendor_path: \"vendor\"",
+		"ignore: \"/vendor/\"",
+		"requirements {",
+		"  direct {",
+		"    url: \"example.com/foo\"",
+		"    alias: \"foo\"",
+		"    version: \"1\"",
+		"  }",
+		"}",
+		"sum {",
+		"  known {",
+		"    url: \"example.com/foo\"",
+		"    seen {",
+		"      version: \"1\"",
+		"      digest: \""+digest+"\"",
+		"    }",
+		"  }",
+		"}")
+	if err = Init(context.Background(), &InitOptions{Dir: root}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(root, "shac.star"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\n# Checks available in the declared packages, uncomment to enable:\n" +
+		"# load(\"@foo//checks.star\", \"lint\")\n" +
+		"# shac.register_check(lint)\n"
+	if !strings.HasSuffix(string(b), want) {
+		t.Errorf("expected %q at the end of:\n%s", want, b)
+	}
+	if strings.Contains(string(b), "Languages detected") {
+		t.Errorf("no language expected in:\n%s", b)
+	}
+}
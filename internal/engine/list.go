@@ -0,0 +1,109 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+// CheckInfo describes a registered check.
+type CheckInfo struct {
+	Name string `json:"name"`
+	// File is the shac.star file that registered the check, relative to the
+	// root, POSIX style.
+	File string `json:"file"`
+	// Impl is the source location of the check implementation.
+	Impl      string `json:"impl"`
+	Formatter bool   `json:"formatter"`
+	// Args are the keyword arguments bound with check.with_args(), as
+	// Starlark literals.
+	Args map[string]string `json:"args,omitempty"`
+	Tags []string          `json:"tags,omitempty"`
+	// Doc is the docstring of the check implementation.
+	Doc string `json:"doc,omitempty"`
+}
+
+// List loads the shac.star files like Run() does and returns the checks that
+// would run, without running them.
+//
+// o.Report receives the output of print() calls made while loading.
+func List(ctx context.Context, o *Options) ([]*CheckInfo, error) {
+	tmpdir, err := os.MkdirTemp("", "shac")
+	if err != nil {
+		return nil, err
+	}
+	out, err := listInner(ctx, o, tmpdir)
+	if err2 := os.RemoveAll(tmpdir); err == nil {
+		err = err2
+	}
+	return out, err
+}
+
+func listInner(ctx context.Context, o *Options, tmpdir string) ([]*CheckInfo, error) {
+	r, err := newRunner(ctx, o, tmpdir)
+	if err != nil {
+		return nil, err
+	}
+	defer r.close()
+	if err = r.load(ctx); err != nil {
+		return nil, err
+	}
+	var out []*CheckInfo
+	for _, s := range r.shacStates {
+		for _, c := range s.checks {
+			info := &CheckInfo{
+				Name:      c.name,
+				File:      path.Join(s.subdir, s.entryPoint),
+				Impl:      c.impl.Position().String(),
+				Formatter: c.formatter,
+				Tags:      c.tags,
+				Doc:       trimDoc(c.impl.Doc()),
+			}
+			for _, kv := range c.kwargs {
+				if info.Args == nil {
+					info.Args = map[string]string{}
+				}
+				info.Args[string(kv[0].(starlark.String))] = kv[1].String()
+			}
+			out = append(out, info)
+		}
+	}
+	return out, nil
+}
+
+// trimDoc removes the indentation common to all the lines of a docstring but
+// the first one, along with leading and trailing blank lines.
+func trimDoc(doc string) string {
+	lines := strings.Split(strings.TrimSpace(doc), "\n")
+	indent := -1
+	for _, l := range lines[1:] {
+		if t := strings.TrimLeft(l, " \t"); t != "" && (indent < 0 || len(l)-len(t) < indent) {
+			indent = len(l) - len(t)
+		}
+	}
+	for i := 1; i < len(lines); i++ {
+		if len(lines[i]) >= indent && indent > 0 {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = strings.TrimLeft(lines[i], " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
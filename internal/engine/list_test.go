@@ -0,0 +1,64 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestList(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, root, "shac.star",
+		"def fmt(ctx):",
+		"    \"\"\"Formats the files.",
+		"",
+		"    Details.",
+		"    \"\"\"",
+		"    pass",
+		"def lint(ctx, strict = False):",
+		"    pass",
+		"shac.register_check(shac.check(fmt, formatter = True))",
+		"shac.register_check(shac.check(lint, tags = [\"slow\"]).with_args(strict = True))")
+	writeFile(t, root, "sub/shac.star",
+		"def sub(ctx):",
+		"    fail(\"must not run\")",
+		"shac.register_check(sub)")
+
+	o := Options{Report: &reportNoPrint{t: t}, Dir: root, Recurse: true}
+	got, err := List(context.Background(), &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*CheckInfo{
+		{Name: "fmt", File: "shac.star", Impl: "//shac.star:1:1", Formatter: true, Doc: "Formats the files.\n\nDetails."},
+		{Name: "lint", File: "shac.star", Impl: "//shac.star:7:1", Args: map[string]string{"strict": "True"}, Tags: []string{"slow"}},
+		{Name: "sub", File: "sub/shac.star", Impl: "//sub/shac.star:1:1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	o = Options{Report: &reportNoPrint{t: t}, Dir: root, Recurse: true, Filter: CheckFilter{DenyList: []string{"fmt", "sub"}}}
+	if got, err = List(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want[1:2], got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
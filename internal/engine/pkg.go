@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
@@ -86,12 +88,43 @@ type PackageManager struct {
 	// root is the location where dependencies are fetched.
 	//
 	// It is valid for this path to be a scratch space.
-	root           string
+	root string
+	// cache is the directory of the persistent package cache. Packages are
+	// fetched in root when empty.
+	cache          string
 	gitCommand     func(ctx context.Context, d string, args ...string) error
 	pkgConcurrency int
+	// httpClient downloads the archives. Defaults to http.DefaultClient.
+	httpClient *http.Client
+	// archiveLimit is the maximum size of an archive, and of the files
+	// extracted from it in total. Defaults to maxArchiveSize.
+	archiveLimit int64
+
+	mu sync.Mutex
+	// locks are the shared locks on the packages used from the persistent
+	// cache.
+	locks []*os.File
+}
+
+// Close releases the packages used from the persistent cache, so they can be
+// removed by GCPackageCache(). The packages retrieved must not be used
+// afterward.
+func (p *PackageManager) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for _, l := range p.locks {
+		errs = append(errs, l.Close())
+	}
+	p.locks = nil
+	return errors.Join(errs...)
 }
 
 // RetrievePackages retrieve all the packages in parallel, up to 8 threads.
+//
+// The requirements declared in the shac.textproto of each package are resolved
+// transitively. Every package of the closure must be required at a single
+// version and have its digest in the sum of doc.
 func (p *PackageManager) RetrievePackages(ctx context.Context, root string, doc *Document) (map[string]fs.FS, error) {
 	if !filepath.IsAbs(p.root) {
 		return nil, fmt.Errorf("path %s is not absolute", p.root)
@@ -105,103 +138,241 @@ func (p *PackageManager) RetrievePackages(ctx context.Context, root string, doc
 	if err := isDir(root); err != nil {
 		return nil, err
 	}
-	mu := sync.Mutex{}
 	packages := map[string]fs.FS{"__main__": os.DirFS(root)}
-	var depslists [][]*Dependency
-	if doc.Requirements != nil {
-		depslists = [][]*Dependency{doc.Requirements.Direct, doc.Requirements.Indirect}
+	fetch := func(ctx context.Context, d *Dependency) (fs.FS, error) {
+		digest := doc.Sum.Digest(d.Url, d.Version)
+		if digest == "" {
+			// Only possible for transitive requirements, the root ones are
+			// validated.
+			return nil, fmt.Errorf("%s@%s is missing from the sum of shac.textproto, run `shac deps update`", d.Url, d.Version)
+		}
+		return p.ensurePkg(ctx, root, doc.VendorPath, d.Url, d.Version, digest)
 	}
-
+	concurrency := p.pkgConcurrency
 	if doc.VendorPath != "" {
-		// Use the vendored versions.
-		vendorRoot := filepath.Join(root, doc.VendorPath)
-		for _, deps := range depslists {
-			// Do it serially for now, decided if parallelism helps performance later.
-			for _, d := range deps {
-				// url is believed to be vetted at this point.
-				dir := filepath.Join(vendorRoot, d.Url)
-				if err := isDir(dir); err != nil {
-					return packages, fmt.Errorf("vendored %w", err)
-				}
-				f, err := p.verifyDir(dir, d.Url, d.Version, doc.Sum.Digest(d.Url, d.Version))
-				if err != nil {
-					return packages, fmt.Errorf("%s couldn't be fetched: %w", d.Url, err)
-				}
-				packages[d.Url] = f
-				if d.Alias != "" {
-					packages[d.Alias] = f
-				}
+		// Do it serially for now, decided if parallelism helps performance later.
+		concurrency = 1
+	}
+	resolved, err := resolvePackages(ctx, doc, concurrency, fetch)
+	if err != nil {
+		return packages, err
+	}
+	// The aliases are shared by all the packages.
+	aliases := map[string]string{}
+	for _, r := range resolved {
+		packages[r.dep.Url] = r.f
+		for _, a := range r.aliases {
+			if u, ok := aliases[a]; ok && u != r.dep.Url {
+				return packages, fmt.Errorf("alias %s is used for both %s and %s", a, u, r.dep.Url)
 			}
+			aliases[a] = r.dep.Url
+			packages[a] = r.f
 		}
-		return packages, nil
 	}
+	return packages, nil
+}
+
+// resolvedPackage is a package in the closure of the requirements.
+type resolvedPackage struct {
+	dep *Dependency
+	// aliases are the aliases the package is required with.
+	aliases []string
+	// by lists who requires the package, __main__ or url@version.
+	by []string
+	f  fs.FS
+}
 
-	eg, ctx := errgroup.WithContext(ctx)
-	eg.SetLimit(p.pkgConcurrency)
-	for _, deps := range depslists {
+// resolvePackages fetches the requirements of doc, then the requirements
+// declared in their own shac.textproto, transitively.
+//
+// The packages are returned in breadth first order, starting with the
+// requirements of doc. fetch is called concurrently up to concurrency times.
+func resolvePackages(ctx context.Context, doc *Document, concurrency int, fetch func(context.Context, *Dependency) (fs.FS, error)) ([]*resolvedPackage, error) {
+	var out, next []*resolvedPackage
+	byURL := map[string]*resolvedPackage{}
+	add := func(d *Dependency, by string) error {
+		r := byURL[d.Url]
+		if r == nil {
+			r = &resolvedPackage{dep: d}
+			byURL[d.Url] = r
+			out = append(out, r)
+			next = append(next, r)
+		} else if r.dep.Version != d.Version {
+			return fmt.Errorf("%s is required at conflicting versions: %s by %s, %s by %s", d.Url, r.dep.Version, strings.Join(r.by, ", "), d.Version, by)
+		}
+		r.by = append(r.by, by)
+		if d.Alias != "" && !slices.Contains(r.aliases, d.Alias) {
+			r.aliases = append(r.aliases, d.Alias)
+		}
+		return nil
+	}
+	for _, deps := range [][]*Dependency{doc.GetRequirements().GetDirect(), doc.GetRequirements().GetIndirect()} {
 		for _, d := range deps {
+			if err := add(d, "__main__"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for len(next) != 0 {
+		current := next
+		next = nil
+		eg, ctx := errgroup.WithContext(ctx)
+		eg.SetLimit(concurrency)
+		for _, r := range current {
 			eg.Go(func() error {
-				f, err := p.ensureGitPkg(ctx, d.Url, d.Version, doc.Sum.Digest(d.Url, d.Version))
+				f, err := fetch(ctx, r.dep)
 				if err != nil {
-					return fmt.Errorf("%s couldn't be fetched: %w", d.Url, err)
+					return fmt.Errorf("%s couldn't be fetched: %w", r.dep.Url, err)
 				}
-				mu.Lock()
-				packages[d.Url] = f
-				if d.Alias != "" {
-					packages[d.Alias] = f
-				}
-				mu.Unlock()
+				r.f = f
 				return nil
 			})
 		}
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+		for _, r := range current {
+			name := r.dep.Url + "@" + r.dep.Version
+			pdoc, err := packageConfig(r.f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			for _, deps := range [][]*Dependency{pdoc.GetRequirements().GetDirect(), pdoc.GetRequirements().GetIndirect()} {
+				for _, d := range deps {
+					if err = d.Validate(); err != nil {
+						return nil, fmt.Errorf("%s: requirement %s: %w", name, d.Url, err)
+					}
+					if isLocalPath(d.Url) {
+						return nil, fmt.Errorf("%s: requirement %s: local paths are only allowed in the root shac.textproto", name, d.Url)
+					}
+					if err = add(d, name); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
 	}
-	err := eg.Wait()
-	return packages, err
+	return out, nil
+}
+
+// ensurePkg returns a fs.FS for the dependency, from the source its url refers
+// to: a local directory, the vendor directory, an archive or a git remote.
+//
+// Local directories are relative to root and are never vendored.
+func (p *PackageManager) ensurePkg(ctx context.Context, root, vendorPath, url, version, digest string) (fs.FS, error) {
+	switch {
+	case isLocalPath(url):
+		dir := filepath.FromSlash(url)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		if err := isDir(dir); err != nil {
+			return nil, fmt.Errorf("local %w", err)
+		}
+		return p.verifyDir(dir, url, version, digest)
+	case vendorPath != "":
+		// Use the vendored versions.
+		// url is believed to be vetted at this point.
+		dir := filepath.Join(root, vendorPath, vendoredName(url))
+		if err := isDir(dir); err != nil {
+			return nil, fmt.Errorf("vendored %w", err)
+		}
+		return p.verifyDir(dir, url, version, digest)
+	case isArchive(url):
+		return p.ensureArchivePkg(ctx, url, version, digest)
+	default:
+		return p.ensureGitPkg(ctx, url, version, digest)
+	}
+}
+
+// vendoredName returns the relative path of a dependency in the vendor
+// directory, which is its url without the scheme.
+func vendoredName(url string) string {
+	if i := strings.Index(url, "://"); i != -1 {
+		url = url[i+len("://"):]
+	}
+	return filepath.FromSlash(url)
 }
 
 // ensureGitPkg returns a fs.FS for the dependency, assuming a git remote.
 //
+// When a persistent cache is configured and the digest is known, the package
+// is only fetched if it is not already in the cache.
+//
 // It is invalid to retrieve the same dependency at multiple versions during a
 // single session.
 func (p *PackageManager) ensureGitPkg(ctx context.Context, url, version string, digest string) (fs.FS, error) {
-	fullURL, err := cleanURL(url)
+	dv := digestVersion("", url, version)
+	if p.cache != "" && digest != "" {
+		return p.ensureCachedPkg(url, dv, digest, func(scratch string) (string, error) {
+			return p.cloneGitPkg(ctx, scratch, url, version)
+		})
+	}
+	depdir, err := p.cloneGitPkg(ctx, p.root, url, version)
 	if err != nil {
 		return nil, err
 	}
+	return p.verifyDir(depdir, url, dv, digest)
+}
 
-	depdir := filepath.Join(p.root, url)
+// isPendingRef returns true if version is a pending Gerrit CL or GitHub PR,
+// which are fetched then checked out as FETCH_HEAD.
+func isPendingRef(version string) bool {
 	if ok, _ := regexp.MatchString("^refs/changes/\\d{1,2}/\\d{1,11}/\\d{1,3}$", version); ok {
-		// Explicitly enable support using a pending Gerrit CL.
-		if err = p.gitCommand(ctx, depdir, "fetch", fullURL, version); err != nil {
-			return nil, err
-		}
-		version = "FETCH_HEAD"
-	} else if ok, _ := regexp.MatchString("^pull/\\d+/head$", version); ok {
-		// Explicitly enable support using a pending GitHub PR.
+		return true
+	}
+	ok, _ := regexp.MatchString("^pull/\\d+/head$", version)
+	return ok
+}
+
+// digestVersion returns the version hashed in the digest of the package.
+//
+// A pending ref fetched with git is hashed as FETCH_HEAD, which is what it is
+// checked out as, so the digests pinned by earlier versions of shac stay
+// valid. A vendored copy hashes the ref itself.
+func digestVersion(vendorPath, url, version string) string {
+	if vendorPath == "" && !isLocalPath(url) && !isArchive(url) && isPendingRef(version) {
+		return "FETCH_HEAD"
+	}
+	return version
+}
+
+// cloneGitPkg clones the dependency under root and returns the path of the
+// checkout.
+func (p *PackageManager) cloneGitPkg(ctx context.Context, root, url, version string) (string, error) {
+	fullURL, err := cleanURL(url)
+	if err != nil {
+		return "", err
+	}
+
+	depdir := filepath.Join(root, url)
+	ref := version
+	if isPendingRef(version) {
+		// Explicitly enable support using a pending Gerrit CL or GitHub PR.
 		if err = p.gitCommand(ctx, depdir, "fetch", fullURL, version); err != nil {
-			return nil, err
+			return "", err
 		}
-		version = "FETCH_HEAD"
+		ref = "FETCH_HEAD"
 	} else {
 		// Use a format similar to Go modules cache.
 		v := ""
 		if v, err = module.EscapeVersion(version); err != nil {
-			return nil, err
+			return "", err
 		}
 		depdir += "@" + v
 	}
 
 	parentdir := filepath.Dir(depdir)
 	if err = os.MkdirAll(parentdir, 0o700); err != nil {
-		return nil, err
+		return "", err
 	}
 	if err = p.gitCommand(ctx, parentdir, "clone", fullURL, filepath.Base(depdir)); err != nil {
-		return nil, err
+		return "", err
 	}
-	if err = p.gitCommand(ctx, depdir, "checkout", version); err != nil {
-		return nil, err
+	if err = p.gitCommand(ctx, depdir, "checkout", ref); err != nil {
+		return "", err
 	}
-	return p.verifyDir(depdir, url, version, digest)
+	return depdir, nil
 }
 
 // verifyDir returns a fs.FS that maps to path `d`, after having confirmed
@@ -249,3 +420,19 @@ func isDir(d string) error {
 	}
 	return nil
 }
+
+// packageConfig returns the shac.textproto of a package, or an empty document
+// if it has none. Its min_shac_version is enforced.
+func packageConfig(f fs.FS) (*Document, error) {
+	doc := &Document{}
+	b, err := fs.ReadFile(f, "shac.textproto")
+	if errors.Is(err, fs.ErrNotExist) {
+		return doc, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = unmarshalConfig(b, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
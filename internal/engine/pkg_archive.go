@@ -0,0 +1,252 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxArchiveSize is the default maximum size of a package archive, and of the
+// files extracted from it in total.
+const maxArchiveSize = 1 << 30
+
+// ensureArchivePkg returns a fs.FS for the dependency, assuming an archive
+// file.
+//
+// When a persistent cache is configured and the digest is known, the package
+// is only fetched if it is not already in the cache.
+func (p *PackageManager) ensureArchivePkg(ctx context.Context, url, version, digest string) (fs.FS, error) {
+	if p.cache != "" && digest != "" {
+		return p.ensureCachedPkg(url, version, digest, func(scratch string) (string, error) {
+			return p.extractArchivePkg(ctx, scratch, url)
+		})
+	}
+	d, err := os.MkdirTemp(p.root, "archive")
+	if err != nil {
+		return nil, err
+	}
+	depdir, err := p.extractArchivePkg(ctx, d, url)
+	if err != nil {
+		return nil, err
+	}
+	return p.verifyDir(depdir, url, version, digest)
+}
+
+// extractArchivePkg downloads the archive and extracts it under root, then
+// returns the path of the package.
+//
+// If all the files of the archive are in a single top level directory, as
+// for the archives generated by GitHub, this directory is the package.
+func (p *PackageManager) extractArchivePkg(ctx context.Context, root, url string) (string, error) {
+	loc, err := archiveURL(url)
+	if err != nil {
+		return "", err
+	}
+	limit := p.archiveLimit
+	if limit == 0 {
+		limit = maxArchiveSize
+	}
+	b, err := p.download(ctx, loc, limit)
+	if err != nil {
+		return "", err
+	}
+	x := &extractor{dst: filepath.Join(root, "pkg"), limit: limit}
+	if err = os.Mkdir(x.dst, 0o700); err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(url, ".zip") {
+		err = x.zip(b)
+	} else {
+		err = x.tarGz(b)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", url, err)
+	}
+	entries, err := os.ReadDir(x.dst)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(x.dst, entries[0].Name()), nil
+	}
+	return x.dst, nil
+}
+
+// download returns the content of a https:// or file:// URL, failing if it is
+// larger than limit bytes.
+func (p *PackageManager) download(ctx context.Context, loc string, limit int64) ([]byte, error) {
+	if f, ok := strings.CutPrefix(loc, "file://"); ok {
+		r, err := os.Open(filepath.FromSlash(f))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		b, err := readAtMost(r, limit)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", loc, err)
+		}
+		return b, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := p.httpClient
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", loc, resp.Status)
+	}
+	b, err := readAtMost(resp.Body, limit)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", loc, err)
+	}
+	return b, nil
+}
+
+// readAtMost reads r until EOF, failing if it is larger than limit bytes.
+func readAtMost(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err == nil && int64(len(b)) > limit {
+		err = fmt.Errorf("larger than %d bytes", limit)
+	}
+	return b, err
+}
+
+// extractor extracts the members of an archive under dst, failing once they
+// total more than the limit, so a small archive can't fill the disk.
+type extractor struct {
+	dst   string
+	limit int64
+	// size is the number of bytes extracted so far.
+	size int64
+}
+
+func (x *extractor) tarGz(b []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	t := tar.NewReader(gz)
+	for {
+		h, err := t.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(h.Name)
+		case tar.TypeReg:
+			err = x.file(h.Name, h.FileInfo().Mode(), t)
+		case tar.TypeXGlobalHeader:
+			// Metadata added by git archive.
+		default:
+			err = fmt.Errorf("unsupported file type for %s", h.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) zip(b []byte) error {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return err
+	}
+	for _, f := range z.File {
+		mode := f.Mode()
+		if mode.IsDir() {
+			err = x.dir(f.Name)
+		} else if mode.IsRegular() {
+			var r io.ReadCloser
+			if r, err = f.Open(); err == nil {
+				err = x.file(f.Name, mode, r)
+				_ = r.Close()
+			}
+		} else {
+			err = fmt.Errorf("unsupported file type for %s", f.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivePath returns the native path of an archive member under dst,
+// refusing the ones that would escape it.
+func archivePath(dst, name string) (string, error) {
+	c := path.Clean(name)
+	if path.IsAbs(c) || c == ".." || strings.HasPrefix(c, "../") || strings.Contains(name, "\\") {
+		return "", fmt.Errorf("illegal path %s", name)
+	}
+	return filepath.Join(dst, filepath.FromSlash(c)), nil
+}
+
+func (x *extractor) dir(name string) error {
+	p, err := archivePath(x.dst, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, 0o700)
+}
+
+func (x *extractor) file(name string, mode fs.FileMode, r io.Reader) error {
+	p, err := archivePath(x.dst, name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	perm := fs.FileMode(0o600)
+	if mode&0o100 != 0 {
+		perm = 0o700
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, x.limit-x.size+1))
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if x.size += n; err == nil && x.size > x.limit {
+		err = fmt.Errorf("extracting %s: the files are larger than %d bytes in total", name, x.limit)
+	}
+	return err
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultPackageCache returns the default directory of the persistent package
+// cache.
+//
+// It is $SHAC_PKG_CACHE if set, otherwise shac/pkg in the user's cache
+// directory, e.g. $XDG_CACHE_HOME/shac/pkg on Linux.
+func DefaultPackageCache() (string, error) {
+	if d := os.Getenv("SHAC_PKG_CACHE"); d != "" {
+		return filepath.Abs(d)
+	}
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "shac", "pkg"), nil
+}
+
+// newCachingPackageManager returns a PackageManager fetching the packages in
+// tmpdir and keeping them in the persistent cache in dir, DefaultPackageCache()
+// if empty.
+func newCachingPackageManager(tmpdir, dir string) (*PackageManager, error) {
+	p := NewPackageManager(tmpdir)
+	if dir != "" {
+		var err error
+		if p.cache, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+	} else {
+		p.cache, _ = DefaultPackageCache()
+	}
+	return p, nil
+}
+
+// The package cache is content addressed: each package is stored in a
+// directory named after its digest, which covers both its url@version and its
+// content. A package is fetched in a scratch directory in pkgCacheTmp, then
+// renamed into place, so concurrent shac processes never see a partial
+// package. The modification time of the directory of a package is updated
+// every time it is used, for GCPackageCache().
+//
+// A shac process holds a shared lock on the file named after the package in
+// pkgCacheLocks while it uses the package. GCPackageCache() skips the packages
+// it can't lock exclusively. The lock files are never removed, otherwise a
+// process could lock a file that was just unlinked.
+const (
+	pkgCacheTmp   = "tmp"
+	pkgCacheLocks = "locks"
+)
+
+// errLocked is returned by lockFile() when the file is locked by another
+// process.
+var errLocked = errors.New("file is locked")
+
+// lockCachedPkg takes a lock on the package name in the cache and returns
+// the lock file. Closing it releases the lock.
+func lockCachedPkg(cache, name string, exclusive, wait bool) (*os.File, error) {
+	d := filepath.Join(cache, pkgCacheLocks)
+	if err := os.MkdirAll(d, 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(d, name), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(f, exclusive, wait); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// pkgCacheEntry returns the name of the directory of the package with digest
+// in the cache.
+func pkgCacheEntry(digest string) (string, error) {
+	if !strings.HasPrefix(digest, "h1:") {
+		return "", fmt.Errorf("unsupported digest %s", digest)
+	}
+	b, err := base64.StdEncoding.DecodeString(digest[3:])
+	if err != nil {
+		return "", fmt.Errorf("invalid digest %s: %w", digest, err)
+	}
+	return "h1-" + hex.EncodeToString(b), nil
+}
+
+// ensureCachedPkg returns a fs.FS for the dependency from the persistent
+// cache, fetching it first if it is missing or doesn't match digest.
+//
+// The package stays locked until Close() is called.
+//
+// fetch fetches the dependency under a scratch directory and returns its path.
+func (p *PackageManager) ensureCachedPkg(url, version, digest string, fetch func(scratch string) (string, error)) (_ fs.FS, err error) {
+	name, err := pkgCacheEntry(digest)
+	if err != nil {
+		return nil, err
+	}
+	// Best effort, the cache may be read only, in which case it can't be
+	// garbage collected either.
+	lock, _ := lockCachedPkg(p.cache, name, false, true)
+	defer func() {
+		if lock == nil {
+			return
+		}
+		if err != nil {
+			_ = lock.Close()
+			return
+		}
+		p.mu.Lock()
+		p.locks = append(p.locks, lock)
+		p.mu.Unlock()
+	}()
+	dir := filepath.Join(p.cache, name)
+	stale := false
+	if isDir(dir) == nil {
+		f, err := p.verifyDir(dir, url, version, digest)
+		if err == nil {
+			// Best effort, the cache may be read only.
+			now := time.Now()
+			_ = os.Chtimes(dir, now, now)
+			return f, nil
+		}
+		stale = true
+	}
+
+	tmp := filepath.Join(p.cache, pkgCacheTmp)
+	if err = os.MkdirAll(tmp, 0o700); err != nil {
+		return nil, err
+	}
+	scratch, err := os.MkdirTemp(tmp, "pkg")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(scratch)
+	depdir, err := fetch(scratch)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = p.verifyDir(depdir, url, version, digest); err != nil {
+		return nil, err
+	}
+	// Move away the invalid entry found above. It is removed along scratch.
+	// Another process may have already replaced it with a valid one.
+	if stale {
+		if _, err = p.verifyDir(dir, url, version, digest); err != nil {
+			if err = os.Rename(dir, filepath.Join(scratch, "stale")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+	if err = os.Rename(depdir, dir); err != nil {
+		// Another process may have stored the package concurrently.
+		if f, err2 := p.verifyDir(dir, url, version, digest); err2 == nil {
+			return f, nil
+		}
+		return nil, err
+	}
+	return os.DirFS(dir), nil
+}
+
+// GCPackageCache removes from the package cache in dir the packages not used
+// for maxAge, and the leftovers of interrupted fetches. The packages removed
+// are written to w. The packages in use by a running shac process are kept.
+func GCPackageCache(dir string, maxAge time.Duration, w io.Writer) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, pkgCacheTmp)
+	if err = os.MkdirAll(tmp, 0o700); err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	var errs []error
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "h1-") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !fi.ModTime().Before(cutoff) {
+			continue
+		}
+		lock, err := lockCachedPkg(dir, e.Name(), true, false)
+		if errors.Is(err, errLocked) {
+			continue
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// Rename first so a shac process starting to use the package after
+		// the lock is released doesn't see a partially deleted package.
+		p := filepath.Join(dir, e.Name())
+		trash, err := os.MkdirTemp(tmp, "gc")
+		if err == nil {
+			if err = os.Rename(p, filepath.Join(trash, e.Name())); err == nil {
+				err = os.RemoveAll(trash)
+				fmt.Fprintf(w, "Removed %s\n", p)
+			}
+		}
+		_ = lock.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Scratch directories are only used for the duration of a fetch.
+	scratches, err := os.ReadDir(tmp)
+	if err != nil {
+		return err
+	}
+	for _, e := range scratches {
+		if fi, err := e.Info(); err == nil && fi.ModTime().Before(time.Now().Add(-24*time.Hour)) {
+			if err = os.RemoveAll(filepath.Join(tmp, e.Name())); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPackageManager_Cache(t *testing.T) {
+	t.Parallel()
+	cache := t.TempDir()
+	clones := 0
+	gitCommand := func(ctx context.Context, d string, args ...string) error {
+		if args[0] == "clone" {
+			clones++
+			writeFile(t, filepath.Join(d, args[2]), "lib.star", "X = 1")
+		}
+		return nil
+	}
+	src := t.TempDir()
+	writeFile(t, src, "lib.star", "X = 1")
+	digest, err := FSToDigest(os.DirFS(src), "example.com/lib@1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := Document{
+		Requirements: &Requirements{Direct: []*Dependency{{Url: "example.com/lib", Version: "1"}}},
+		Sum:          &Sum{Known: []*Known{{Url: "example.com/lib", Seen: []*VersionDigest{{Version: "1", Digest: digest}}}}},
+	}
+	retrieve := func() *PackageManager {
+		t.Helper()
+		p := &PackageManager{root: t.TempDir(), cache: cache, gitCommand: gitCommand, pkgConcurrency: 1}
+		packages, err := p.RetrievePackages(context.Background(), t.TempDir(), &doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := fs.ReadFile(packages["example.com/lib"], "lib.star"); err != nil || string(b) != "X = 1" {
+			t.Fatalf("unexpected content %q: %v", b, err)
+		}
+		return p
+	}
+	if err = retrieve().Close(); err != nil {
+		t.Fatal(err)
+	}
+	if clones != 1 {
+		t.Fatalf("expected 1 clone, got %d", clones)
+	}
+	// Served from the cache without git.
+	p := retrieve()
+	if clones != 1 {
+		t.Fatalf("expected 1 clone, got %d", clones)
+	}
+	entry, err := pkgCacheEntry(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A modified package is fetched again.
+	writeFile(t, filepath.Join(cache, entry), "lib.star", "X = 2")
+	if err = retrieve().Close(); err != nil {
+		t.Fatal(err)
+	}
+	if clones != 2 {
+		t.Fatalf("expected 2 clones, got %d", clones)
+	}
+
+	// Recently used packages are kept.
+	if err = GCPackageCache(cache, time.Hour, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err = isDir(filepath.Join(cache, entry)); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err = os.Chtimes(filepath.Join(cache, entry), old, old); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, cache, "tmp/pkg123/partial", "x")
+	if err = os.Chtimes(filepath.Join(cache, "tmp", "pkg123"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	// Packages in use are kept.
+	out := strings.Builder{}
+	if err = GCPackageCache(cache, time.Hour, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = GCPackageCache(cache, time.Hour, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Removed " + filepath.Join(cache, entry) + "\n"; out.String() != want {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	entries, err := os.ReadDir(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "locks" || entries[1].Name() != "tmp" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+	if entries, err = os.ReadDir(filepath.Join(cache, "tmp")); err != nil || len(entries) != 0 {
+		t.Fatalf("unexpected entries %v: %v", entries, err)
+	}
+}
+
+func TestPackageManager_CacheConcurrentFetch(t *testing.T) {
+	t.Parallel()
+	cache := t.TempDir()
+	src := t.TempDir()
+	writeFile(t, src, "lib.star", "X = 1")
+	digest, err := FSToDigest(os.DirFS(src), "example.com/lib@1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := pkgCacheEntry(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(cache, entry)
+	var stored fs.FileInfo
+	p := &PackageManager{cache: cache}
+	defer p.Close()
+	_, err = p.ensureCachedPkg("example.com/lib", "1", digest, func(scratch string) (string, error) {
+		// Another process stores the package while this one fetches it.
+		writeFile(t, dir, "lib.star", "X = 1")
+		var err error
+		if stored, err = os.Stat(dir); err != nil {
+			return "", err
+		}
+		writeFile(t, scratch, "lib/lib.star", "X = 1")
+		return filepath.Join(scratch, "lib"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The valid package stored concurrently is not moved away.
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(stored, fi) {
+		t.Fatal("the package stored concurrently was replaced")
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// Deps resolves, pins and verifies the packages declared in the shac.textproto
+// at the root of a repository.
+//
+// Call Close() once done.
+type Deps struct {
+	// Force accepts a package whose digest changed since it was pinned at the
+	// same version, e.g. because a tag was moved. Otherwise Add and Update
+	// fail, since it may also be a compromised remote.
+	Force bool
+
+	root       string
+	configFile string
+	content    []byte
+	doc        *Document
+	w          io.Writer
+	tmpdir     string
+	pkgMgr     *PackageManager
+	// fetched are the packages already fetched, keyed by url@version.
+	fetched map[string]fs.FS
+}
+
+// OpenDeps loads the shac.textproto of the repository containing dir.
+//
+// pkgCache is the directory of the persistent package cache, see
+// Options.PackageCache.
+//
+// Unlike the other commands, the sum of the packages doesn't have to be
+// complete since the point is to complete it. Progress is written to w.
+func OpenDeps(ctx context.Context, dir, pkgCache string, w io.Writer) (*Deps, error) {
+	root, err := resolveRoot(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &Deps{
+		root:       root,
+		configFile: filepath.Join(root, "shac.textproto"),
+		doc:        &Document{},
+		w:          w,
+		fetched:    map[string]fs.FS{},
+	}
+	if d.content, err = os.ReadFile(d.configFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err = unmarshalConfig(d.content, d.doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", d.configFile, err)
+	}
+	if d.tmpdir, err = os.MkdirTemp("", "shac"); err != nil {
+		return nil, err
+	}
+	if d.pkgMgr, err = newCachingPackageManager(d.tmpdir, pkgCache); err != nil {
+		_ = os.RemoveAll(d.tmpdir)
+		return nil, err
+	}
+	return d, nil
+}
+
+// Close releases the packages fetched.
+func (d *Deps) Close() error {
+	err := d.pkgMgr.Close()
+	if err2 := os.RemoveAll(d.tmpdir); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Add adds the package url at version as a direct requirement, or changes the
+// version of an existing requirement, and pins its digest along the digests
+// of its own requirements.
+//
+// If alias is empty, the alias of an existing requirement is kept. It fails if
+// the package was already pinned at version with a different digest, unless
+// d.Force is set.
+func (d *Deps) Add(ctx context.Context, url, version, alias string) (err error) {
+	if err = (&Dependency{Url: url, Version: version, Alias: alias}).Validate(); err != nil {
+		return fmt.Errorf("%s@%s: %w", url, version, err)
+	}
+	old := proto.Clone(d.doc).(*Document)
+	defer func() {
+		if err != nil {
+			d.doc = old
+		}
+	}()
+	if d.doc.Requirements == nil {
+		d.doc.Requirements = &Requirements{}
+	}
+	req := d.doc.Requirements
+	dep := &Dependency{Url: url}
+	prev := ""
+	if i := slices.IndexFunc(req.Indirect, func(x *Dependency) bool { return x.Url == url }); i != -1 {
+		// Promote the indirect requirement.
+		dep, prev = req.Indirect[i], req.Indirect[i].Version
+		req.Indirect = slices.Delete(req.Indirect, i, i+1)
+		req.Direct = append(req.Direct, dep)
+	} else if i := slices.IndexFunc(req.Direct, func(x *Dependency) bool { return x.Url == url }); i != -1 {
+		dep, prev = req.Direct[i], req.Direct[i].Version
+	} else {
+		req.Direct = append(req.Direct, dep)
+	}
+	dep.Version = version
+	if alias != "" {
+		dep.Alias = alias
+	}
+	closure, err := d.closure(ctx)
+	if err != nil {
+		return err
+	}
+	digest := ""
+	for _, c := range closure {
+		if c.Url != url && d.doc.Sum.Digest(c.Url, c.Version) != "" {
+			continue
+		}
+		h, err := d.digest(ctx, c.Url, c.Version)
+		if err != nil {
+			return err
+		}
+		if err = d.checkPinned(c.Url, c.Version, h); err != nil {
+			return err
+		}
+		d.setDigest(c.Url, c.Version, h)
+		if c.Url == url {
+			digest = h
+		} else {
+			fmt.Fprintf(d.w, "Pinned %s@%s %s\n", c.Url, c.Version, h)
+		}
+	}
+	if prev != "" && prev != version {
+		d.removeDigest(url, prev)
+	}
+	if err = d.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(d.w, "Added %s@%s %s\n", url, version, digest)
+	return nil
+}
+
+// Update pins the digest of every package required directly or transitively
+// and removes the sum entries no package references anymore.
+//
+// It fails without modifying shac.textproto if the digest of an already pinned
+// version changed, unless d.Force is set.
+func (d *Deps) Update(ctx context.Context) error {
+	closure, err := d.closure(ctx)
+	if err != nil {
+		return err
+	}
+	digests := make([]string, len(closure))
+	var errs []error
+	for i, dep := range closure {
+		if digests[i], err = d.digest(ctx, dep.Url, dep.Version); err != nil {
+			return err
+		}
+		if err = d.checkPinned(dep.Url, dep.Version, digests[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	for i, dep := range closure {
+		digest := digests[i]
+		if old := d.doc.Sum.Digest(dep.Url, dep.Version); old != digest {
+			if old != "" {
+				fmt.Fprintf(d.w, "Updated %s@%s from %s to %s\n", dep.Url, dep.Version, old, digest)
+			} else {
+				fmt.Fprintf(d.w, "Pinned %s@%s %s\n", dep.Url, dep.Version, digest)
+			}
+			d.setDigest(dep.Url, dep.Version, digest)
+		}
+	}
+	for _, u := range d.unreferenced(closure) {
+		fmt.Fprintf(d.w, "Removed unreferenced %s@%s\n", u.Url, u.Seen[0].Version)
+		d.removeDigest(u.Url, u.Seen[0].Version)
+	}
+	return d.save()
+}
+
+// Verify fetches every package required directly or transitively and confirms
+// its digest matches the sum.
+//
+// It returns an error listing the packages not matching and the sum entries no
+// package references.
+func (d *Deps) Verify(ctx context.Context) error {
+	closure, err := d.closure(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, dep := range closure {
+		digest, err := d.digest(ctx, dep.Url, dep.Version)
+		if err != nil {
+			return err
+		}
+		if want := d.doc.Sum.Digest(dep.Url, dep.Version); want == "" {
+			errs = append(errs, fmt.Errorf("%s@%s is missing from sum", dep.Url, dep.Version))
+		} else if want != digest {
+			errs = append(errs, fmt.Errorf("%s@%s has mismatched digest, got %s, expected %s", dep.Url, dep.Version, digest, want))
+		}
+	}
+	for _, u := range d.unreferenced(closure) {
+		errs = append(errs, fmt.Errorf("%s@%s is in sum but not referenced by any package", u.Url, u.Seen[0].Version))
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	fmt.Fprintf(d.w, "Verified %d packages\n", len(closure))
+	return nil
+}
+
+// Graph prints the requirements graph, one "<package> <requirement>" edge per
+// line, in the format of `go mod graph`. The repository is named __main__.
+//
+// The requirements of a package are the direct requirements declared in its
+// own shac.textproto.
+func (d *Deps) Graph(ctx context.Context, w io.Writer) error {
+	type node struct {
+		name string
+		deps []*Dependency
+	}
+	queue := []node{{"__main__", d.doc.GetRequirements().GetDirect()}}
+	seen := map[string]struct{}{}
+	for len(queue) != 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, dep := range n.deps {
+			key := dep.Url + "@" + dep.Version
+			if _, err := fmt.Fprintf(w, "%s %s\n", n.name, key); err != nil {
+				return err
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			f, err := d.fetch(ctx, dep.Url, dep.Version)
+			if err != nil {
+				return fmt.Errorf("%s couldn't be fetched: %w", key, err)
+			}
+			doc, err := packageConfig(f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			queue = append(queue, node{key, doc.GetRequirements().GetDirect()})
+		}
+	}
+	return nil
+}
+
+// requirements returns the direct and indirect requirements.
+func (d *Deps) requirements() []*Dependency {
+	return append(slices.Clone(d.doc.GetRequirements().GetDirect()), d.doc.GetRequirements().GetIndirect()...)
+}
+
+// closure returns the requirements and the requirements declared by the
+// packages, transitively.
+func (d *Deps) closure(ctx context.Context) ([]*Dependency, error) {
+	resolved, err := resolvePackages(ctx, d.doc, 1, func(ctx context.Context, dep *Dependency) (fs.FS, error) {
+		return d.fetch(ctx, dep.Url, dep.Version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Dependency, len(resolved))
+	for i, r := range resolved {
+		out[i] = r.dep
+	}
+	return out, nil
+}
+
+// fetch returns the content of the package, without verifying its digest.
+func (d *Deps) fetch(ctx context.Context, url, version string) (fs.FS, error) {
+	key := url + "@" + version
+	if f, ok := d.fetched[key]; ok {
+		return f, nil
+	}
+	f, err := d.pkgMgr.ensurePkg(ctx, d.root, d.doc.VendorPath, url, version, "")
+	if err != nil {
+		return nil, err
+	}
+	d.fetched[key] = f
+	return f, nil
+}
+
+// digest fetches the package and returns its digest.
+func (d *Deps) digest(ctx context.Context, url, version string) (string, error) {
+	f, err := d.fetch(ctx, url, version)
+	if err != nil {
+		return "", err
+	}
+	digest, err := FSToDigest(f, url+"@"+digestVersion(d.doc.VendorPath, url, version))
+	if err != nil {
+		return "", fmt.Errorf("%s@%s: hashing failed: %w", url, version, err)
+	}
+	return digest, nil
+}
+
+// checkPinned returns an error if a different digest is already pinned for the
+// version of the package, unless d.Force is set.
+func (d *Deps) checkPinned(url, version, digest string) error {
+	if old := d.doc.Sum.Digest(url, version); old != "" && old != digest && !d.Force {
+		return fmt.Errorf("%s@%s has mismatched digest, got %s, expected %s; use --force to accept the new content", url, version, digest, old)
+	}
+	return nil
+}
+
+// setDigest records the digest of the package in the sum, keeping the versions
+// sorted.
+func (d *Deps) setDigest(url, version, digest string) {
+	if d.doc.Sum == nil {
+		d.doc.Sum = &Sum{}
+	}
+	i := slices.IndexFunc(d.doc.Sum.Known, func(k *Known) bool { return k.Url == url })
+	if i == -1 {
+		d.doc.Sum.Known = append(d.doc.Sum.Known, &Known{Url: url})
+		i = len(d.doc.Sum.Known) - 1
+	}
+	k := d.doc.Sum.Known[i]
+	j, found := slices.BinarySearchFunc(k.Seen, version, func(vd *VersionDigest, v string) int {
+		switch {
+		case vd.Version < v:
+			return -1
+		case vd.Version > v:
+			return 1
+		}
+		return 0
+	})
+	if found {
+		k.Seen[j].Digest = digest
+	} else {
+		k.Seen = slices.Insert(k.Seen, j, &VersionDigest{Version: version, Digest: digest})
+	}
+}
+
+// removeDigest removes the version of the package from the sum.
+func (d *Deps) removeDigest(url, version string) {
+	if d.doc.Sum == nil {
+		return
+	}
+	for i, k := range d.doc.Sum.Known {
+		if k.Url != url {
+			continue
+		}
+		k.Seen = slices.DeleteFunc(k.Seen, func(vd *VersionDigest) bool { return vd.Version == version })
+		if len(k.Seen) == 0 {
+			d.doc.Sum.Known = slices.Delete(d.doc.Sum.Known, i, i+1)
+		}
+		return
+	}
+}
+
+// unreferenced returns the sum entries not referenced by any package of the
+// closure, with one version per item.
+func (d *Deps) unreferenced(closure []*Dependency) []*Known {
+	refs := map[string]struct{}{}
+	for _, dep := range closure {
+		refs[dep.Url+"@"+dep.Version] = struct{}{}
+	}
+	var out []*Known
+	for _, k := range d.doc.GetSum().GetKnown() {
+		for _, vd := range k.Seen {
+			if _, ok := refs[k.Url+"@"+vd.Version]; !ok {
+				out = append(out, &Known{Url: k.Url, Seen: []*VersionDigest{vd}})
+			}
+		}
+	}
+	return out
+}
+
+// save rewrites shac.textproto in place with the updated requirements and sum,
+// after having confirmed the result is valid.
+func (d *Deps) save() error {
+	if len(d.requirements()) == 0 {
+		d.doc.Requirements = nil
+	}
+	if len(d.doc.GetSum().GetKnown()) == 0 {
+		d.doc.Sum = nil
+	}
+	b, err := setPackages(d.content, d.doc.Requirements, d.doc.Sum)
+	if err != nil {
+		return fmt.Errorf("%s: %w", d.configFile, err)
+	}
+	doc := &Document{}
+	if err = unmarshalConfig(b, doc); err != nil {
+		return fmt.Errorf("%s: internal error while rewriting: %w", d.configFile, err)
+	}
+	if !proto.Equal(doc.Requirements, d.doc.Requirements) || !proto.Equal(doc.Sum, d.doc.Sum) {
+		return fmt.Errorf("%s: internal error while rewriting the packages", d.configFile)
+	}
+	if err = doc.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", d.configFile, err)
+	}
+	if string(b) == string(d.content) {
+		return nil
+	}
+	if err = os.WriteFile(d.configFile, b, 0o644); err != nil {
+		return err
+	}
+	d.content = b
+	return nil
+}
+
+// unmarshalConfig parses a shac.textproto document without validating it.
+func unmarshalConfig(b []byte, doc *Document) error {
+	// See readConfig() for the rationale of parsing twice.
+	opts := prototext.UnmarshalOptions{DiscardUnknown: true}
+	if err := opts.Unmarshal(b, doc); err != nil {
+		return err
+	}
+	if err := doc.CheckVersion(); err != nil {
+		return err
+	}
+	opts.DiscardUnknown = false
+	return opts.Unmarshal(b, doc)
+}
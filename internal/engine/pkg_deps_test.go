@@ -0,0 +1,228 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeps(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, root, "vendor/example.com/foo/checks.star", "def lint(ctx):", "    pass")
+	writeFile(t, root, "vendor/example.com/foo/shac.textproto",
+		"requirements {",
+		"  direct {",
+		"    url: \"example.com/bar\"",
+		"    version: \"1\"",
+		"  }",
+		"}")
+	writeFile(t, root, "vendor/example.com/bar/util.star", "X = 1")
+	writeFile(t, root, "shac.textproto",
+		"# Keep me.",
+		"vendor_path: \"vendor\"",
+		"ignore: \"/vendor/\"  # Keep me too.")
+	digest := func(url, version string) string {
+		d, err := FSToDigest(os.DirFS(filepath.Join(root, "vendor", url)), url+"@"+version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	ctx := context.Background()
+	d, err := OpenDeps(ctx, root, "", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err = d.Add(ctx, "example.com/foo", "1", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	want := "# Keep me.\n" +
+		"vendor_path: \"vendor\"\n" +
+		"ignore: \"/vendor/\"  # Keep me too.\n" +
+		"requirements {\n" +
+		"  direct {\n" +
+		"    url: \"example.com/foo\"\n" +
+		"    alias: \"foo\"\n" +
+		"    version: \"1\"\n" +
+		"  }\n" +
+		"}\n" +
+		"sum {\n" +
+		"  known {\n" +
+		"    url: \"example.com/foo\"\n" +
+		"    seen {\n" +
+		"      version: \"1\"\n" +
+		"      digest: \"" + digest("example.com/foo", "1") + "\"\n" +
+		"    }\n" +
+		"  }\n" +
+		"  known {\n" +
+		"    url: \"example.com/bar\"\n" +
+		"    seen {\n" +
+		"      version: \"1\"\n" +
+		"      digest: \"" + digest("example.com/bar", "1") + "\"\n" +
+		"    }\n" +
+		"  }\n" +
+		"}\n"
+	if b, err := os.ReadFile(filepath.Join(root, "shac.textproto")); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if err = d.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	b := strings.Builder{}
+	if err = d.Graph(ctx, &b); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("__main__ example.com/foo@1\nexample.com/foo@1 example.com/bar@1\n", b.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Bump the version, add a requirement without its digest and a stale entry.
+	writeFile(t, root, "shac.textproto",
+		"vendor_path: \"vendor\"",
+		"requirements {",
+		"  direct {",
+		"    url: \"example.com/foo\"",
+		"    version: \"2\"",
+		"  }",
+		"  # Needed by foo.",
+		"  indirect {",
+		"    url: \"example.com/bar\"",
+		"    version: \"1\"",
+		"  }",
+		"}",
+		"sum {",
+		"  known {",
+		"    url: \"example.com/foo\"",
+		"    seen {",
+		"      version: \"1\"",
+		"      digest: \""+digest("example.com/foo", "1")+"\"",
+		"    }",
+		"  }",
+		"}")
+	if d, err = OpenDeps(ctx, root, "", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	err = d.Verify(ctx)
+	wantErr := "example.com/foo@2 is missing from sum\n" +
+		"example.com/bar@1 is missing from sum\n" +
+		"example.com/foo@1 is in sum but not referenced by any package"
+	if err == nil || err.Error() != wantErr {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := strings.Builder{}
+	d.w = &out
+	if err = d.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wantOut := "Pinned example.com/foo@2 " + digest("example.com/foo", "2") + "\n" +
+		"Pinned example.com/bar@1 " + digest("example.com/bar", "1") + "\n" +
+		"Removed unreferenced example.com/foo@1\n"
+	if diff := cmp.Diff(wantOut, out.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if err = d.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+	doc := Document{}
+	if _, err = readConfig(filepath.Join(root, "shac.textproto"), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "shac.textproto")); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(b), "  # Needed by foo.\n  indirect {\n") {
+		t.Fatalf("comment lost:\n%s", b)
+	}
+
+	// Promoting an indirect requirement.
+	if err = d.Add(ctx, "example.com/bar", "1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.doc.Requirements.Direct[1].Url; got != "example.com/bar" || len(d.doc.Requirements.Indirect) != 0 {
+		t.Fatalf("unexpected requirements: %s", d.doc.Requirements)
+	}
+
+	// A modified package is detected.
+	writeFile(t, root, "vendor/example.com/bar/util.star", "X = 2")
+	if d, err = OpenDeps(ctx, root, "", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	err = d.Verify(ctx)
+	wantErr = "example.com/bar@1 has mismatched digest, got " + digest("example.com/bar", "1") +
+		", expected " + d.doc.Sum.Digest("example.com/bar", "1")
+	if err == nil || err.Error() != wantErr {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The modified package is not pinned again unless forced.
+	before := readFile(t, filepath.Join(root, "shac.textproto"))
+	wantErr += "; use --force to accept the new content"
+	if err = d.Update(ctx); err == nil || err.Error() != wantErr {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = d.Add(ctx, "example.com/bar", "1", ""); err == nil || err.Error() != wantErr {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(before, readFile(t, filepath.Join(root, "shac.textproto"))); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	out.Reset()
+	d.w = &out
+	d.Force = true
+	wantOut = "Updated example.com/bar@1 from " + d.doc.Sum.Digest("example.com/bar", "1") +
+		" to " + digest("example.com/bar", "1") + "\n"
+	if err = d.Update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantOut, out.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if err = d.Verify(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeps_Err(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, root, "shac.textproto", "vendor_path: \"vendor\"")
+	ctx := context.Background()
+	d, err := OpenDeps(ctx, root, "", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if err = d.Add(ctx, "example.com/foo", "a..b", ""); err == nil || err.Error() != "example.com/foo@a..b: version is invalid" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = d.Add(ctx, "example.com/foo", "1", "")
+	if want := "example.com/foo couldn't be fetched: vendored path " + filepath.Join(root, "vendor", "example.com", "foo") + " is missing"; err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
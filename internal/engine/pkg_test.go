@@ -15,13 +15,21 @@
 package engine
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
@@ -248,3 +256,277 @@ func TestPackageManager_Err(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestPackageManager_PendingRefDigest(t *testing.T) {
+	t.Parallel()
+	gitCommand := func(ctx context.Context, d string, args ...string) error {
+		if args[0] == "clone" {
+			writeFile(t, filepath.Join(d, args[2]), "lib.star", "X = 1")
+		}
+		return nil
+	}
+	// The digest of a pending ref hashes FETCH_HEAD, as it always did. Changing
+	// it would invalidate the digests already pinned in shac.textproto files.
+	const digest = "h1:l1Pc5TVQP0BD8vfoHd5WNrgvV5awsfUeZz3+BmuUqLU="
+	const version = "refs/changes/45/12345/12"
+	doc := Document{
+		Requirements: &Requirements{Direct: []*Dependency{{Url: "example.com/gerrit", Version: version}}},
+		Sum:          &Sum{Known: []*Known{{Url: "example.com/gerrit", Seen: []*VersionDigest{{Version: version, Digest: digest}}}}},
+	}
+	for _, cache := range []string{"", t.TempDir()} {
+		p := &PackageManager{root: t.TempDir(), cache: cache, gitCommand: gitCommand, pkgConcurrency: 1}
+		if _, err := p.RetrievePackages(context.Background(), t.TempDir(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// shac deps pins the same digest.
+	root := t.TempDir()
+	d, err := OpenDeps(context.Background(), root, t.TempDir(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	d.pkgMgr.gitCommand = gitCommand
+	got, err := d.digest(context.Background(), "example.com/gerrit", version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != digest {
+		t.Fatalf("unexpected digest %s", got)
+	}
+}
+
+func TestPackageManager_Transitive(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, root, "vendor/example.com/foo/shac.textproto",
+		"requirements {",
+		"  direct {",
+		"    url: \"example.com/bar\"",
+		"    alias: \"b\"",
+		"    version: \"1\"",
+		"  }",
+		"}")
+	writeFile(t, root, "vendor/example.com/bar/api.star", "X = 1")
+	digest := func(url, version string) string {
+		d, err := FSToDigest(os.DirFS(filepath.Join(root, "vendor", url)), url+"@"+version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	newDoc := func() *Document {
+		return &Document{
+			VendorPath: "vendor",
+			Requirements: &Requirements{
+				Direct: []*Dependency{{Url: "example.com/foo", Version: "1"}},
+			},
+			Sum: &Sum{
+				Known: []*Known{
+					{Url: "example.com/foo", Seen: []*VersionDigest{{Version: "1", Digest: digest("example.com/foo", "1")}}},
+				},
+			},
+		}
+	}
+	p := NewPackageManager(t.TempDir())
+	ctx := context.Background()
+
+	// The sum must cover the transitive requirements.
+	doc := newDoc()
+	_, err := p.RetrievePackages(ctx, root, doc)
+	want := "example.com/bar couldn't be fetched: example.com/bar@1 is missing from the sum of shac.textproto, run `shac deps update`"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.Sum.Known = append(doc.Sum.Known, &Known{Url: "example.com/bar", Seen: []*VersionDigest{{Version: "1", Digest: digest("example.com/bar", "1")}}})
+	packages, err := p.RetrievePackages(ctx, root, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for n := range packages {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	if diff := cmp.Diff([]string{"__main__", "b", "example.com/bar", "example.com/foo"}, names); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Conflicting versions.
+	doc.Requirements.Indirect = []*Dependency{{Url: "example.com/bar", Version: "2"}}
+	doc.Sum.Known[1].Seen = append(doc.Sum.Known[1].Seen, &VersionDigest{Version: "2", Digest: digest("example.com/bar", "2")})
+	_, err = p.RetrievePackages(ctx, root, doc)
+	want = "example.com/bar is required at conflicting versions: 2 by __main__, 1 by example.com/foo@1"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Conflicting aliases.
+	doc.Requirements.Indirect = nil
+	doc.Requirements.Direct[0].Alias = "b"
+	_, err = p.RetrievePackages(ctx, root, doc)
+	want = "alias b is used for both example.com/foo and example.com/bar"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// min_shac_version of the packages is enforced.
+	writeFile(t, root, "vendor/example.com/foo/shac.textproto", "min_shac_version: \"1000\"")
+	_, err = p.RetrievePackages(ctx, root, newDoc())
+	want = "example.com/foo@1: min_shac_version specifies unsupported version \"1000\", running " + Version.String()
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPackageManager_Sources(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "repo")
+	checks := filepath.Join(dir, "checks")
+	writeFile(t, root, "shac.star", "")
+	writeFile(t, checks, "api.star", "X = 1")
+	tgz := filepath.Join(dir, "checks.tar.gz")
+	writeArchive(t, tgz, map[string]string{"checks-1.0/api.star": "X = 1"})
+	zipFile := filepath.Join(dir, "checks.zip")
+	writeArchive(t, zipFile, map[string]string{"api.star": "X = 1"})
+	b, err := os.ReadFile(zipFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/checks.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	deps := []*Dependency{
+		{Url: "../checks", Alias: "local", Version: "1"},
+		{Url: "file://" + filepath.ToSlash(tgz), Alias: "tgz", Version: "1"},
+		{Url: "file://" + filepath.ToSlash(zipFile), Alias: "zip", Version: "1"},
+		{Url: srv.URL + "/checks.zip", Alias: "web", Version: "1"},
+	}
+	doc := Document{Requirements: &Requirements{Direct: deps}, Sum: &Sum{}}
+	for _, d := range deps {
+		digest, err := FSToDigest(os.DirFS(checks), d.Url+"@"+d.Version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc.Sum.Known = append(doc.Sum.Known, &Known{Url: d.Url, Seen: []*VersionDigest{{Version: d.Version, Digest: digest}}})
+	}
+	if err = doc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cache := t.TempDir()
+	retrieve := func() error {
+		p := NewPackageManager(t.TempDir())
+		p.cache = cache
+		p.httpClient = srv.Client()
+		packages, err := p.RetrievePackages(context.Background(), root, &doc)
+		if err != nil {
+			return err
+		}
+		for _, d := range deps {
+			if b, err := fs.ReadFile(packages[d.Alias], "api.star"); err != nil || string(b) != "X = 1" {
+				t.Errorf("%s: unexpected content %q: %v", d.Alias, b, err)
+			}
+		}
+		return nil
+	}
+	if err = retrieve(); err != nil {
+		t.Fatal(err)
+	}
+	// The archives are cached.
+	srv.Close()
+	if err = os.Remove(tgz); err != nil {
+		t.Fatal(err)
+	}
+	if err = retrieve(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The local directory is verified too.
+	writeFile(t, checks, "api.star", "X = 2")
+	if err = retrieve(); err == nil || !strings.Contains(err.Error(), "../checks couldn't be fetched: mismatched digest") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPackageManager_ArchiveErr(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	tgz := filepath.Join(dir, "evil.tar.gz")
+	writeArchive(t, tgz, map[string]string{"../evil.star": "X = 1"})
+	p := NewPackageManager(dir)
+	_, err := p.ensureArchivePkg(context.Background(), "file://"+filepath.ToSlash(tgz), "1", "")
+	if want := "file://" + filepath.ToSlash(tgz) + ": illegal path ../evil.star"; err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The archive and the files extracted from it are limited in size.
+	p.archiveLimit = 1000
+	big := strings.Repeat("X", 600)
+	for _, name := range []string{"big.tar.gz", "big.zip"} {
+		loc := "file://" + filepath.ToSlash(filepath.Join(dir, name))
+		writeArchive(t, filepath.Join(dir, name), map[string]string{"a.star": big, "b.star": big})
+		_, err = p.ensureArchivePkg(context.Background(), loc, "1", "")
+		// The file exceeding the limit depends on the order in the archive.
+		if err == nil || !strings.HasPrefix(err.Error(), loc+": extracting ") || !strings.HasSuffix(err.Error(), ": the files are larger than 1000 bytes in total") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	p.archiveLimit = 10
+	_, err = p.ensureArchivePkg(context.Background(), "file://"+filepath.ToSlash(tgz), "1", "")
+	if want := "reading file://" + filepath.ToSlash(tgz) + ": larger than 10 bytes"; err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// writeArchive writes a .tar.gz or a .zip file with the files.
+func writeArchive(t *testing.T, p string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if strings.HasSuffix(p, ".zip") {
+		z := zip.NewWriter(&buf)
+		for name, content := range files {
+			w, err := z.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = io.WriteString(w, content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := z.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for name, content := range files {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(tw, content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
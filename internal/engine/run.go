@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"maps"
@@ -107,10 +108,17 @@ const (
 type CheckFilter struct {
 	FormatterFiltering FormatterFiltering
 	// AllowList specifies checks to run. If non-empty, all other checks will be
-	// skipped.
+	// skipped, unless they have a tag in AllowTags. Each item is either a check
+	// name or a glob pattern as supported by path.Match.
 	AllowList []string
-	// DenyList specifies checks to skip.
+	// DenyList specifies checks to skip. Each item is either a check name or a
+	// glob pattern as supported by path.Match.
 	DenyList []string
+	// AllowTags specifies tags of checks to run. If non-empty, all other checks
+	// will be skipped, unless they are in AllowList.
+	AllowTags []string
+	// DenyTags specifies tags of checks to skip.
+	DenyTags []string
 }
 
 func (f *CheckFilter) filter(checks []*registeredCheck) ([]*registeredCheck, error) {
@@ -118,23 +126,14 @@ func (f *CheckFilter) filter(checks []*registeredCheck) ([]*registeredCheck, err
 		return checks, nil
 	}
 
-	allowList := make(map[string]struct{})
-	for _, name := range f.AllowList {
-		allowList[name] = struct{}{}
-	}
-	denyList := make(map[string]struct{})
-	for _, name := range f.DenyList {
-		denyList[name] = struct{}{}
-	}
-
 	var filtered []*registeredCheck
 	for _, check := range checks {
-		if len(f.AllowList) != 0 {
-			if _, ok := allowList[check.name]; !ok {
+		if len(f.AllowList) != 0 || len(f.AllowTags) != 0 {
+			if !matchesAny(f.AllowList, check.name) && !hasAnyTag(check, f.AllowTags) {
 				continue
 			}
 		}
-		if _, ok := denyList[check.name]; ok {
+		if matchesAny(f.DenyList, check.name) || hasAnyTag(check, f.DenyTags) {
 			continue
 		}
 		switch f.FormatterFiltering {
@@ -159,48 +158,94 @@ func (f *CheckFilter) filter(checks []*registeredCheck) ([]*registeredCheck, err
 // validate validates the filter configuration against the set of discovered
 // checks.
 func (f *CheckFilter) validate(shacStates []*shacState) error {
-	allowList := make(map[string]struct{})
-	for _, name := range f.AllowList {
-		allowList[name] = struct{}{}
-	}
-	var allowedAndDenied []string
-	denyList := make(map[string]struct{})
-	for _, name := range f.DenyList {
-		denyList[name] = struct{}{}
-		if _, ok := allowList[name]; ok {
-			allowedAndDenied = append(allowedAndDenied, name)
+	for _, p := range slices.Concat(f.AllowList, f.DenyList) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid check pattern %q: %w", p, err)
 		}
 	}
-	if len(allowedAndDenied) > 0 {
+	if both := intersect(f.AllowList, f.DenyList); len(both) > 0 {
 		return fmt.Errorf(
 			"checks cannot be both allowed and denied: %s",
-			strings.Join(allowedAndDenied, ", "))
+			strings.Join(both, ", "))
+	}
+	if both := intersect(f.AllowTags, f.DenyTags); len(both) > 0 {
+		return fmt.Errorf(
+			"tags cannot be both allowed and denied: %s",
+			strings.Join(both, ", "))
 	}
 
-	// Remove all known checks from the allowlist and denylist to validate that
-	// there are no invalid checks in either list.
+	// Remove all the items matching a known check from the lists to validate
+	// that there are no invalid checks or tags in either list.
+	unknownChecks := map[string]struct{}{}
+	for _, name := range slices.Concat(f.AllowList, f.DenyList) {
+		unknownChecks[name] = struct{}{}
+	}
+	unknownTags := map[string]struct{}{}
+	for _, tag := range slices.Concat(f.AllowTags, f.DenyTags) {
+		unknownTags[tag] = struct{}{}
+	}
 	for _, s := range shacStates {
 		for _, check := range s.checks {
-			delete(allowList, check.name)
-			delete(denyList, check.name)
+			for p := range unknownChecks {
+				if matchesAny([]string{p}, check.name) {
+					delete(unknownChecks, p)
+				}
+			}
+			for _, tag := range check.tags {
+				delete(unknownTags, tag)
+			}
 		}
 	}
-	if len(allowList) > 0 || len(denyList) > 0 {
-		var invalidChecks []string
-		invalidChecks = slices.AppendSeq(invalidChecks, maps.Keys(allowList))
-		invalidChecks = slices.AppendSeq(invalidChecks, maps.Keys(denyList))
-		var msg string
-		if len(invalidChecks) == 1 {
-			msg = "check does not exist"
-		} else {
-			msg = "checks do not exist"
-		}
-		slices.Sort(invalidChecks)
-		return fmt.Errorf("%s: %s", msg, strings.Join(invalidChecks, ", "))
+	if len(unknownChecks) > 0 {
+		return unknownError("check does not exist", "checks do not exist", unknownChecks)
+	}
+	if len(unknownTags) > 0 {
+		return unknownError("tag does not exist", "tags do not exist", unknownTags)
 	}
 	return nil
 }
 
+// matchesAny returns true if name is equal to or matches one of the glob
+// patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		// Patterns were validated by CheckFilter.validate().
+		if ok, _ := path.Match(p, name); ok || p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyTag returns true if the check has one of the tags.
+func hasAnyTag(check *registeredCheck, tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(check.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// intersect returns the items present in both a and b, in the order of b.
+func intersect(a, b []string) []string {
+	var out []string
+	for _, x := range b {
+		if slices.Contains(a, x) && !slices.Contains(out, x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func unknownError(singular, plural string, unknown map[string]struct{}) error {
+	msg := singular
+	if len(unknown) > 1 {
+		msg = plural
+	}
+	return fmt.Errorf("%s: %s", msg, strings.Join(slices.Sorted(maps.Keys(unknown)), ", "))
+}
+
 // Level is one of "notice", "warning" or "error".
 //
 // A check is only considered failed if it emits at least one finding with
@@ -233,6 +278,25 @@ func (l *Level) Type() string {
 	return "level"
 }
 
+// AtLeast returns true if l is as severe as o or more severe. Nothing is less
+// severe than any other level.
+func (l Level) AtLeast(o Level) bool {
+	return l.rank() >= o.rank()
+}
+
+func (l Level) rank() int {
+	switch l {
+	case Notice:
+		return 1
+	case Warning:
+		return 2
+	case Error:
+		return 3
+	default:
+		return 0
+	}
+}
+
 func (l Level) isValid() bool {
 	switch l {
 	case Notice, Warning, Error:
@@ -291,6 +355,13 @@ type Options struct {
 	AllFiles bool
 	// Recurse tells the engine to run all Main files found in subdirectories.
 	Recurse bool
+	// Base is the revision to compute the affected files, new lines and
+	// commits against. It defaults to the upstream branch, or HEAD~1 or HEAD
+	// if there is no upstream.
+	Base string
+	// MergeBase tells to diff against the merge base of HEAD and Base instead
+	// of Base itself.
+	MergeBase bool
 	// Filter controls which checks run.
 	Filter CheckFilter
 	// Vars contains the user-specified runtime variables and their values.
@@ -298,8 +369,65 @@ type Options struct {
 	// EntryPoint is the main source file to run. Defaults to shac.star.
 	EntryPoint string
 
+	// Stdin is the content of the only file in Files, to use instead of the
+	// content on disk, e.g. for a buffer not yet saved in an editor. The
+	// content on disk is used when nil, while an empty non-nil slice is an
+	// empty file.
 	Stdin []byte
 
+	// Timeout is the maximum duration of each check that doesn't specify its
+	// own timeout. 0 means no timeout.
+	Timeout time.Duration
+
+	// Trace, when set, receives a Chrome trace event JSON of the run, with the
+	// duration of the parsing of each Main file, of each check, of each
+	// subprocess and of each git invocation.
+	Trace io.Writer
+
+	// ShardIndex is the index of the shard to run, in [0, ShardCount).
+	ShardIndex int
+	// ShardCount is the number of shards the work is partitioned into. Each
+	// check is run by a single shard, except the checks that opted in to
+	// sharding their files, which are run by every shard on a subset of the
+	// files. 0 or 1 disables sharding.
+	ShardCount int
+
+	// KeepGoing runs all the checks to completion even if some fail
+	// abnormally, e.g. by calling fail(). Run() then returns a *ChecksError
+	// listing every failed check.
+	KeepGoing bool
+
+	// NoCache disables the caching of check results configured by cache_dir
+	// in shac.textproto.
+	NoCache bool
+
+	// PackageCache is the directory of the persistent cache of the packages
+	// fetched from git repositories and archives. Defaults to
+	// DefaultPackageCache(). When no default can be determined, the packages
+	// are fetched in a temporary directory on every run.
+	PackageCache string
+
+	// Baseline lists known findings that are neither reported nor fail the
+	// checks. Baseline.Stats() summarizes the matching once Run() returns.
+	Baseline *Baseline
+	// RecordBaseline, when set, receives all the findings emitted by the
+	// checks, including the ones suppressed by Baseline, so it can be saved
+	// as a new baseline.
+	RecordBaseline *Baseline
+
+	// FailOn is the lowest level of finding that makes Run() return
+	// ErrCheckFailed. Defaults to Error.
+	FailOn Level
+
+	// UnusedSuppressions is the level of the finding emitted for each
+	// shac:ignore directive that didn't suppress any finding of the check it
+	// names. Defaults to Nothing, which doesn't flag them.
+	UnusedSuppressions Level
+
+	// Verbose forwards the lines streamed from subprocesses with
+	// ctx.os.exec().stream() to Report.Print as they are read.
+	Verbose bool
+
 	// config is the configuration file. Defaults to shac.textproto. Only used in
 	// unit tests.
 	config string
@@ -311,24 +439,95 @@ func Run(ctx context.Context, o *Options) error {
 	if err != nil {
 		return err
 	}
+	var t *tracer
+	if o.Trace != nil {
+		t = newTracer()
+		ctx = context.WithValue(ctx, &tracerCtxKey, t)
+	}
 	err = runInner(ctx, o, tmpdir)
 	if err2 := os.RemoveAll(tmpdir); err == nil {
 		err = err2
 	}
+	if t != nil {
+		if err2 := t.write(o.Trace); err == nil {
+			err = err2
+		}
+	}
 	return err
 }
 
 func runInner(ctx context.Context, o *Options, tmpdir string) error {
-	root, err := resolveRoot(ctx, o.Dir)
+	r, err := newRunner(ctx, o, tmpdir)
 	if err != nil {
 		return err
 	}
+	defer r.close()
+	if err = r.load(ctx); err != nil {
+		return err
+	}
+	return r.runChecks(ctx, nil)
+}
+
+// runner holds everything needed to run checks that is independent of the
+// parsed Starlark code.
+//
+// It is split from the parsing and the execution of checks so Watch() can keep
+// it resident across runs.
+type runner struct {
+	o             *Options
+	root          string
+	entryPoint    string
+	config        string
+	configExists  bool
+	doc           *Document
+	scm           *cachingSCM
+	pkgMgr        *PackageManager
+	packages      map[string]fs.FS
+	sandbox       sandbox.Sandbox
+	tmpdir        string
+	subprocessSem *semaphore.Weighted
+	// cacheDir is the absolute path to the directory in which check results
+	// are cached. Empty if caching is disabled.
+	cacheDir string
+	// shard is the part of the work done by this run.
+	shard shard
+	// checkConfigs are the checks blocks of shac.textproto, keyed by check
+	// name.
+	checkConfigs map[string]*checkConfig
+	// baseline is nil unless Options.Baseline or Options.RecordBaseline is
+	// set.
+	baseline *baselineMatcher
+	// suppressions tracks the shac:ignore directives in use.
+	suppressions *suppressions
+
+	// Set by load().
+	env        *starlarkEnv
+	shacStates []*shacState
+	// registered are the names of all the registered checks, including the
+	// ones filtered out.
+	registered map[string]bool
+}
+
+// newRunner reads the configuration, initializes the SCM, retrieves the
+// packages and initializes the sandbox.
+func newRunner(ctx context.Context, o *Options, tmpdir string) (*runner, error) {
+	root, err := resolveRoot(ctx, o.Dir)
+	if err != nil {
+		return nil, err
+	}
 	entryPoint := o.EntryPoint
 	if entryPoint == "" {
 		entryPoint = DefaultEntryPoint
 	}
 	if filepath.IsAbs(entryPoint) {
-		return errors.New("entrypoint file must not be an absolute path")
+		return nil, errors.New("entrypoint file must not be an absolute path")
+	}
+	if o.FailOn != Nothing && !o.FailOn.isValid() {
+		return nil, fmt.Errorf("invalid FailOn value %q", o.FailOn)
+	}
+	sh, err := newShard(o.ShardIndex, o.ShardCount)
+	if err != nil {
+		return nil, err
 	}
 	config := o.config
 	if config == "" {
@@ -338,97 +537,143 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 	if !filepath.IsAbs(absConfig) {
 		absConfig = filepath.Join(root, absConfig)
 	}
-	var b []byte
 	doc := Document{}
-	configExists := false
-	if b, err = os.ReadFile(absConfig); err == nil {
-		configExists = true
-		// First parse the config file ignoring unknown fields and check only
-		// min_shac_version, so users get an "unsupported version" error if they
-		// set fields that are only available in a later version of shac (as
-		// long as min_shac_version is set appropriately).
-		opts := prototext.UnmarshalOptions{DiscardUnknown: true}
-		if err = opts.Unmarshal(b, &doc); err != nil {
-			return err
-		}
-		if err = doc.CheckVersion(); err != nil {
-			return err
-		}
-		// Parse the config file again, failing on any unknown fields.
-		opts.DiscardUnknown = false
-		if err = opts.Unmarshal(b, &doc); err != nil {
-			return err
-		}
-	} else if !errors.Is(err, fs.ErrNotExist) {
-		return err
+	configExists, err := readConfig(absConfig, &doc)
+	if err != nil {
+		return nil, err
 	}
-	if err = doc.Validate(); err != nil {
-		return err
+	checkConfigs, err := doc.checkConfigs()
+	if err != nil {
+		return nil, err
 	}
 
 	var scm scmCheckout
-	if len(o.Files) > 0 {
+	if o.Stdin != nil && len(o.Files) == 1 {
+		// Make a scm that is for just the one in-memory file
 		var files []file
-		files, err = normalizeFiles(o.Files, root)
+		var dirs []string
+		files, dirs, err = normalizeFiles(o.Files, root)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		var baseSCM scmCheckout
-		baseSCM, err = getSCM(ctx, root, false)
-		if err != nil {
-			return err
+		if len(dirs) != 0 {
+			return nil, fmt.Errorf("is a directory: %s", o.Files[0])
 		}
-		scm = &specifiedFilesOnly{files: files, root: root, base: baseSCM}
-	} else if len(o.Stdin) > 0 && len(o.Files) == 1 {
-		// Make a scm that is for just the one in-memory file
+		scm = &inMemoryFile{root: root, targetFile: files[0], data: o.Stdin}
+	} else if len(o.Files) > 0 {
 		var files []file
-		files, err = normalizeFiles(o.Files, root)
+		var dirs []string
+		files, dirs, err = normalizeFiles(o.Files, root)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		scm = &inMemoryFile{root: root, targetFile: files[0], data: o.Stdin}
-	} else {
-		scm, err = getSCM(ctx, root, o.AllFiles)
+		var baseSCM scmCheckout
+		baseSCM, err = getSCM(ctx, root, false, o.Base, o.MergeBase)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if len(doc.Ignore) > 0 {
-			var patterns []gitignore.Pattern
-			for _, p := range doc.Ignore {
-				if p == "" {
-					return errEmptyIgnore
-				}
-				patterns = append(patterns, gitignore.ParsePattern(p, nil))
+		if len(dirs) != 0 {
+			if files, err = expandDirs(ctx, baseSCM, &doc, files, dirs); err != nil {
+				return nil, err
 			}
+		}
+		scm = &specifiedFilesOnly{files: files, dirs: dirs, root: root, base: baseSCM}
+	} else {
+		scm, err = getSCM(ctx, root, o.AllFiles, o.Base, o.MergeBase)
+		if err != nil {
+			return nil, err
+		}
+		var matcher gitignore.Matcher
+		if matcher, err = doc.ignoreMatcher(); err != nil {
+			return nil, err
+		}
+		if matcher != nil {
 			scm = &filteredSCM{
-				matcher: gitignore.NewMatcher(patterns),
+				matcher: matcher,
 				scm:     scm,
 			}
 		}
 	}
 
-	// Always cache the SCM to avoid recomputing the same values multiple times.
-	scm = &cachingSCM{scm: scm}
-
-	pkgMgr := NewPackageManager(tmpdir)
+	pkgMgr, err := newCachingPackageManager(tmpdir, o.PackageCache)
+	if err != nil {
+		return nil, err
+	}
 	packages, err := pkgMgr.RetrievePackages(ctx, root, &doc)
 	if err != nil {
-		return err
+		_ = pkgMgr.Close()
+		return nil, err
 	}
 
 	sb, err := sandbox.New(tmpdir)
 	if err != nil {
-		return err
+		_ = pkgMgr.Close()
+		return nil, err
+	}
+	var baseline *baselineMatcher
+	if o.Baseline != nil || o.RecordBaseline != nil {
+		baseline = &baselineMatcher{root: root, files: &fileLines{root: root, scm: scm}, apply: o.Baseline, record: o.RecordBaseline}
+	}
+	cacheDir := ""
+	// The content of a file read from stdin is not on disk, so the cache
+	// can't be validated.
+	if doc.CacheDir != "" && !o.NoCache && o.Stdin == nil {
+		cacheDir = filepath.FromSlash(doc.CacheDir)
+		if !filepath.IsAbs(cacheDir) {
+			cacheDir = filepath.Join(root, cacheDir)
+		}
+	}
+	return &runner{
+		o:            o,
+		root:         root,
+		entryPoint:   entryPoint,
+		config:       config,
+		configExists: configExists,
+		doc:          &doc,
+		// Always cache the SCM to avoid recomputing the same values multiple
+		// times.
+		scm:           &cachingSCM{scm: scm},
+		pkgMgr:        pkgMgr,
+		packages:      packages,
+		sandbox:       sb,
+		tmpdir:        tmpdir,
+		subprocessSem: semaphore.NewWeighted(int64(maxConcurrency)),
+		cacheDir:      cacheDir,
+		shard:         sh,
+		checkConfigs:  checkConfigs,
+		baseline:      baseline,
+		suppressions:  &suppressions{root: root, scm: scm},
+	}, nil
+}
+
+// close releases the packages. The runner must not be used afterward.
+func (r *runner) close() {
+	_ = r.pkgMgr.Close()
+}
+
+// load discovers and parses all the shac.star files, then filters the
+// registered checks.
+//
+// It can be called multiple times, in which case all the Starlark code is
+// parsed again from scratch.
+func (r *runner) load(ctx context.Context) error {
+	// Clean up after a previous call.
+	for _, s := range r.shacStates {
+		if err := s.resetTempDir(); err != nil {
+			return err
+		}
 	}
-	env := starlarkEnv{
+	o := r.o
+	doc := r.doc
+	root := r.root
+	entryPoint := r.entryPoint
+	env := &starlarkEnv{
 		globals:  getPredeclared(),
 		sources:  map[string]*loadedSource{},
-		packages: packages,
+		packages: r.packages,
 		opts:     starlarkOptions(),
 	}
 
-	subprocessSem := semaphore.NewWeighted(int64(maxConcurrency))
-
 	var vars map[string]string
 
 	newState := func(scm scmCheckout, subdir string, idx int) (*shacState, error) {
@@ -442,10 +687,10 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 			}
 			for name, value := range o.Vars {
 				if _, ok := vars[name]; !ok {
-					if configExists {
-						return nil, fmt.Errorf("var not declared in %s: %s", config, name)
+					if r.configExists {
+						return nil, fmt.Errorf("var not declared in %s: %s", r.config, name)
 					}
-					return nil, fmt.Errorf("var must be declared in a %s file: %s", config, name)
+					return nil, fmt.Errorf("var must be declared in a %s file: %s", r.config, name)
 				}
 				vars[name] = value
 			}
@@ -472,22 +717,30 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 		}
 		return &shacState{
 			allowNetwork:              doc.AllowNetwork,
-			env:                       &env,
+			env:                       env,
 			filter:                    o.Filter,
 			entryPoint:                entryPoint,
 			r:                         o.Report,
 			root:                      root,
-			sandbox:                   sb,
+			sandbox:                   r.sandbox,
 			scm:                       scm,
 			subdir:                    subdir,
-			subprocessSem:             subprocessSem,
-			tmpdir:                    filepath.Join(tmpdir, strconv.Itoa(idx)),
+			subprocessSem:             r.subprocessSem,
+			tmpdir:                    filepath.Join(r.tmpdir, strconv.Itoa(idx)),
 			writableRoot:              doc.WritableRoot,
 			vars:                      vars,
 			passthroughEnv:            doc.PassthroughEnv,
+			execLimits:                doc.ExecLimits.limits(),
+			verbose:                   o.Verbose,
+			maxSteps:                  doc.MaxSteps,
+			shard:                     r.shard,
+			baseline:                  r.baseline,
+			suppressions:              r.suppressions,
+			unusedSuppressions:        o.UnusedSuppressions,
 			allowedFindingsProperties: allowedFindingsProps,
 		}, nil
 	}
+	var scm scmCheckout = r.scm
 	var shacStates []*shacState
 	if o.Recurse {
 		// Each found shac.star is run in its own interpreter for maximum
@@ -503,7 +756,8 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 		// This is also an optimization to avoid doing a `git ls-files` just to
 		// discover shac.star files when files to analyze are specified on the
 		// command line, since `git ls-files` is slow on large repositories.
-		if v, ok := scm.(overridesShacFileDirs); ok {
+		if v, ok := r.scm.scm.(overridesShacFileDirs); ok {
+			var err error
 			subdirs, err = v.shacFileDirs(entryPoint)
 			if err != nil {
 				return err
@@ -551,7 +805,10 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 	for _, s := range shacStates {
 		eg.Go(func() error {
 			stateCtx := context.WithValue(egCtx, &shacStateCtxKey, s)
-			if err := s.parse(stateCtx); err != nil {
+			end := ctxTracer(ctx).begin("parse", path.Join(s.subdir, s.entryPoint), nil)
+			err := s.parse(stateCtx)
+			end()
+			if err != nil {
 				return err
 			}
 			if len(s.checks) == 0 && !s.printCalled {
@@ -567,44 +824,154 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 	if err := o.Filter.validate(shacStates); err != nil {
 		return err
 	}
+	unknown := map[string]struct{}{}
+	for name := range r.checkConfigs {
+		unknown[name] = struct{}{}
+	}
+	registered := map[string]bool{}
+	for _, s := range shacStates {
+		for _, check := range s.checks {
+			delete(unknown, check.name)
+			registered[check.name] = true
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%s: %w", r.config, unknownError("check does not exist", "checks do not exist", unknown))
+	}
 
 	var hasChecksAfterFiltering bool
 	var totalChecks int
 	for _, s := range shacStates {
 		totalChecks += len(s.checks)
+		if err := s.resolveDeps(); err != nil {
+			return err
+		}
+		if err := s.applyConfigs(r.checkConfigs); err != nil {
+			return err
+		}
 		checks, err := s.filter.filter(s.checks)
 		if err != nil {
 			return err
 		}
-		s.checks = checks
+		// Checks that the selected checks depend on must run too. This also
+		// orders the checks so each one is started after its dependencies.
+		s.checks = withDeps(checks, s.checks)
 		if len(s.checks) > 0 {
 			hasChecksAfterFiltering = true
 		}
+		// A shard may legitimately have no check to run.
+		s.checks = s.shard.filterChecks(s.subdir, s.checks)
 	}
 	if totalChecks > 0 && !hasChecksAfterFiltering {
 		return errors.New("no checks to run")
 	}
+	r.env = env
+	r.shacStates = shacStates
+	r.registered = registered
+	return nil
+}
 
+// runChecks runs the checks loaded by load().
+//
+// If include is not nil, only the checks for which it returns true are run.
+func (r *runner) runChecks(ctx context.Context, include func(*registeredCheck) bool) error {
 	// Run all checks concurrently honoring the CPU limit.
-	eg, egCtx = errgroup.WithContext(ctx)
+	eg, egCtx := errgroup.WithContext(ctx)
+	if r.o.KeepGoing {
+		// Don't cancel the other checks when one fails. The errors are
+		// collected from the checks once they all completed.
+		eg, egCtx = &errgroup.Group{}, ctx
+	}
 	eg.SetLimit(maxConcurrency)
 
-	for _, s := range shacStates {
-		shacCtx, err := getCtx(path.Join(s.root, s.subdir), s.vars)
+	r.suppressions.reset()
+	if r.baseline != nil {
+		r.baseline.reset()
+	}
+	var cache *checkCache
+	if r.cacheDir != "" {
+		cache = &checkCache{dir: r.cacheDir, sources: r.env.sourcesDigest()}
+	}
+	// The warning is printed directly to the Report so it is not recorded in
+	// the cache, and at most once per run.
+	var limitsWarning sync.Once
+	warnLimits := func(ctx context.Context, check string, pos syntax.Position) {
+		limitsWarning.Do(func() {
+			r.o.Report.Print(ctx, check, pos.Filename(), int(pos.Line), "warning: the resource limits of ctx.os.exec() are not enforced on "+runtime.GOOS)
+		})
+	}
+	var ran []*registeredCheck
+	for _, s := range r.shacStates {
+		if err := s.resetTempDir(); err != nil {
+			return err
+		}
+		s.warnLimits = warnLimits
+		var rec *cacheRecorder
+		if cache != nil {
+			rec = &cacheRecorder{Report: r.o.Report, events: map[string][]cacheEvent{}}
+			s.r = rec
+		}
+		shacCtx, err := getCtx(path.Join(s.root, s.subdir), s.vars, scmBase(s.scm))
 		if err != nil {
 			return err
 		}
 		args := starlark.Tuple{shacCtx}
 		args.Freeze()
+		// s.checks is sorted so that dependencies are started first, which
+		// guarantees progress with the concurrency limit.
+		selected := map[*registeredCheck]bool{}
 		for _, check := range s.checks {
+			if include != nil && !include(check) && !slices.ContainsFunc(check.deps, func(d *registeredCheck) bool {
+				return selected[d]
+			}) {
+				continue
+			}
+			selected[check] = true
+			check.reset()
+			ran = append(ran, check)
 			eg.Go(func() error {
+				defer close(check.done)
 				stateCtx := context.WithValue(egCtx, &shacStateCtxKey, s)
+				for _, d := range check.deps {
+					select {
+					case <-d.done:
+					case <-stateCtx.Done():
+						return stateCtx.Err()
+					}
+					if d.failed {
+						check.failed = true
+						s.r.CheckCompleted(stateCtx, check.name, time.Now(), 0, Nothing, fmt.Errorf("skipped because dependency %q failed", d.name))
+						return nil
+					}
+				}
+				// The published result is not cached so the check must run
+				// when other checks depend on it.
+				if cache != nil && !check.hasDependents {
+					if hit, err := cache.replay(stateCtx, s, check, r.o.Report); hit || err != nil {
+						check.err = err
+						check.failed = err != nil || check.highestLevel == Error
+						return err
+					}
+				}
 				start := time.Now()
 				pi := func(th *starlark.Thread, msg string) {
 					pos := th.CallFrame(1).Pos
 					s.r.Print(stateCtx, check.name, pos.Filename(), int(pos.Line), msg)
 				}
-				err := check.call(stateCtx, s.env, args, pi)
+				timeout := check.timeout
+				if timeout == 0 {
+					timeout = r.o.Timeout
+				}
+				maxSteps := check.maxSteps
+				if maxSteps == 0 {
+					maxSteps = s.maxSteps
+				}
+				end := ctxTracer(ctx).begin("check", check.name, map[string]string{"dir": s.subdir})
+				err := check.call(stateCtx, s.env, args, pi, timeout, maxSteps)
+				end()
+				if err == nil && s.unusedSuppressions != Nothing {
+					err = s.flagUnusedSuppressions(stateCtx, check, s.unusedSuppressions)
+				}
 				if err != nil && stateCtx.Err() != nil {
 					// Don't report the check completion if the context was
 					// canceled. The error was probably caused by the context
@@ -613,25 +980,78 @@ func runInner(ctx context.Context, o *Options, tmpdir string) error {
 					// the canceled check failures.
 					return stateCtx.Err()
 				}
+				if err == nil && cache != nil {
+					if err2 := cache.save(stateCtx, s, check, rec.take(check.name)); err2 != nil {
+						log.Printf("failed to cache the result of %s: %s", check.name, err2)
+					}
+				}
+				check.err = err
+				check.failed = err != nil || check.highestLevel == Error
 				s.r.CheckCompleted(stateCtx, check.name, start, time.Since(start), check.highestLevel, err)
 				return err
 			})
 		}
 	}
 	if err := eg.Wait(); err != nil {
-		return err
+		if !r.o.KeepGoing || ctx.Err() != nil {
+			return err
+		}
+		errs := &ChecksError{}
+		for _, c := range ran {
+			if c.err != nil {
+				errs.Checks = append(errs.Checks, c.name)
+				errs.Errs = append(errs.Errs, c.err)
+			}
+		}
+		return errs
+	}
+	if r.baseline != nil {
+		if err := r.baseline.finish(ctx, r.shacStates, ran, r.registered); err != nil {
+			return err
+		}
 	}
 	// If any check failed, return an error.
-	for _, s := range shacStates {
-		for i := range s.checks {
-			if s.checks[i].highestLevel == Error {
-				return ErrCheckFailed
-			}
+	failOn := r.o.FailOn
+	if failOn == Nothing {
+		failOn = Error
+	}
+	for _, c := range ran {
+		if c.highestLevel != Nothing && c.highestLevel.AtLeast(failOn) {
+			return ErrCheckFailed
 		}
 	}
 	return nil
 }
 
+// readConfig reads and validates a shac.textproto file into doc.
+//
+// Returns false if the file doesn't exist, in which case doc is left empty.
+func readConfig(p string, doc *Document) (bool, error) {
+	b, err := os.ReadFile(p)
+	exists := err == nil
+	if exists {
+		// First parse the config file ignoring unknown fields and check only
+		// min_shac_version, so users get an "unsupported version" error if they
+		// set fields that are only available in a later version of shac (as
+		// long as min_shac_version is set appropriately).
+		opts := prototext.UnmarshalOptions{DiscardUnknown: true}
+		if err = opts.Unmarshal(b, doc); err != nil {
+			return exists, err
+		}
+		if err = doc.CheckVersion(); err != nil {
+			return exists, err
+		}
+		// Parse the config file again, failing on any unknown fields.
+		opts.DiscardUnknown = false
+		if err = opts.Unmarshal(b, doc); err != nil {
+			return exists, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return exists, err
+	}
+	return exists, doc.Validate()
+}
+
 // resolveRoot resolves an appropriate root directory from which to load shac
 // checks and analyze files.
 func resolveRoot(ctx context.Context, dir string) (string, error) {
@@ -674,19 +1094,20 @@ func resolveRoot(ctx context.Context, dir string) (string, error) {
 // and removes duplicates.
 //
 // Input paths may be absolute or relative. If relative, they are assumed to be
-// relative to the current working directory.
-func normalizeFiles(files []string, root string) ([]file, error) {
+// relative to the current working directory. Directories are returned
+// separately as POSIX paths, "." being the root itself.
+func normalizeFiles(files []string, root string) ([]file, []string, error) {
 	var cwd string
 	cwd, err := os.Getwd()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	resolvedRoot, err := filepath.EvalSymlinks(root)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var relativized []string
+	var relativized, dirs []string
 	for _, orig := range files {
 		f := orig
 		if !filepath.IsAbs(f) {
@@ -698,45 +1119,76 @@ func normalizeFiles(files []string, root string) ([]file, error) {
 			if errors.Is(err, os.ErrNotExist) {
 				// Make the error message more concise and use the original
 				// user-specified path rather than the normalized absolute path.
-				return nil, fmt.Errorf("no such file: %s", orig)
+				return nil, nil, fmt.Errorf("no such file: %s", orig)
 			}
-			return nil, err
-		}
-
-		// TODO(olivernewman): Support analyzing directories. This will require
-		// doing a filesystem traversal that respects the scm, so `shac check .`
-		// still ignores git-ignored files.
-		if fi.IsDir() {
-			return nil, fmt.Errorf("is a directory: %s", orig)
+			return nil, nil, err
 		}
 
 		f, err = filepath.EvalSymlinks(f)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		var rel string
 		rel, err = filepath.Rel(resolvedRoot, f)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		// Validates that the path is within the root directory (i.e.
 		// doesn't start with "..").
 		if !filepath.IsLocal(rel) {
-			return nil, fmt.Errorf("cannot analyze file outside root: %s", orig)
+			return nil, nil, fmt.Errorf("cannot analyze file outside root: %s", orig)
 		}
 
-		relativized = append(relativized, rel)
+		if fi.IsDir() {
+			dirs = append(dirs, filepath.ToSlash(rel))
+		} else {
+			relativized = append(relativized, rel)
+		}
 	}
 
 	slices.Sort(relativized)
 	relativized = slices.Compact(relativized)
+	slices.Sort(dirs)
+	dirs = slices.Compact(dirs)
 
 	var res []file
 	for _, f := range relativized {
 		res = append(res, &fileImpl{path: filepath.ToSlash(f)})
 	}
-	return res, nil
+	return res, dirs, nil
+}
+
+// expandDirs adds to files all the files known to the scm in the directories
+// dirs, except the ones matched by the ignore patterns in shac.textproto, then
+// sorts and removes duplicates.
+func expandDirs(ctx context.Context, scm scmCheckout, doc *Document, files []file, dirs []string) ([]file, error) {
+	m, err := doc.ignoreMatcher()
+	if err != nil {
+		return nil, err
+	}
+	all, err := scm.allFiles(ctx, fileFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range all {
+		p := f.rootedpath()
+		if !slices.ContainsFunc(dirs, func(d string) bool {
+			return d == "." || strings.HasPrefix(p, d+"/")
+		}) {
+			continue
+		}
+		if m != nil && m.Match(strings.Split(p, "/"), false) {
+			continue
+		}
+		files = append(files, &fileImpl{path: p})
+	}
+	slices.SortFunc(files, func(a, b file) int {
+		return strings.Compare(a.rootedpath(), b.rootedpath())
+	})
+	return slices.CompactFunc(files, func(a, b file) bool {
+		return a.rootedpath() == b.rootedpath()
+	}), nil
 }
 
 // shacState represents a parsing state of one shac.star.
@@ -770,6 +1222,25 @@ type shacState struct {
 	// filter controls which checks run. If nil, all checks will run.
 	filter         CheckFilter
 	passthroughEnv []*PassthroughEnv
+	// execLimits are the default resource limits of ctx.os.exec().
+	execLimits sandbox.Limits
+	// warnLimits prints a warning that the resource limits of ctx.os.exec()
+	// are not enforced by the sandbox. Set for each run.
+	warnLimits func(ctx context.Context, check string, pos syntax.Position)
+	// verbose forwards streamed subprocess output to Report.Print.
+	verbose bool
+	// maxSteps is the default maximum number of Starlark execution steps of
+	// each check.
+	maxSteps uint64
+	// shard is the part of the work done by this run.
+	shard shard
+	// baseline suppresses known findings. nil when not used.
+	baseline *baselineMatcher
+	// suppressions tracks the shac:ignore directives in use.
+	suppressions *suppressions
+	// unusedSuppressions is the level of the findings flagging unused
+	// shac:ignore directives, or Nothing.
+	unusedSuppressions Level
 
 	// allowedFindingsProperties is a map of the allowed property names for shac results.
 	allowedFindingsProperties map[string]bool
@@ -844,6 +1315,18 @@ func (s *shacState) newTempDir() (string, error) {
 	return p, nil
 }
 
+// resetTempDir deletes the temporary directories created by the previous run
+// of the checks, if any.
+func (s *shacState) resetTempDir() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tmpdirIndex == 0 {
+		return nil
+	}
+	s.tmpdirIndex = 0
+	return os.RemoveAll(s.tmpdir)
+}
+
 // registeredCheck represents one check that has been registered by
 // shac.register_check().
 type registeredCheck struct {
@@ -851,6 +1334,84 @@ type registeredCheck struct {
 	failErr      *failure // set when fail() is called from within the check, an abnormal failure.
 	highestLevel Level    // highest level emitted by EmitFinding.
 	subprocesses []*subprocess
+
+	// config is the checks block of shac.textproto for this check, if any.
+	// Set by applyConfigs().
+	config *checkConfig
+	// deps are the resolved check.deps. Set by resolveDeps().
+	deps []*registeredCheck
+	// hasDependents is true if another check depends on this one.
+	hasDependents bool
+	// done is closed once the check completed. failed and result must only be
+	// accessed after done is closed.
+	done chan struct{}
+	// failed is true if the check returned an error or emitted a finding with
+	// level "error", in which case the checks depending on it are skipped.
+	failed bool
+	// result is the value published via ctx.results.publish().
+	result starlark.Value
+	// err is the abnormal failure returned by the check, if any. It must only
+	// be accessed after done is closed.
+	err error
+
+	mu sync.Mutex
+	// inputs are the queries made by the check that may affect its results.
+	// Used by Watch() to determine which checks to run again and to validate
+	// cached results.
+	inputs []*checkInput
+}
+
+// reset clears the state of the previous run of the check.
+func (c *registeredCheck) reset() {
+	c.failErr = nil
+	c.highestLevel = Nothing
+	c.subprocesses = nil
+	c.done = make(chan struct{})
+	c.failed = false
+	c.result = nil
+	c.err = nil
+	c.mu.Lock()
+	c.inputs = nil
+	c.mu.Unlock()
+}
+
+// mapLevel returns the level of a finding emitted by the check, as remapped
+// by its configuration.
+func (c *registeredCheck) mapLevel(l Level) Level {
+	if c.config != nil {
+		if to, ok := c.config.levels[l]; ok {
+			return to
+		}
+	}
+	return l
+}
+
+// recordInput records a query made by the check.
+func (c *registeredCheck) recordInput(i *checkInput) {
+	c.mu.Lock()
+	c.inputs = append(c.inputs, i)
+	c.mu.Unlock()
+}
+
+// dependsOn returns true if any of the files could affect the result of the
+// check.
+//
+// files are relative to root, POSIX style. A check that never requested files
+// is assumed to depend on everything.
+func (c *registeredCheck) dependsOn(root string, files []string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.inputs) == 0 {
+		return true
+	}
+	for _, i := range c.inputs {
+		for _, f := range files {
+			if i.matches(root, f) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 var checkCtxKey = "shac.check"
@@ -866,15 +1427,36 @@ func ctxCheck(ctx context.Context) *registeredCheck {
 // call calls the check callback and returns an error if an abnormal error happened.
 //
 // A "normal" error will still have this function return nil.
-func (c *registeredCheck) call(ctx context.Context, env *starlarkEnv, args starlark.Tuple, pi printImpl) error {
+func (c *registeredCheck) call(ctx context.Context, env *starlarkEnv, args starlark.Tuple, pi printImpl, timeout time.Duration, maxSteps uint64) error {
 	ctx = context.WithValue(ctx, &checkCtxKey, c)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	th := env.thread(ctx, c.name, pi)
+	if maxSteps > 0 {
+		th.SetMaxExecutionSteps(maxSteps)
+	}
+	// Interrupt the Starlark code too, not only the subprocesses.
+	stop := context.AfterFunc(ctx, func() { th.Cancel(ctx.Err().Error()) })
+	defer stop()
 	if r, err := starlark.Call(th, c.impl, args, c.kwargs); err != nil {
+		if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("check %q %w after %s", c.name, ErrTimeout, timeout)
+		}
 		if c.failErr != nil {
 			// fail() was called, return this error since this is an abnormal failure.
 			return c.failErr
 		}
 		if evalErr, ok := errors.AsType[*starlark.EvalError](err); ok {
+			if maxSteps > 0 && th.ExecutionSteps() >= maxSteps {
+				// Point at the frame that was running when the budget ran out.
+				return &failure{
+					Message: fmt.Sprintf("check %q exceeded the maximum of %d execution steps", c.name, maxSteps),
+					Stack:   evalErr.CallStack,
+				}
+			}
 			return &evalError{evalErr}
 		}
 		// The vast majority of errors should be caught by the above checks, if
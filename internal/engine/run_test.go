@@ -15,12 +15,15 @@
 package engine
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -29,6 +32,7 @@ import (
 	"runtime"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -250,6 +254,76 @@ func TestRun_Fail(t *testing.T) {
 			}(),
 			"checks do not exist: also-does-not-exist, does-not-exist",
 		},
+		{
+			"allowlist pattern matching no check",
+			func() Options {
+				root := t.TempDir()
+				writeFile(t, root, "shac.star",
+					"def cb(ctx):",
+					"    pass",
+					"shac.register_check(cb)")
+				return Options{
+					Dir: root,
+					Filter: CheckFilter{
+						AllowList: []string{"c?x*"},
+					},
+				}
+			}(),
+			"check does not exist: c?x*",
+		},
+		{
+			"invalid allowlist pattern",
+			func() Options {
+				root := t.TempDir()
+				writeFile(t, root, "shac.star",
+					"def cb(ctx):",
+					"    pass",
+					"shac.register_check(cb)")
+				return Options{
+					Dir: root,
+					Filter: CheckFilter{
+						AllowList: []string{"cb["},
+					},
+				}
+			}(),
+			"invalid check pattern \"cb[\": syntax error in pattern",
+		},
+		{
+			"unknown tags",
+			func() Options {
+				root := t.TempDir()
+				writeFile(t, root, "shac.star",
+					"def cb(ctx):",
+					"    pass",
+					"shac.register_check(shac.check(cb, tags = [\"slow\"]))")
+				return Options{
+					Dir: root,
+					Filter: CheckFilter{
+						AllowTags: []string{"slow", "fast"},
+						DenyTags:  []string{"security"},
+					},
+				}
+			}(),
+			"tags do not exist: fast, security",
+		},
+		{
+			"tag allowed and denied",
+			func() Options {
+				root := t.TempDir()
+				writeFile(t, root, "shac.star",
+					"def cb(ctx):",
+					"    pass",
+					"shac.register_check(shac.check(cb, tags = [\"slow\"]))")
+				return Options{
+					Dir: root,
+					Filter: CheckFilter{
+						AllowTags: []string{"slow"},
+						DenyTags:  []string{"slow"},
+					},
+				}
+			}(),
+			"tags cannot be both allowed and denied: slow",
+		},
 		{
 			"invalid FormatterFiltering",
 			func() Options {
@@ -500,6 +574,52 @@ func TestRun_SpecificFiles(t *testing.T) {
 	})
 }
 
+func TestRun_SpecificFiles_Directory(t *testing.T) {
+	t.Parallel()
+
+	root := makeGit(t)
+	writeFile(t, root, "shac.textproto", prototext.Format(&Document{
+		Ignore: []string{"*.gen"},
+	}))
+	writeFile(t, root, ".gitignore", "*.log\n")
+	writeFile(t, root, "src/a.txt", "")
+	writeFile(t, root, "src/b.gen", "")
+	writeFile(t, root, "src/sub/c.txt", "")
+	writeFile(t, root, "other/d.txt", "")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-m", "Third commit")
+	// Untracked files are included, unless ignored by git.
+	writeFile(t, root, "src/untracked.txt", "")
+	writeFile(t, root, "src/e.log", "")
+
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(\"root: \" + \" \".join(ctx.scm.affected_files()))",
+		"shac.register_check(cb)")
+	writeFile(t, root, "src/sub/shac.star",
+		"def sub(ctx):",
+		"    print(\"sub: \" + \" \".join(ctx.scm.affected_files()))",
+		"shac.register_check(sub)")
+	// Not in the relevant subtree so not loaded.
+	writeFile(t, root, "other/shac.star", "fail(\"unexpected\")")
+
+	r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+	o := Options{
+		Report:  &r,
+		Dir:     root,
+		Files:   []string{filepath.Join(root, "src"), filepath.Join(root, "src", "a.txt"), filepath.Join(root, "z.txt")},
+		Recurse: true,
+	}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	want := "[//shac.star:2] root: src/a.txt src/sub/c.txt src/sub/shac.star src/untracked.txt z.txt\n" +
+		"[//src/sub/shac.star:2] sub: c.txt shac.star\n"
+	if diff := cmp.Diff(want, sortLines(r.b.String())); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestRun_AffectedFiles_ExcludeDirectories(t *testing.T) {
 	t.Parallel()
 
@@ -600,8 +720,6 @@ func TestRun_SpecificFiles_Fail(t *testing.T) {
 	t.Parallel()
 
 	root := t.TempDir()
-	subdir := filepath.Join(root, "dir")
-	mkdirAll(t, subdir)
 	dirOutsideRoot := t.TempDir()
 	writeFile(t, dirOutsideRoot, "outside-root.txt", "")
 
@@ -615,11 +733,6 @@ func TestRun_SpecificFiles_Fail(t *testing.T) {
 			files:   []string{filepath.Join(dirOutsideRoot, "outside-root.txt")},
 			wantErr: fmt.Sprintf("cannot analyze file outside root: %s", filepath.Join(dirOutsideRoot, "outside-root.txt")),
 		},
-		{
-			name:    "directory",
-			files:   []string{subdir},
-			wantErr: fmt.Sprintf("is a directory: %s", subdir),
-		},
 		{
 			name:    "nonexistent file",
 			files:   []string{filepath.Join(root, "nonexistent.txt")},
@@ -652,8 +765,8 @@ func TestRun_Filtering(t *testing.T) {
 		"    print(\"non-formatter running\")",
 		"def formatter(ctx):",
 		"    print(\"formatter running\")",
-		"shac.register_check(shac.check(formatter, formatter = True))",
-		"shac.register_check(shac.check(non_formatter))")
+		"shac.register_check(shac.check(formatter, formatter = True, tags = [\"fast\"]))",
+		"shac.register_check(shac.check(non_formatter, tags = [\"slow\", \"security\"]))")
 
 	data := []struct {
 		name   string
@@ -708,6 +821,37 @@ func TestRun_Filtering(t *testing.T) {
 			},
 			want: "[//shac.star:4] formatter running\n",
 		},
+		{
+			name: "allowlist pattern",
+			filter: CheckFilter{
+				AllowList: []string{"*formatter"},
+				DenyList:  []string{"non_*"},
+			},
+			want: "[//shac.star:4] formatter running\n",
+		},
+		{
+			name: "allowed tag",
+			filter: CheckFilter{
+				AllowTags: []string{"slow"},
+			},
+			want: "[//shac.star:2] non-formatter running\n",
+		},
+		{
+			name: "denied tag",
+			filter: CheckFilter{
+				DenyTags: []string{"security"},
+			},
+			want: "[//shac.star:4] formatter running\n",
+		},
+		{
+			name: "allowlist or allowed tag",
+			filter: CheckFilter{
+				AllowList: []string{"formatter"},
+				AllowTags: []string{"slow"},
+			},
+			want: "[//shac.star:2] non-formatter running\n" +
+				"[//shac.star:4] formatter running\n",
+		},
 	}
 	for i := range data {
 		t.Run(data[i].name, func(t *testing.T) {
@@ -830,6 +974,75 @@ func TestRun_Filtering_MultipleFiles(t *testing.T) {
 	}
 }
 
+func TestRun_CheckDeps(t *testing.T) {
+	t.Parallel()
+
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.star",
+		"def lint(ctx):",
+		"    print(\"lint running\")",
+		"    ctx.emit.finding(level = ctx.vars.get(\"level\"), message = \"bad\")",
+		"def analyze(ctx):",
+		"    print(\"analyze running\")",
+		"l = shac.check(lint)",
+		"shac.register_check(shac.check(analyze, deps = [l]))",
+		"shac.register_check(l)")
+	writeFile(t, root, "shac.textproto",
+		"vars: [",
+		"  {",
+		"    name: \"level\"",
+		"    default: \"error\"",
+		"  }",
+		"]")
+
+	data := []struct {
+		name   string
+		vars   map[string]string
+		filter CheckFilter
+		want   string
+		err    error
+	}{
+		{
+			name: "dependency failed",
+			want: "[//shac.star:2] lint running\n",
+			err:  ErrCheckFailed,
+		},
+		{
+			name: "dependency succeeded",
+			vars: map[string]string{"level": "warning"},
+			want: "[//shac.star:2] lint running\n" +
+				"[//shac.star:5] analyze running\n",
+		},
+		{
+			name:   "dependency not selected",
+			vars:   map[string]string{"level": "warning"},
+			filter: CheckFilter{AllowList: []string{"analyze"}},
+			want: "[//shac.star:2] lint running\n" +
+				"[//shac.star:5] analyze running\n",
+		},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			r := reportDeps{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}
+			o := Options{Report: &r, Dir: root, Vars: data[i].vars, Filter: data[i].filter}
+			if err := Run(context.Background(), &o); !errors.Is(err, data[i].err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(data[i].want, r.b.String()); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+			want := map[string]string{"lint": "", "analyze": ""}
+			if data[i].err != nil {
+				want["analyze"] = "skipped because dependency \"lint\" failed"
+			}
+			if diff := cmp.Diff(want, r.completed); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestRun_Ignore(t *testing.T) {
 	t.Parallel()
 	root := t.TempDir()
@@ -1093,6 +1306,644 @@ func TestRun_Exec_InvalidPATHElements(t *testing.T) {
 	testStarlarkPrint(t, root, "shac.star", false, false, want)
 }
 
+func TestRun_Timeout(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	root := resolvedTempDir(t)
+	// The shell spawns a child process that keeps stdout open, so the whole
+	// process tree must be killed for wait() to return.
+	writeFileBytes(t, root, "hang.sh", []byte("#!/bin/sh\nsleep 60\n"), 0o700)
+	writeFile(t, root, "shac.star",
+		"def loop(ctx):",
+		"    for _ in range(1 << 40):",
+		"        pass",
+		"def hang(ctx, timeout = None):",
+		"    ctx.os.exec([\"./hang.sh\"], timeout = timeout).wait()",
+		"shac.register_check(shac.check(loop, timeout = 0.1))",
+		"shac.register_check(shac.check(hang, name = \"exec_timeout\").with_args(timeout = 0.1))",
+		"shac.register_check(shac.check(hang, name = \"check_timeout\"))",
+		"shac.register_check(shac.check(hang, name = \"check_timeout_override\", timeout = 0.1))")
+
+	data := []struct {
+		name    string
+		check   string
+		timeout time.Duration
+		want    string
+	}{
+		{
+			name:  "starlark",
+			check: "loop",
+			want:  "check \"loop\" timed out after 100ms",
+		},
+		{
+			name:  "subprocess",
+			check: "exec_timeout",
+			want:  "wait: command timed out after 100ms: [./hang.sh]",
+		},
+		{
+			name:    "default",
+			check:   "check_timeout",
+			timeout: 100 * time.Millisecond,
+			want:    "check \"check_timeout\" timed out after 100ms",
+		},
+		{
+			name:    "override",
+			check:   "check_timeout_override",
+			timeout: time.Hour,
+			want:    "check \"check_timeout_override\" timed out after 100ms",
+		},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			o := Options{
+				Report:  &reportNoPrint{t: t},
+				Dir:     root,
+				Timeout: data[i].timeout,
+				Filter:  CheckFilter{AllowList: []string{data[i].check}},
+			}
+			start := time.Now()
+			err := Run(context.Background(), &o)
+			if !errors.Is(err, ErrTimeout) {
+				t.Fatalf("expected a timeout, got %v", err)
+			}
+			if diff := cmp.Diff(data[i].want, err.Error()); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+			if d := time.Since(start); d > 30*time.Second {
+				t.Fatalf("took %s", d)
+			}
+		})
+	}
+}
+
+func TestRun_Stream(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	root := resolvedTempDir(t)
+	writeFileBytes(t, root, "lines.sh", []byte("#!/bin/sh\necho first\necho second\nprintf third\necho oops >&2\n"), 0o700)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    proc = ctx.os.exec([\"./lines.sh\"])",
+		"    for line in proc.stream():",
+		"        print(\"line: \" + line)",
+		"    res = proc.wait()",
+		"    print(\"stdout: %r, stderr: %r\" % (res.stdout, res.stderr))",
+		"shac.register_check(cb)")
+
+	data := []struct {
+		name    string
+		verbose bool
+		want    string
+	}{
+		{
+			name: "quiet",
+			want: "[//shac.star:4] line: first\n" +
+				"[//shac.star:4] line: second\n" +
+				"[//shac.star:4] line: third\n" +
+				"[//shac.star:6] stdout: \"\", stderr: \"oops\\n\"\n",
+		},
+		{
+			name:    "verbose",
+			verbose: true,
+			want: "[//shac.star:3] first\n" +
+				"[//shac.star:4] line: first\n" +
+				"[//shac.star:3] second\n" +
+				"[//shac.star:4] line: second\n" +
+				"[//shac.star:3] third\n" +
+				"[//shac.star:4] line: third\n" +
+				"[//shac.star:6] stdout: \"\", stderr: \"oops\\n\"\n",
+		},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+			o := Options{Report: &r, Dir: root, Verbose: data[i].verbose}
+			if err := Run(context.Background(), &o); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(data[i].want, r.b.String()); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRun_ExitSignal(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	root := resolvedTempDir(t)
+	writeFileBytes(t, root, "term.sh", []byte("#!/bin/sh\nkill -TERM $$\n"), 0o700)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx, raise_on_failure = True):",
+		"    res = ctx.os.exec([\"./term.sh\"], raise_on_failure = raise_on_failure).wait()",
+		"    print(\"retcode: %d, signal: %s, core_dumped: %s\" % (res.retcode, res.signal, res.core_dumped))",
+		"shac.register_check(shac.check(cb, name = \"raise\"))",
+		"shac.register_check(shac.check(cb, name = \"no_raise\").with_args(raise_on_failure = False))")
+
+	t.Run("raise", func(t *testing.T) {
+		t.Parallel()
+		o := Options{Report: &reportNoPrint{t: t}, Dir: root, Filter: CheckFilter{AllowList: []string{"raise"}}}
+		err := Run(context.Background(), &o)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if diff := cmp.Diff("wait: command killed by SIGTERM: [./term.sh]", err.Error()); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("no_raise", func(t *testing.T) {
+		t.Parallel()
+		r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+		o := Options{Report: &r, Dir: root, Filter: CheckFilter{AllowList: []string{"no_raise"}}}
+		if err := Run(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		want := "[//shac.star:3] retcode: -1, signal: SIGTERM, core_dumped: False\n"
+		// nsjail exits with 128+N when the jailed process is killed by signal
+		// N.
+		wantNsjail := "[//shac.star:3] retcode: 143, signal: SIGTERM, core_dumped: False\n"
+		if got := r.b.String(); got != wantNsjail {
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		}
+	})
+}
+
+func TestRun_ExecLimitsKilled(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS != "linux" {
+		t.Skip("limits are only enforced on linux")
+	}
+
+	root := resolvedTempDir(t)
+	writeFileBytes(t, root, "kill.sh", []byte("#!/bin/sh\nkill -KILL $$\n"), 0o700)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    ctx.os.exec([\"./kill.sh\"], limits = {\"memory_mb\": 512, \"processes\": 8}).wait()",
+		"shac.register_check(cb)")
+	o := Options{Report: &reportNoPrint{t: t}, Dir: root}
+	err := Run(context.Background(), &o)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	// The limits are listed since the process could have been killed by the
+	// OOM killer.
+	want := "wait: command killed by SIGKILL, possibly for exceeding one of its limits (memory of 512 MiB, 8 processes): [./kill.sh]"
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_ExecLimitsNotEnforced(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    ctx.os.exec([\"sh\", \"-c\", \"true\"], limits = {\"open_files\": 64}).wait()",
+		"    ctx.os.exec([\"sh\", \"-c\", \"true\"], limits = {\"processes\": 8}).wait()",
+		"shac.register_check(cb)")
+	r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+	o := Options{Report: &r, Dir: root}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	// The warning is printed once per run.
+	want := "[//shac.star:1] warning: the resource limits of ctx.os.exec() are not enforced on " + runtime.GOOS + "\n"
+	if runtime.GOOS == "linux" && (runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64") {
+		want = ""
+	}
+	if diff := cmp.Diff(want, r.b.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_ExitCodeAbove128(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    res = ctx.os.exec([\"sh\", \"-c\", \"exit 255\"], ok_retcodes = [255]).wait()",
+		"    print(\"retcode: %d, signal: %s\" % (res.retcode, res.signal))",
+		"shac.register_check(cb)")
+	r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+	o := Options{Report: &r, Dir: root}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	// Not reported as a signal.
+	want := "[//shac.star:3] retcode: 255, signal: None\n"
+	if diff := cmp.Diff(want, r.b.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_MaxSteps(t *testing.T) {
+	t.Parallel()
+
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.star",
+		"def count(ctx):",
+		"    n = 0",
+		"    for _ in range(1000):",
+		"        n += 1",
+		"shac.register_check(shac.check(count, name = \"default\"))",
+		"shac.register_check(shac.check(count, name = \"override\", max_steps = 100000))")
+	writeFile(t, root, "shac.textproto", "max_steps: 100")
+
+	data := []struct {
+		check string
+		want  string
+	}{
+		{"default", "fail: check \"default\" exceeded the maximum of 100 execution steps"},
+		{"override", ""},
+	}
+	for i := range data {
+		t.Run(data[i].check, func(t *testing.T) {
+			t.Parallel()
+			o := Options{
+				Report: &reportNoPrint{t: t},
+				Dir:    root,
+				Filter: CheckFilter{AllowList: []string{data[i].check}},
+			}
+			err := Run(context.Background(), &o)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff(data[i].want, got); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRun_FailOn(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    ctx.emit.finding(level = \"warning\", message = \"meh\")",
+		"shac.register_check(cb)")
+	data := []struct {
+		failOn Level
+		want   error
+	}{
+		{Nothing, nil},
+		{Error, nil},
+		{Warning, ErrCheckFailed},
+		{Notice, ErrCheckFailed},
+	}
+	for i, l := range data {
+		o := Options{Report: &reportEmitNoPrint{reportNoPrint: reportNoPrint{t: t}}, Dir: root, FailOn: l.failOn}
+		if err := Run(context.Background(), &o); !errors.Is(err, l.want) {
+			t.Errorf("#%d: expected %v, got %v", i, l.want, err)
+		}
+	}
+	o := Options{Report: &reportNoPrint{t: t}, Dir: root, FailOn: "never"}
+	if err := Run(context.Background(), &o); err == nil || err.Error() != "invalid FailOn value \"never\"" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRun_CheckConfig(t *testing.T) {
+	t.Parallel()
+
+	all := `["a.go", "a_test.go", "b.txt", "shac.star", "shac.textproto"]`
+	data := []struct {
+		name      string
+		config    string
+		want      string
+		completed []string
+		err       error
+	}{
+		{
+			name:      "none",
+			want:      "[//shac.star:3] with_args default " + all + "\n",
+			completed: []string{"analyze", "lint", "other"},
+			err:       ErrCheckFailed,
+		},
+		{
+			name: "disabled",
+			config: "checks {\n" +
+				"  name: \"lint\"\n" +
+				"  disabled: true\n" +
+				"}\n",
+			completed: []string{"other"},
+		},
+		{
+			name: "levels",
+			config: "checks {\n" +
+				"  name: \"lint\"\n" +
+				"  levels {\n" +
+				"    from: \"error\"\n" +
+				"    to: \"warning\"\n" +
+				"  }\n" +
+				"}\n",
+			want: "[//shac.star:3] with_args default " + all + "\n" +
+				"[//shac.star:6] analyze default\n",
+			completed: []string{"analyze", "lint", "other"},
+		},
+		{
+			name: "files",
+			config: "checks {\n" +
+				"  name: \"lint\"\n" +
+				"  include: \"*.go\"\n" +
+				"  exclude: \"*_test.go\"\n" +
+				"}\n",
+			want:      "[//shac.star:3] with_args default [\"a.go\"]\n",
+			completed: []string{"analyze", "lint", "other"},
+			err:       ErrCheckFailed,
+		},
+		{
+			name: "vars and args",
+			config: "checks {\n" +
+				"  name: \"lint\"\n" +
+				"  levels {\n" +
+				"    from: \"error\"\n" +
+				"    to: \"notice\"\n" +
+				"  }\n" +
+				"  vars {\n" +
+				"    name: \"foo\"\n" +
+				"    value: \"config\"\n" +
+				"  }\n" +
+				"  args {\n" +
+				"    name: \"mode\"\n" +
+				"    value {\n" +
+				"      string_value: \"config\"\n" +
+				"    }\n" +
+				"  }\n" +
+				"}\n",
+			want: "[//shac.star:3] config config " + all + "\n" +
+				"[//shac.star:6] analyze default\n",
+			completed: []string{"analyze", "lint", "other"},
+		},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			root := t.TempDir()
+			writeFile(t, root, "shac.star",
+				"def lint(ctx, mode = \"default\"):",
+				"    files = sorted(ctx.scm.all_files())",
+				"    print(mode, ctx.vars.get(\"foo\"), files)",
+				"    ctx.emit.finding(level = \"error\", message = \"bad\")",
+				"def analyze(ctx):",
+				"    print(\"analyze\", ctx.vars.get(\"foo\"))",
+				"def other(ctx):",
+				"    pass",
+				"l = shac.check(lint).with_args(mode = \"with_args\")",
+				"shac.register_check(l)",
+				"shac.register_check(shac.check(analyze, deps = [l]))",
+				"shac.register_check(other)")
+			writeFile(t, root, "shac.textproto",
+				"vars {\n"+
+					"  name: \"foo\"\n"+
+					"  default: \"default\"\n"+
+					"}\n"+
+					data[i].config)
+			writeFile(t, root, "a.go", "")
+			writeFile(t, root, "a_test.go", "")
+			writeFile(t, root, "b.txt", "")
+			r := reportDeps{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}
+			o := Options{Report: &r, Dir: root}
+			if err := Run(context.Background(), &o); !errors.Is(err, data[i].err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(data[i].want, r.b.String()); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(data[i].completed, slices.Sorted(maps.Keys(r.completed))); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("unknown check", func(t *testing.T) {
+		t.Parallel()
+		root := t.TempDir()
+		writeFile(t, root, "shac.star",
+			"def lint(ctx):",
+			"    pass",
+			"shac.register_check(lint)")
+		writeFile(t, root, "shac.textproto",
+			"checks {",
+			"  name: \"lnit\"",
+			"}")
+		o := Options{Report: &reportNoPrint{t: t}, Dir: root}
+		err := Run(context.Background(), &o)
+		want := "shac.textproto: check does not exist: lnit"
+		if err == nil || err.Error() != want {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	})
+
+	t.Run("invalid arg", func(t *testing.T) {
+		t.Parallel()
+		root := t.TempDir()
+		writeFile(t, root, "shac.star",
+			"def lint(ctx, mode = None):",
+			"    pass",
+			"shac.register_check(lint)")
+		writeFile(t, root, "shac.textproto",
+			"checks {",
+			"  name: \"lint\"",
+			"  args {",
+			"    name: \"mdoe\"",
+			"  }",
+			"}")
+		o := Options{Report: &reportNoPrint{t: t}, Dir: root}
+		err := Run(context.Background(), &o)
+		want := "checks block for lint: invalid argument \"mdoe\", must be one of: (mode)"
+		if err == nil || err.Error() != want {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	})
+}
+
+func TestRun_KeepGoing(t *testing.T) {
+	t.Parallel()
+
+	root := resolvedTempDir(t)
+	writeFile(t, root, "shac.star",
+		"def fails(ctx):",
+		"    fail(\"boom\")",
+		"def divides(ctx):",
+		"    print(1 // 0)",
+		"def succeeds(ctx):",
+		"    print(\"done\")",
+		"shac.register_check(fails)",
+		"shac.register_check(divides)",
+		"shac.register_check(succeeds)")
+
+	r := reportDeps{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}
+	o := Options{Report: &r, Dir: root, KeepGoing: true}
+	err := Run(context.Background(), &o)
+	want := "2 checks failed:\n" +
+		"  fails: fail: boom\n" +
+		"  divides: floored division by zero"
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	wantTrace := "fails: Traceback (most recent call last):\n" +
+		"  //shac.star:2:9: in fails\n" +
+		"divides: Traceback (most recent call last):\n" +
+		"  //shac.star:4:13: in divides\n"
+	if diff := cmp.Diff(wantTrace, err.(BacktraceableError).Backtrace()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	wantCompleted := map[string]string{
+		"fails":    "fail: boom",
+		"divides":  "floored division by zero",
+		"succeeds": "",
+	}
+	if diff := cmp.Diff(wantCompleted, r.completed); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("[//shac.star:6] done\n", r.b.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Trace(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	root := makeGit(t)
+	writeFileBytes(t, root, "true.sh", []byte("#!/bin/sh\n"), 0o700)
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    ctx.os.exec([\"./true.sh\"]).wait()",
+		"shac.register_check(cb)")
+
+	var b bytes.Buffer
+	o := Options{Report: &reportNoPrint{t: t}, Dir: root, Trace: &b}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	spans := map[string]bool{}
+	for _, e := range got.TraceEvents {
+		if e.Phase != "X" || e.Duration < 0 || e.TID < 1 {
+			t.Errorf("unexpected event %+v", e)
+		}
+		if e.Category == "git" {
+			spans["git"] = true
+		} else {
+			spans[e.Category+" "+e.Name] = true
+		}
+	}
+	want := map[string]bool{
+		"git":                  true,
+		"parse shac.star":      true,
+		"check cb":             true,
+		"wait subprocess slot": true,
+		"exec ./true.sh":       true,
+	}
+	if diff := cmp.Diff(want, spans); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Shard(t *testing.T) {
+	t.Parallel()
+
+	root := resolvedTempDir(t)
+	var files []string
+	for i := range 20 {
+		f := fmt.Sprintf("file%d.txt", i)
+		writeFile(t, root, f, "content")
+		files = append(files, f)
+	}
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    pass",
+		"def per_file(ctx):",
+		"    for f in ctx.scm.all_files():",
+		"        print(\"file \" + f)",
+		"def dep(ctx):",
+		"    pass",
+		"d = shac.check(dep)",
+		"shac.register_check(d)",
+		"shac.register_check(shac.check(cb, name = \"uses_dep\", deps = [d]))",
+		"[shac.register_check(shac.check(cb, name = \"check%d\" % i)) for i in range(10)]",
+		"shac.register_check(shac.check(per_file, shard_files = True))")
+	files = append(files, "shac.star")
+	slices.Sort(files)
+
+	const count = 3
+	var gotFiles []string
+	gotChecks := map[string]int{}
+	shardOf := map[string]int{}
+	for i := range count {
+		r := reportDeps{reportPrint: reportPrint{reportNoPrint: reportNoPrint{t: t}}}
+		o := Options{Report: &r, Dir: root, AllFiles: true, ShardIndex: i, ShardCount: count}
+		if err := Run(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		for c := range r.completed {
+			gotChecks[c]++
+			shardOf[c] = i
+		}
+		for l := range strings.SplitSeq(strings.TrimSpace(r.b.String()), "\n") {
+			if _, f, ok := strings.Cut(l, "] file "); ok {
+				gotFiles = append(gotFiles, f)
+			}
+		}
+	}
+	// Every check is run exactly once, except per_file which is run by every
+	// shard on a disjoint subset of the files.
+	wantChecks := map[string]int{"dep": 1, "uses_dep": 1, "per_file": count}
+	for i := range 10 {
+		wantChecks[fmt.Sprintf("check%d", i)] = 1
+	}
+	if diff := cmp.Diff(wantChecks, gotChecks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if shardOf["dep"] != shardOf["uses_dep"] {
+		t.Errorf("dep ran on shard %d but uses_dep ran on shard %d", shardOf["dep"], shardOf["uses_dep"])
+	}
+	slices.Sort(gotFiles)
+	if diff := cmp.Diff(files, gotFiles); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	o := Options{Report: &reportNoPrint{t: t}, Dir: root, ShardIndex: 3, ShardCount: 3}
+	err := Run(context.Background(), &o)
+	if err == nil || err.Error() != "invalid shard index 3, must be in [0, 3)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestRun_SCM_Raw(t *testing.T) {
 	t.Parallel()
 	root := t.TempDir()
@@ -1318,6 +2169,104 @@ func TestRun_SCM_Git_Upstream_Staged(t *testing.T) {
 	}
 }
 
+func TestRun_SCM_Git_Base(t *testing.T) {
+	t.Parallel()
+	root := makeGit(t)
+	runGit(t, root, "checkout", "-b", "feature", "HEAD~1")
+	writeFile(t, root, "f.txt", "feature")
+	runGit(t, root, "add", "f.txt")
+	runGit(t, root, "commit", "-m", "Feature commit")
+	runGit(t, root, "checkout", "master")
+	writeFile(t, root, "b.txt", "third")
+	runGit(t, root, "add", "b.txt")
+	runGit(t, root, "commit", "-m", "Third commit")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(ctx.scm.base.ref + \" \" + ctx.scm.base.hash)",
+		"    print(\" \".join(ctx.scm.affected_files()))",
+		"    print(str(len(ctx.scm.commits())))",
+		"shac.register_check(cb)")
+	// shac.star is untracked so it is always affected.
+	first := runGit(t, root, "rev-parse", "HEAD~2")
+	data := []struct {
+		base      string
+		mergeBase bool
+		want      string
+	}{
+		{
+			"",
+			false,
+			"[//shac.star:2] HEAD~1 " + runGit(t, root, "rev-parse", "HEAD~1") + "\n" +
+				"[//shac.star:3] b.txt shac.star\n" +
+				"[//shac.star:4] 1\n",
+		},
+		{
+			"HEAD~2",
+			false,
+			"[//shac.star:2] HEAD~2 " + first + "\n" +
+				"[//shac.star:3] a.txt b.txt shac.star z.txt\n" +
+				"[//shac.star:4] 2\n",
+		},
+		{
+			// f.txt is deleted compared to the base.
+			"feature",
+			false,
+			"[//shac.star:2] feature " + runGit(t, root, "rev-parse", "feature") + "\n" +
+				"[//shac.star:3] a.txt b.txt shac.star z.txt\n" +
+				"[//shac.star:4] 2\n",
+		},
+		{
+			"feature",
+			true,
+			"[//shac.star:2] feature " + first + "\n" +
+				"[//shac.star:3] a.txt b.txt shac.star z.txt\n" +
+				"[//shac.star:4] 2\n",
+		},
+	}
+	for i := range data {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+			o := Options{Report: &r, Dir: root, Base: data[i].base, MergeBase: data[i].mergeBase}
+			if err := Run(context.Background(), &o); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(data[i].want, r.b.String()); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+		o := Options{Report: &r, Dir: root, Base: "missing"}
+		err := Run(context.Background(), &o)
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid base \"missing\": ") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("no git", func(t *testing.T) {
+		t.Parallel()
+		root := t.TempDir()
+		writeFile(t, root, "shac.star", "shac.register_check(shac.check(lambda ctx: print(ctx.scm.base), name = \"base\"))")
+		r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+		o := Options{Report: &r, Dir: root, MergeBase: true}
+		err := Run(context.Background(), &o)
+		if err == nil || err.Error() != "a diff base can only be used in a git checkout" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		o = Options{Report: &r, Dir: root}
+		if err = Run(context.Background(), &o); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("[//shac.star:1] None\n", r.b.String()); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
 func TestRun_SCM_Git_Untracked(t *testing.T) {
 	t.Parallel()
 
@@ -2219,6 +3168,11 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"ctx.os.exec: unexpected keyword argument \"unknown\"",
 			"  //ctx-os-exec-bad_arg.star:16:16: in cb\n",
 		},
+		{
+			"ctx-os-exec-bad_encoding.star",
+			"ctx.os.exec: for parameter \"encoding\": unsupported encoding \"utf-16\", must be one of \"utf-8\", \"latin-1\" or \"bytes\"",
+			"  //ctx-os-exec-bad_encoding.star:17:16: in cb\n",
+		},
 		{
 			"ctx-os-exec-bad_env_key.star",
 			"ctx.os.exec: \"env\" key is not a string: 1",
@@ -2229,11 +3183,21 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"ctx.os.exec: \"env\" value is not a string: 1",
 			"  //ctx-os-exec-bad_env_value.star:16:16: in cb\n",
 		},
+		{
+			"ctx-os-exec-bad_limits.star",
+			"ctx.os.exec: for parameter \"limits\": unknown limit \"memory\", must be one of memory_mb, cpu_seconds, open_files or processes",
+			"  //ctx-os-exec-bad_limits.star:16:16: in cb\n",
+		},
 		{
 			"ctx-os-exec-bad_stdin_type.star",
 			"ctx.os.exec: for parameter \"stdin\": got dict, want str or bytes",
 			"  //ctx-os-exec-bad_stdin_type.star:16:16: in cb\n",
 		},
+		{
+			"ctx-os-exec-bad_timeout.star",
+			"ctx.os.exec: for parameter \"timeout\": got string, want int or float",
+			"  //ctx-os-exec-bad_timeout.star:16:16: in cb\n",
+		},
 		{
 			"ctx-os-exec-bad_type_in_args.star",
 			"ctx.os.exec: for parameter \"cmd\": got list, want sequence of str",
@@ -2315,6 +3279,11 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"unhashable type: subprocess",
 			"  //ctx-os-exec-result_unhashable.star:20:16: in cb\n",
 		},
+		{
+			"ctx-os-exec-stream-after_wait.star",
+			"stream: wait was already called",
+			"  //ctx-os-exec-stream-after_wait.star:22:16: in cb\n",
+		},
 		{
 			"ctx-re-allmatches-no_arg.star",
 			"ctx.re.allmatches: missing argument for pattern",
@@ -2330,6 +3299,21 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"ctx.re.match: missing argument for pattern",
 			"  //ctx-re-match-no_arg.star:16:17: in cb\n",
 		},
+		{
+			"ctx-results-get-frozen.star",
+			"append: cannot append to frozen list",
+			"  //ctx-results-get-frozen.star:19:30: in b\n",
+		},
+		{
+			"ctx-results-get-not_dep.star",
+			"ctx.results.get: for parameter \"check\": \"a\" is not a dependency of \"b\"",
+			"  //ctx-results-get-not_dep.star:19:20: in b\n",
+		},
+		{
+			"ctx-results-publish-twice.star",
+			"ctx.results.publish: can only be called once per check",
+			"  //ctx-results-publish-twice.star:17:24: in cb\n",
+		},
 		{
 			"ctx-scm-affected_files-arg.star",
 			"ctx.scm.affected_files: for parameter include_deleted: got string, want bool",
@@ -2414,6 +3398,27 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"cannot load ./load-recurse.star: //load-recurse.star was loaded in a cycle dependency graph",
 			"  //load-recurse.star:15:1: in <toplevel>\n",
 		},
+		{
+			"shac-check-deps-bad_type.star",
+			"shac.check: for parameter \"deps\": got string, want shac.check",
+			"  //shac-check-deps-bad_type.star:18:11: in <toplevel>\n",
+		},
+		{
+			"shac-check-deps-unknown.star",
+			"check \"b\" depends on \"a\" which is not registered",
+			"",
+		},
+		{
+			"shac-check-max_steps-negative.star",
+			"shac.check: for parameter \"max_steps\": must be positive, got -1",
+			"  //shac-check-max_steps-negative.star:19:31: in <toplevel>\n",
+		},
+		{
+			"shac-check-max_steps.star",
+			"fail: check \"cb\" exceeded the maximum of 1000 execution steps",
+			"  //shac-check-max_steps.star:17:10: in cb\n" +
+				"  //shac-check-max_steps.star:19:1: in _loop\n",
+		},
 		{
 			"shac-check-star_args.star",
 			"shac.check: \"impl\" must not accept *args",
@@ -2424,6 +3429,16 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"shac.check: \"impl\" must not accept **kwargs",
 			"  //shac-check-star_kwargs.star:18:31: in <toplevel>\n",
 		},
+		{
+			"shac-check-tags-bad_type.star",
+			"shac.check: for parameter \"tags\": got list, want sequence of str",
+			"  //shac-check-tags-bad_type.star:19:31: in <toplevel>\n",
+		},
+		{
+			"shac-check-timeout-negative.star",
+			"shac.check: for parameter \"timeout\": must be positive, got -1",
+			"  //shac-check-timeout-negative.star:18:11: in <toplevel>\n",
+		},
 		{
 			"shac-check-with_args-ctx.star",
 			"with_args: \"ctx\" argument cannot be overridden",
@@ -2469,6 +3484,11 @@ func TestTestDataFailOrThrow(t *testing.T) {
 			"shac.register_check: \"impl\" must not have a default value for the \"ctx\" parameter",
 			"  //shac-register_check-default_ctx.star:18:20: in <toplevel>\n",
 		},
+		{
+			"shac-register_check-deps_cycle.star",
+			"shac.register_check: check dependency cycle: b -> a -> b",
+			"  //shac-register_check-deps_cycle.star:23:20: in <toplevel>\n",
+		},
 		{
 			"shac-register_check-kwarg.star",
 			"shac.register_check: unexpected keyword argument \"invalid\"",
@@ -2842,6 +3862,13 @@ func TestTestDataPrint(t *testing.T) {
 				return "[//ctx-os-exec-abspath.star:17] Hello, world\n\n"
 			}(),
 		},
+		{
+			name: "ctx-os-exec-encoding.star",
+			want: "[//ctx-os-exec-encoding.star:24] None: \"caf\\xe9\\n\"\n" +
+				"[//ctx-os-exec-encoding.star:24] utf-8: \"caf\ufffd\\n\"\n" +
+				"[//ctx-os-exec-encoding.star:24] latin-1: \"café\\n\"\n" +
+				"[//ctx-os-exec-encoding.star:24] bytes: b\"caf\\xe9\\n\"\n",
+		},
 		{
 			name: "ctx-os-exec-env.star",
 			want: func() string {
@@ -2901,7 +3928,7 @@ func TestTestDataPrint(t *testing.T) {
 		},
 		{
 			name: "dir-ctx.star",
-			want: "[//dir-ctx.star:16] [\"emit\", \"io\", \"os\", \"platform\", \"re\", \"scm\", \"vars\"]\n",
+			want: "[//dir-ctx.star:16] [\"emit\", \"io\", \"os\", \"platform\", \"re\", \"results\", \"scm\", \"vars\"]\n",
 		},
 		{
 			name: "dir-shac.star",
@@ -2921,6 +3948,12 @@ func TestTestDataPrint(t *testing.T) {
 			name: "print-shac-version.star",
 			want: "[//print-shac-version.star:15] " + v + "\n",
 		},
+		{
+			name: "shac-check-deps.star",
+			want: "[//shac-check-deps.star:20] {\"files\": [\"a.txt\"]}\n" +
+				"[//shac-check-deps.star:24] 1\n" +
+				"[//shac-check-deps.star:25] {\"files\": [\"a.txt\"]}\n",
+		},
 		{
 			name: "shac-check-with_args.star",
 			want: "[//shac-check-with_args.star:33] print_hello_check: <check print_hello>\n" +
@@ -2963,7 +3996,7 @@ func TestTestDataPrint(t *testing.T) {
 				return "[//subprocess.star:20] str(proc): <subprocess \"" + cmd + "\">\n" +
 					"[//subprocess.star:21] type(proc): subprocess\n" +
 					"[//subprocess.star:22] bool(proc): True\n" +
-					"[//subprocess.star:23] dir(proc): [\"wait\"]\n"
+					"[//subprocess.star:23] dir(proc): [\"stream\", \"wait\"]\n"
 			}(),
 		},
 		{
@@ -3235,6 +4268,28 @@ func (r *reportPrint) Print(ctx context.Context, check, file string, line int, m
 	r.mu.Unlock()
 }
 
+// reportDeps records the findings and the checks completion errors.
+type reportDeps struct {
+	reportPrint
+	completed map[string]string
+}
+
+func (r *reportDeps) EmitFinding(ctx context.Context, check string, level Level, message, root, file string, s Span, replacements []string, props map[string]string) error {
+	return nil
+}
+
+func (r *reportDeps) CheckCompleted(ctx context.Context, check string, start time.Time, d time.Duration, l Level, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.completed == nil {
+		r.completed = map[string]string{}
+	}
+	r.completed[check] = ""
+	if err != nil {
+		r.completed[check] = err.Error()
+	}
+}
+
 type finding struct {
 	Check        string
 	Level        Level
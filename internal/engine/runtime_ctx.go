@@ -23,7 +23,7 @@ import (
 // getCtx returns the ctx object to pass to a registered check callback.
 //
 // Make sure to update //doc/stdlib.star whenever this function is modified.
-func getCtx(root string, vars map[string]string) (starlark.Value, error) {
+func getCtx(root string, vars map[string]string, base starlark.Value) (starlark.Value, error) {
 	starlarkVars := starlark.NewDict(len(vars))
 	for k, v := range vars {
 		if err := starlarkVars.SetKey(starlark.String(k), starlark.String(v)); err != nil {
@@ -54,9 +54,15 @@ func getCtx(root string, vars map[string]string) (starlark.Value, error) {
 			"match":      newBuiltin("ctx.re.match", ctxReMatch),
 			"allmatches": newBuiltin("ctx.re.allmatches", ctxReAllMatches),
 		}),
+		// Implemented in runtime_ctx_results.go
+		"results": toValue("ctx.results", starlark.StringDict{
+			"publish": newBuiltinNone("ctx.results.publish", ctxResultsPublish),
+			"get":     newBuiltin("ctx.results.get", ctxResultsGet),
+		}),
 		// Implemented in runtime_ctx_scm.go
 		"scm": toValue("ctx.scm", starlark.StringDict{
 			"root":           starlark.String(root),
+			"base":           base,
 			"affected_files": newBuiltin("ctx.scm.affected_files", ctxScmAffectedFiles),
 			"all_files":      newBuiltin("ctx.scm.all_files", ctxScmAllFiles),
 			"commits":        newBuiltin("ctx.scm.commits", ctxScmCommits),
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -104,9 +105,7 @@ func ctxEmitFinding(ctx context.Context, s *shacState, name string, args starlar
 		props = argproperties.unpackedProperties
 	}
 
-	if c.highestLevel == "" || level == Error || (level == Warning && c.highestLevel != Error) {
-		c.highestLevel = level
-	}
+	level = c.mapLevel(level)
 	root := ""
 	if file != "" {
 		root = filepath.Join(s.root, s.subdir)
@@ -119,7 +118,34 @@ func ctxEmitFinding(ctx context.Context, s *shacState, name string, args starlar
 			return fmt.Errorf("for parameter \"filepath\": %s is not tracked", argfilepath)
 		}
 	}
-	if err := s.r.EmitFinding(ctx, c.name, level, message, root, file, span, replacements, props); err != nil {
+	rooted := file
+	if file != "" {
+		rooted = path.Join(s.subdir, file)
+	}
+	if justification, ok := s.suppressions.match(c.name, rooted, span.Start.Line); ok {
+		if sr, ok := s.r.(SuppressionReport); ok {
+			if err := sr.EmitSuppressedFinding(ctx, c.name, level, message, root, file, span, justification); err != nil {
+				return fmt.Errorf("failed to emit: %w", err)
+			}
+		}
+		return nil
+	}
+	r := s.r
+	if rec, ok := r.(*cacheRecorder); ok {
+		// Record the finding even if the baseline suppresses it, the baseline
+		// is matched again when the cached result is replayed.
+		rec.record(c.name, cacheEvent{Kind: eventBaselineFinding, Level: level, Message: message, Root: root, File: file, Span: span, Replacements: replacements, Properties: props})
+		r = rec.Report
+	}
+	if s.baseline != nil {
+		if s.baseline.suppress(c.name, rooted, message, span.Start.Line) {
+			return nil
+		}
+	}
+	if c.highestLevel == "" || level == Error || (level == Warning && c.highestLevel != Error) {
+		c.highestLevel = level
+	}
+	if err := r.EmitFinding(ctx, c.name, level, message, root, file, span, replacements, props); err != nil {
 		return fmt.Errorf("failed to emit: %w", err)
 	}
 	return nil
@@ -314,6 +340,7 @@ func ctxEmitCommitMessageFinding(ctx context.Context, s *shacState, name string,
 		return fmt.Errorf("for parameter \"message\": must not be empty")
 	}
 
+	level = c.mapLevel(level)
 	if c.highestLevel == "" || level == Error || (level == Warning && c.highestLevel != Error) {
 		c.highestLevel = level
 	}
@@ -16,6 +16,8 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -54,8 +56,13 @@ func ctxIoReadFile(ctx context.Context, s *shacState, name string, args starlark
 			return nil, fmt.Errorf("for parameter \"filepath\": %s %w", argfilepath, err)
 		}
 	}
-	b, err := readFileImpl(dst, size)
-	if err != nil {
+	var err error
+	b, ok := inMemoryContent(s.scm, dst)
+	if ok {
+		if size > 0 && int64(len(b)) > size {
+			b = b[:size]
+		}
+	} else if b, err = readFileImpl(dst, size); err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			// Hide the underlying error for determinism.
 			return nil, fmt.Errorf("for parameter \"filepath\": %s not found", argfilepath)
@@ -63,6 +70,10 @@ func ctxIoReadFile(ctx context.Context, s *shacState, name string, args starlark
 		// Something other than a file not found error, return it as is.
 		return nil, fmt.Errorf("for parameter \"filepath\": %s %w", argfilepath, err)
 	}
+	if c := ctxCheck(ctx); c != nil {
+		d := sha256.Sum256(b)
+		c.recordInput(&checkInput{Kind: inputReadFile, Path: dst, Size: size, Digest: hex.EncodeToString(d[:])})
+	}
 	// TODO(maruel): Use unsafe conversion to save a memory copy.
 	return starlark.Bytes(b), nil
 }
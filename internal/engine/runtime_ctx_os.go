@@ -28,6 +28,7 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"time"
 
 	"go.fuchsia.dev/shac-project/shac/internal/execsupport"
 	"go.fuchsia.dev/shac-project/shac/internal/sandbox"
@@ -38,14 +39,21 @@ import (
 type subprocess struct {
 	cmd            *exec.Cmd
 	args           []string
-	stdout         *bytes.Buffer
+	stdout         *streamBuffer
 	stderr         *bytes.Buffer
 	raiseOnFailure bool
 	okRetcodes     []int
-	tempDir        string
-	errs           <-chan error
+	limits         sandbox.Limits
+	sandbox        sandbox.Sandbox
+	// encoding is how the output is decoded, one of "utf-8", "latin-1" or
+	// "bytes". If empty, the output is passed through unmodified as a str.
+	encoding string
+	tempDir  string
+	errs     <-chan error
 
 	waitCalled bool
+	// exitChecked is true once sandbox.Exit() was called.
+	exitChecked bool
 }
 
 var _ starlark.HasAttrs = (*subprocess)(nil)
@@ -71,6 +79,8 @@ func (s *subprocess) Hash() (uint32, error) {
 
 func (s *subprocess) Attr(name string) (starlark.Value, error) {
 	switch name {
+	case "stream":
+		return subprocessStreamBuiltin.BindReceiver(s), nil
 	case "wait":
 		return subprocessWaitBuiltin.BindReceiver(s), nil
 	default:
@@ -79,7 +89,7 @@ func (s *subprocess) Attr(name string) (starlark.Value, error) {
 }
 
 func (s *subprocess) AttrNames() []string {
-	return []string{"wait"}
+	return []string{"stream", "wait"}
 }
 
 func (s *subprocess) wait() (starlark.Value, error) {
@@ -96,7 +106,8 @@ func (s *subprocess) wait() (starlark.Value, error) {
 
 func (s *subprocess) waitInner() (starlark.Value, error) {
 	retcode := 0
-	if err := <-s.errs; err != nil {
+	err := <-s.errs
+	if err != nil {
 		if errExit, ok := errors.AsType[*exec.ExitError](err); ok {
 			retcode = errExit.ExitCode()
 		} else {
@@ -105,6 +116,10 @@ func (s *subprocess) waitInner() (starlark.Value, error) {
 		}
 	}
 
+	// It may append the sandbox's own errors to stderr.
+	st := s.sandbox.Exit(s.cmd)
+	s.exitChecked = true
+
 	// Limits output to 10Mib. If it needs more, a file should probably be used.
 	// If there is a use case, it's fine to increase.
 	const limit = 10 * 1024 * 1024
@@ -115,22 +130,72 @@ func (s *subprocess) waitInner() (starlark.Value, error) {
 		return nil, errors.New("process returned too much stderr")
 	}
 
+	signal := ""
+	if st.Signal != 0 {
+		signal = signalName(st.Signal)
+	}
 	if !slices.Contains(s.okRetcodes, retcode) && s.raiseOnFailure {
 		var msgBuilder strings.Builder
-		msgBuilder.WriteString(fmt.Sprintf("command failed with exit code %d: %s", retcode, s.args))
+		if l := s.limits.Exceeded(st); l != "" && signal != "" {
+			msgBuilder.WriteString(fmt.Sprintf("command exceeded %s (killed by %s): %s", l, signal, s.args))
+		} else if l != "" {
+			// The process limit makes process creation fail, the command
+			// exits by itself.
+			msgBuilder.WriteString(fmt.Sprintf("command exceeded %s (exit code %d): %s", l, retcode, s.args))
+		} else if signal != "" {
+			msgBuilder.WriteString(fmt.Sprintf("command killed by %s", signal))
+			if st.CoreDumped {
+				msgBuilder.WriteString(" (core dumped)")
+			}
+			// The kernel kills the process when the hard CPU time limit is
+			// exceeded, or the memory limit without cgroup v2, which can't
+			// be told apart from other causes.
+			if l := s.limits.Describe(); l != "" {
+				msgBuilder.WriteString(fmt.Sprintf(", possibly for exceeding one of its limits (%s)", l))
+			}
+			msgBuilder.WriteString(fmt.Sprintf(": %s", s.args))
+		} else {
+			msgBuilder.WriteString(fmt.Sprintf("command failed with exit code %d: %s", retcode, s.args))
+		}
 		if s.stderr.Len() > 0 {
 			msgBuilder.WriteString("\n")
 			msgBuilder.WriteString(s.stderr.String())
 		}
 		return nil, errors.New(msgBuilder.String())
 	}
+	var signalValue starlark.Value = starlark.None
+	if signal != "" {
+		signalValue = starlark.String(signal)
+	}
 	return toValue("completed_subprocess", starlark.StringDict{
-		"retcode": starlark.MakeInt(retcode),
-		"stdout":  starlark.String(s.stdout.String()),
-		"stderr":  starlark.String(s.stderr.String()),
+		"retcode":     starlark.MakeInt(retcode),
+		"signal":      signalValue,
+		"core_dumped": starlark.Bool(st.CoreDumped),
+		"stdout":      decodeOutput(s.stdout.Bytes(), s.encoding),
+		"stderr":      decodeOutput(s.stderr.Bytes(), s.encoding),
 	}), nil
 }
 
+// decodeOutput converts the output of a subprocess to a starlark value
+// according to encoding.
+func decodeOutput(b []byte, encoding string) starlark.Value {
+	switch encoding {
+	case "bytes":
+		return starlark.Bytes(b)
+	case "latin-1":
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return starlark.String(r)
+	case "utf-8":
+		// Invalid sequences are replaced so the string is always valid UTF-8.
+		return starlark.String(strings.ToValidUTF8(string(b), "\uFFFD"))
+	default:
+		return starlark.String(b)
+	}
+}
+
 func (s *subprocess) cleanup() error {
 	// Wait for the subprocess to launch before trying to kill it. s.startErrs
 	// gets closed after the subprocess starts, so even if the error has already
@@ -148,18 +213,108 @@ func (s *subprocess) cleanup() error {
 		// exit before cleaning up resources.
 		_ = s.cmd.Wait()
 	}
+	if !s.exitChecked {
+		// Release the resources of the sandbox.
+		_ = s.sandbox.Exit(s.cmd)
+		s.exitChecked = true
+	}
 
 	if err2 := os.RemoveAll(s.tempDir); err == nil {
 		err = err2
 	}
 
-	buffers.push(s.stdout)
+	s.stdout.release()
 	buffers.push(s.stderr)
 	s.stdout, s.stderr = nil, nil
 
 	return err
 }
 
+// subprocessStreamBuiltin implements the native function
+// ctx.os.exec().stream().
+//
+// It doesn't use newBoundBuiltin() since the position of the caller is needed
+// to forward the lines to Report.Print().
+//
+// Make sure to update //doc/stdlib.star whenever this function is modified.
+var subprocessStreamBuiltin = starlark.NewBuiltin("stream", func(th *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	const name = "stream"
+	pos := th.CallFrame(1).Pos
+	return builtinWrapper(th, name, func(ctx context.Context, s *shacState) (starlark.Value, error) {
+		if err := starlark.UnpackArgs(name, args, kwargs); err != nil {
+			return nil, err
+		}
+		proc := fn.Receiver().(*subprocess)
+		if proc.waitCalled {
+			return nil, fmt.Errorf("wait was already called")
+		}
+		l := &lines{proc: proc}
+		if s.verbose {
+			check := ctxCheck(ctx).name
+			l.print = func(line []byte) {
+				s.r.Print(ctx, check, pos.Filename(), int(pos.Line), strings.ToValidUTF8(string(line), "\uFFFD"))
+			}
+		}
+		return l, nil
+	})
+})
+
+// lines is the iterable of stdout lines returned by
+// ctx.os.exec().stream().
+type lines struct {
+	proc *subprocess
+	// print is called with each line read, only in verbose mode.
+	print func([]byte)
+}
+
+var _ starlark.Iterable = (*lines)(nil)
+
+func (l *lines) String() string {
+	return fmt.Sprintf("<lines %q>", strings.Join(l.proc.args, " "))
+}
+
+func (l *lines) Type() string {
+	return "lines"
+}
+
+func (l *lines) Truth() starlark.Bool {
+	return true
+}
+
+func (l *lines) Freeze() {
+}
+
+func (l *lines) Hash() (uint32, error) {
+	return 0, errors.New("unhashable type: lines")
+}
+
+func (l *lines) Iterate() starlark.Iterator {
+	return &linesIterator{l: l}
+}
+
+type linesIterator struct {
+	l *lines
+}
+
+func (i *linesIterator) Next(p *starlark.Value) bool {
+	// stdout is reset once wait() is called.
+	if i.l.proc.stdout == nil {
+		return false
+	}
+	line, ok := i.l.proc.stdout.readLine()
+	if !ok {
+		return false
+	}
+	if i.l.print != nil {
+		i.l.print(line)
+	}
+	*p = decodeOutput(line, i.l.proc.encoding)
+	return true
+}
+
+func (i *linesIterator) Done() {
+}
+
 var subprocessWaitBuiltin = newBoundBuiltin("wait", func(ctx context.Context, s *shacState, name string, self starlark.Value, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	if err := starlark.UnpackArgs(name, args, kwargs); err != nil {
 		return nil, err
@@ -178,6 +333,9 @@ func ctxOsExec(ctx context.Context, s *shacState, name string, args starlark.Tup
 	var argraiseOnFailure starlark.Bool = true
 	var argallowNetwork starlark.Bool
 	var argokRetcodes starlark.Value = starlark.None
+	var argtimeout starlark.Value = starlark.None
+	var arglimits = starlark.NewDict(0)
+	var argencoding starlark.Value = starlark.None
 	if err := starlark.UnpackArgs(name, args, kwargs,
 		"cmd", &argcmd,
 		"cwd?", &argcwd,
@@ -186,12 +344,36 @@ func ctxOsExec(ctx context.Context, s *shacState, name string, args starlark.Tup
 		"allow_network?", &argallowNetwork,
 		"ok_retcodes?", &argokRetcodes,
 		"raise_on_failure?", &argraiseOnFailure,
+		"timeout?", &argtimeout,
+		"limits?", &arglimits,
+		"encoding?", &argencoding,
 	); err != nil {
 		return nil, err
 	}
 	if argcmd.Len() == 0 {
 		return nil, errors.New("cmdline must not be an empty list")
 	}
+	timeout, err := toDuration(argtimeout)
+	if err != nil {
+		return nil, fmt.Errorf("for parameter \"timeout\": %w", err)
+	}
+	encoding := ""
+	if argencoding != starlark.None {
+		switch argencoding {
+		case starlark.String("utf-8"), starlark.String("latin-1"), starlark.String("bytes"):
+			encoding = string(argencoding.(starlark.String))
+		default:
+			return nil, fmt.Errorf("for parameter \"encoding\": unsupported encoding %s, must be one of \"utf-8\", \"latin-1\" or \"bytes\"", argencoding)
+		}
+	}
+	limits := s.execLimits
+	if err = updateLimits(&limits, arglimits); err != nil {
+		return nil, fmt.Errorf("for parameter \"limits\": %w", err)
+	}
+	if limits != (sandbox.Limits{}) && !s.sandbox.EnforcesLimits() {
+		c := ctxCheck(ctx)
+		s.warnLimits(ctx, c.name, c.impl.Position())
+	}
 
 	var okRetcodes []int
 	if argokRetcodes == starlark.None {
@@ -338,6 +520,7 @@ func ctxOsExec(ctx context.Context, s *shacState, name string, args starlark.Tup
 		Cwd:          cwd,
 		AllowNetwork: bool(argallowNetwork),
 		Env:          env,
+		Limits:       limits,
 	}
 	// config.Mounts is ignored for the moment on Windows.
 	if runtime.GOOS != "windows" {
@@ -404,27 +587,50 @@ func ctxOsExec(ctx context.Context, s *shacState, name string, args starlark.Tup
 		}
 	}
 
-	cmd := s.sandbox.Command(ctx, config)
+	// The command is killed when cmdCtx is canceled, either because the
+	// timeout expired or because the check is being aborted.
+	cmdCtx, cancel := context.WithCancelCause(ctx)
+	cmd := s.sandbox.Command(cmdCtx, config)
 
-	stdout, stderr := buffers.get(), buffers.get()
-	// TODO(olivernewman): Also handle commands that may output non-utf-8 bytes.
+	stdout, stderr := newStreamBuffer(), buffers.get()
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 	cmd.Stdin = stdin
 
+	procArgs := sequenceToStrings(argcmd)
 	errs := make(chan error, 1)
 	// Run the command in a non-blocking goroutine so exec() calls don't block
 	// if there's already the maximum number of subprocesses running. wait()
 	// will block until the subprocess starts *and* finishes.
 	go func() {
 		errs <- func() error {
-			if err := s.subprocessSem.Acquire(ctx, 1); err != nil {
+			defer cancel(nil)
+			t := ctxTracer(ctx)
+			traceArgs := map[string]string{"check": ctxCheck(ctx).name}
+			end := t.begin("wait", "subprocess slot", traceArgs)
+			err := s.subprocessSem.Acquire(ctx, 1)
+			end()
+			if err != nil {
 				return err
 			}
 			defer s.subprocessSem.Release(1)
+			// Only start the timer once the subprocess is allowed to run.
+			errTimeout := fmt.Errorf("command %w after %s: %s", ErrTimeout, timeout, procArgs)
+			if timeout > 0 {
+				t := time.AfterFunc(timeout, func() { cancel(errTimeout) })
+				defer t.Stop()
+			}
 			log.Printf("Running command: %s", cmd)
-			return execsupport.Run(ctx, cmd)
+			end = t.begin("exec", strings.Join(procArgs, " "), traceArgs)
+			err = execsupport.Run(cmdCtx, cmd)
+			end()
+			if err != nil && context.Cause(cmdCtx) == errTimeout {
+				return errTimeout
+			}
+			return err
 		}()
+		// Unblocks subprocess.stream() readers.
+		stdout.close()
 		// Signals to subprocess.wait() that the subprocess is done, whether or
 		// not it was successful.
 		close(errs)
@@ -432,11 +638,14 @@ func ctxOsExec(ctx context.Context, s *shacState, name string, args starlark.Tup
 
 	proc := &subprocess{
 		cmd:            cmd,
-		args:           sequenceToStrings(argcmd),
+		args:           procArgs,
 		stdout:         stdout,
 		stderr:         stderr,
 		raiseOnFailure: bool(argraiseOnFailure),
 		okRetcodes:     okRetcodes,
+		limits:         limits,
+		sandbox:        s.sandbox,
+		encoding:       encoding,
 		tempDir:        tempDir,
 		errs:           errs,
 	}
@@ -450,6 +659,52 @@ func ctxOsExec(ctx context.Context, s *shacState, name string, args starlark.Tup
 	return proc, nil
 }
 
+// updateLimits overrides the limits with the ones specified in d, a dict of
+// limit name to non-negative int. 0 removes the limit.
+func updateLimits(l *sandbox.Limits, d *starlark.Dict) error {
+	for _, item := range d.Items() {
+		k, ok := item[0].(starlark.String)
+		if !ok {
+			return fmt.Errorf("key is not a string: %s", item[0])
+		}
+		var v uint64
+		if i, ok := item[1].(starlark.Int); !ok {
+			return fmt.Errorf("value for %s is not an int: %s", k, item[1])
+		} else if v, ok = i.Uint64(); !ok {
+			return fmt.Errorf("value for %s must not be negative: %s", k, item[1])
+		}
+		switch k {
+		case "memory_mb":
+			l.MemoryMB = v
+		case "cpu_seconds":
+			l.CPUSeconds = v
+		case "open_files":
+			l.OpenFiles = v
+		case "processes":
+			l.Processes = v
+		default:
+			return fmt.Errorf("unknown limit %s, must be one of memory_mb, cpu_seconds, open_files or processes", k)
+		}
+	}
+	return nil
+}
+
+// toDuration converts a starlark number of seconds into a time.Duration. None
+// is converted to 0, meaning no timeout.
+func toDuration(v starlark.Value) (time.Duration, error) {
+	if v == starlark.None {
+		return 0, nil
+	}
+	f, ok := starlark.AsFloat(v)
+	if !ok {
+		return 0, fmt.Errorf("got %s, want int or float", v.Type())
+	}
+	if f <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", v)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
 // sequenceToInts converts a starlark sequence (list, tuple) into a slice of
 // ints.
 func sequenceToInts(s starlark.Sequence) []int {
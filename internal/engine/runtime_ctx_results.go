@@ -0,0 +1,83 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// ctxResultsPublish implements native function ctx.results.publish().
+//
+// The value is frozen so it can be safely shared with the dependent checks,
+// which run concurrently.
+//
+// Make sure to update //doc/stdlib.star whenever this function is modified.
+func ctxResultsPublish(ctx context.Context, s *shacState, name string, args starlark.Tuple, kwargs []starlark.Tuple) error {
+	var argvalue starlark.Value
+	if err := starlark.UnpackArgs(name, args, kwargs,
+		"value", &argvalue,
+	); err != nil {
+		return err
+	}
+	c := ctxCheck(ctx)
+	if c == nil {
+		return errors.New("can only be called from within a check")
+	}
+	if c.result != nil {
+		return errors.New("can only be called once per check")
+	}
+	argvalue.Freeze()
+	c.result = argvalue
+	return nil
+}
+
+// ctxResultsGet implements native function ctx.results.get().
+//
+// Make sure to update //doc/stdlib.star whenever this function is modified.
+func ctxResultsGet(ctx context.Context, s *shacState, name string, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var argcheck starlark.Value
+	if err := starlark.UnpackArgs(name, args, kwargs,
+		"check", &argcheck,
+	); err != nil {
+		return nil, err
+	}
+	var dep string
+	switch v := argcheck.(type) {
+	case *check:
+		dep = v.name
+	case starlark.String:
+		dep = string(v)
+	default:
+		return nil, fmt.Errorf("for parameter \"check\": got %s, want shac.check or str", argcheck.Type())
+	}
+	c := ctxCheck(ctx)
+	if c == nil {
+		return nil, errors.New("can only be called from within a check")
+	}
+	for _, d := range c.deps {
+		if d.name == dep {
+			// d.done is closed before c is started.
+			if d.result == nil {
+				return starlark.None, nil
+			}
+			return d.result, nil
+		}
+	}
+	return nil, fmt.Errorf("for parameter \"check\": %q is not a dependency of %q", dep, c.name)
+}
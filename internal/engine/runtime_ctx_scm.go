@@ -25,6 +25,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -115,6 +116,9 @@ func (f *fileImpl) getMetadata() starlark.Value {
 					f.newLines, f.err = s.scm.newLines(ctx, f)
 				}
 				f.mu.Unlock()
+				if c := ctxCheck(ctx); c != nil && f.err == nil {
+					c.recordInput(&checkInput{Kind: inputNewLines, Path: f.path, Action: f.a})
+				}
 				return f.newLines, f.err
 			}),
 		})
@@ -216,7 +220,7 @@ type inMemoryFile struct {
 	root       string
 }
 
-var _ scmCheckout = (*inMemoryFile)(nil)
+var _ overridesShacFileDirs = (*inMemoryFile)(nil)
 
 func (s *inMemoryFile) affectedFiles(ctx context.Context, filter fileFilter) ([]file, error) {
 	return []file{s.targetFile}, nil
@@ -237,11 +241,36 @@ func (s *inMemoryFile) commits(ctx context.Context) ([]scmCommit, error) {
 	return nil, nil
 }
 
+// inMemoryContent returns the content to use instead of the file on disk if
+// the absolute path p is the in-memory file of the scm.
+func inMemoryContent(s scmCheckout, p string) ([]byte, bool) {
+	switch v := s.(type) {
+	case *cachingSCM:
+		return inMemoryContent(v.scm, p)
+	case *subdirSCM:
+		return inMemoryContent(v.s, p)
+	case *inMemoryFile:
+		if filepath.Join(v.root, filepath.FromSlash(v.targetFile.rootedpath())) == p {
+			return v.data, true
+		}
+	}
+	return nil, false
+}
+
+// shacFileDirs returns all directories containing shac.star files that apply to
+// the file.
+func (s *inMemoryFile) shacFileDirs(basename string) ([]string, error) {
+	return ancestorShacFileDirs(s.root, []string{path.Dir(s.targetFile.rootedpath())}, basename)
+}
+
 // specifiedFilesOnly is an scm that returns only a specified set of files.
 type specifiedFilesOnly struct {
 	files []file
-	root  string
-	base  scmCheckout
+	// dirs are the directories specified on the command line, which were
+	// expanded into files. POSIX path style, relative to root.
+	dirs []string
+	root string
+	base scmCheckout
 }
 
 var _ overridesShacFileDirs = (*specifiedFilesOnly)(nil)
@@ -263,25 +292,42 @@ func (s *specifiedFilesOnly) commits(ctx context.Context) ([]scmCommit, error) {
 }
 
 // shacFileDirs returns all directories containing shac.star files that apply to
-// any of the listed files; i.e. every ancestor directory of one of the listed
-// files that contains a shac.star file.
+// any of the listed files or directories; i.e. every ancestor directory of one
+// of the listed files, or of one of the listed directories including itself,
+// that contains a shac.star file.
+//
+// shac.star files in a listed directory's subdirectories are found via the
+// expanded files.
 func (s *specifiedFilesOnly) shacFileDirs(basename string) ([]string, error) {
-	dirs := map[string]struct{}{}
+	dirs := slices.Clone(s.dirs)
 	for _, f := range s.files {
-		for cur := path.Dir(f.rootedpath()); ; cur = path.Dir(cur) {
-			dirs[cur] = struct{}{}
+		dirs = append(dirs, path.Dir(f.rootedpath()))
+	}
+	return ancestorShacFileDirs(s.root, dirs, basename)
+}
+
+// ancestorShacFileDirs returns every directory in dirs or one of their
+// ancestors that contains a shac.star file.
+func ancestorShacFileDirs(root string, dirs []string, basename string) ([]string, error) {
+	seen := map[string]struct{}{}
+	for _, d := range dirs {
+		for cur := d; ; cur = path.Dir(cur) {
+			if _, ok := seen[cur]; ok {
+				break
+			}
+			seen[cur] = struct{}{}
 			if cur == "." {
 				break
 			}
 		}
 	}
 	var res []string
-	for dir := range dirs {
+	for dir := range seen {
 		// TODO(olivernewman): Check whether the shac.star file exists according
 		// to the scm, rather than just checking whether it exists on disk, but
 		// only if it's possible to do so without doing a full listing of all
 		// files in the scm.
-		_, err := os.Stat(filepath.Join(s.root, filepath.FromSlash(dir), basename))
+		_, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir), basename))
 		if errors.Is(err, os.ErrNotExist) {
 			continue
 		} else if err != nil {
@@ -289,6 +335,7 @@ func (s *specifiedFilesOnly) shacFileDirs(basename string) ([]string, error) {
 		}
 		res = append(res, dir)
 	}
+	slices.Sort(res)
 	return res, nil
 }
 
@@ -344,10 +391,10 @@ func (s *subdirSCM) commits(ctx context.Context) ([]scmCommit, error) {
 // getSCM returns the scmCheckout implementation relevant for directory root.
 //
 // root is must be a clean path.
-func getSCM(ctx context.Context, root string, allFiles bool) (scmCheckout, error) {
+func getSCM(ctx context.Context, root string, allFiles bool, base string, mergeBase bool) (scmCheckout, error) {
 	// Flip to POSIX style path.
 	root = strings.ReplaceAll(root, string(os.PathSeparator), "/")
-	g := &gitCheckout{returnAll: allFiles, checkoutRoot: root}
+	g := &gitCheckout{returnAll: allFiles, base: base, mergeBase: mergeBase, checkoutRoot: root}
 	err := g.init(ctx)
 	if err == nil {
 		if g.checkoutRoot != root {
@@ -380,10 +427,36 @@ func getSCM(ctx context.Context, root string, allFiles bool) (scmCheckout, error
 		// stop the next time `g.run` is called.
 		return nil, g.err
 	}
+	if base != "" || mergeBase {
+		return nil, errors.New("a diff base can only be used in a git checkout")
+	}
 	// TODO(maruel): Add the scm of your choice.
 	return &rawTree{root: root}, nil
 }
 
+// scmBase returns the commit the affected files are computed against as a
+// ctx.scm.base struct, or None if the scm doesn't diff against a commit.
+//
+// Make sure to update //doc/stdlib.star whenever this function is modified.
+func scmBase(s scmCheckout) starlark.Value {
+	switch v := s.(type) {
+	case *cachingSCM:
+		return scmBase(v.scm)
+	case *filteredSCM:
+		return scmBase(v.scm)
+	case *subdirSCM:
+		return scmBase(v.s)
+	case *specifiedFilesOnly:
+		return scmBase(v.base)
+	case *gitCheckout:
+		return toValue("ctx.scm.base", starlark.StringDict{
+			"hash": starlark.String(v.upstream.hash),
+			"ref":  starlark.String(v.upstream.ref),
+		})
+	}
+	return starlark.None
+}
+
 // cachingSCM wraps any other scmCheckout and memoizes return values.
 type cachingSCM struct {
 	scm scmCheckout
@@ -423,6 +496,117 @@ func (c *cachingSCM) allFiles(ctx context.Context, filter fileFilter) ([]file, e
 	return c.all[filter], err
 }
 
+// invalidateSCM forgets all the values memoized by s and the scmCheckout it
+// wraps, so that files changed on disk since are taken into account.
+func invalidateSCM(s scmCheckout) {
+	switch v := s.(type) {
+	case *cachingSCM:
+		v.mu.Lock()
+		v.affected = nil
+		v.all = nil
+		v.commitsVal = nil
+		v.commitsLoaded = false
+		v.commitsErr = nil
+		v.mu.Unlock()
+		invalidateSCM(v.scm)
+	case *filteredSCM:
+		invalidateSCM(v.scm)
+	case *subdirSCM:
+		invalidateSCM(v.s)
+	case *rawTree:
+		v.mu.Lock()
+		v.all = nil
+		v.mu.Unlock()
+	}
+}
+
+// unwrapGit returns the git checkout wrapped by s, if any, and the
+// subdirectory of the checkout that s is rooted at. The subdirectory is a POSIX
+// path ending with "/", or empty.
+func unwrapGit(s scmCheckout) (*gitCheckout, string) {
+	switch v := s.(type) {
+	case *cachingSCM:
+		return unwrapGit(v.scm)
+	case *filteredSCM:
+		return unwrapGit(v.scm)
+	case *subdirSCM:
+		g, subdir := unwrapGit(v.s)
+		return g, subdir + v.subdir
+	case *gitCheckout:
+		return v, ""
+	}
+	return nil, ""
+}
+
+// reinitSCM resolves again the commits of the git checkout wrapped by s, so
+// that a commit made or checked out since is taken into account.
+func reinitSCM(ctx context.Context, s scmCheckout) error {
+	g, _ := unwrapGit(s)
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = nil
+	return g.init(ctx)
+}
+
+// ignoredDirs returns the untracked directories ignored by the version control
+// system wrapping s, relative to the root of s, POSIX style.
+//
+// The subdirectories of a returned directory are not returned.
+func ignoredDirs(ctx context.Context, s scmCheckout) ([]string, error) {
+	g, subdir := unwrapGit(s)
+	if g == nil {
+		return nil, nil
+	}
+	args := []string{"ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory"}
+	if subdir != "" {
+		args = append(args, "--", subdir)
+	}
+	o, err := runGitCmd(ctx, g.checkoutRoot, args...)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, p := range strings.Split(o, "\x00") {
+		if d, ok := strings.CutSuffix(p, "/"); ok && strings.HasPrefix(d, subdir) && d != strings.TrimSuffix(subdir, "/") {
+			out = append(out, d[len(subdir):])
+		}
+	}
+	return out, nil
+}
+
+// ignoredFiles returns the paths ignored by the version control system wrapping
+// s. The paths are relative to the root of s, POSIX style.
+func ignoredFiles(ctx context.Context, s scmCheckout, paths []string) ([]string, error) {
+	g, subdir := unwrapGit(s)
+	if g == nil || len(paths) == 0 {
+		return nil, nil
+	}
+	// -z is only supported with --stdin, so make sure the paths are not quoted.
+	args := []string{"-c", "core.quotePath=false", "check-ignore", "--"}
+	for _, p := range paths {
+		args = append(args, subdir+p)
+	}
+	// Don't use g.run() since git check-ignore exits with 1 when no path is
+	// ignored.
+	o, err := runGitCmd(ctx, g.checkoutRoot, args...)
+	if err != nil {
+		if ee, ok := errors.AsType[*exec.ExitError](err); ok && ee.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []string
+	for _, p := range strings.Split(o, "\n") {
+		if p != "" {
+			out = append(out, strings.TrimPrefix(p, subdir))
+		}
+	}
+	return out, nil
+}
+
 func (c *cachingSCM) newLines(ctx context.Context, fi file) (starlark.Value, error) {
 	return c.scm.newLines(ctx, fi)
 }
@@ -450,6 +634,10 @@ const (
 type gitCheckout struct {
 	// Configuration.
 	returnAll bool
+	// base is the revision to diff against. Defaults to the upstream.
+	base string
+	// mergeBase tells to diff against the merge base of HEAD and the base.
+	mergeBase bool
 
 	// Detected environment at initialization.
 	// checkoutRoot is a POSIX path.
@@ -468,6 +656,24 @@ func (g *gitCheckout) init(ctx context.Context) error {
 		// Not worth continuing.
 		return g.err
 	}
+	if g.base != "" {
+		g.upstream.ref = g.base
+		g.upstream.hash = g.run(ctx, "rev-parse", "--verify", "--end-of-options", g.base+"^{commit}")
+		if g.err != nil {
+			g.err = fmt.Errorf("invalid base %q: %w", g.base, g.err)
+		}
+	} else {
+		g.initUpstream(ctx)
+	}
+	if g.mergeBase {
+		g.upstream.hash = g.run(ctx, "merge-base", g.upstream.hash, g.head.hash)
+	}
+	return g.err
+}
+
+// initUpstream sets upstream to the upstream branch, or HEAD~1 or HEAD if
+// there is none.
+func (g *gitCheckout) initUpstream(ctx context.Context) {
 	// Determine pristine status but ignoring untracked files. We do not
 	// distinguish between indexed or not.
 	isPristine := g.run(ctx, "status", "--porcelain", "--untracked-files=no") == ""
@@ -489,7 +695,6 @@ func (g *gitCheckout) init(ctx context.Context) error {
 	} else {
 		g.upstream.ref = g.run(ctx, "rev-parse", "--abbrev-ref=strict", "--symbolic-full-name", "@{u}")
 	}
-	return g.err
 }
 
 // run runs a git command in the check. After init() is called, the mu lock is
@@ -913,6 +1118,14 @@ func ctxScmAffectedFiles(ctx context.Context, s *shacState, name string, args st
 	); err != nil {
 		return nil, err
 	}
+	glob, err := globPatterns(argglob)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newGlobMatcher(glob)
+	if err != nil {
+		return nil, err
+	}
 	files, err := s.scm.affectedFiles(ctx, fileFilter{
 		includeDeleted:  bool(argincludeDeleted),
 		includeSymlinks: bool(argincludeSymlinks),
@@ -920,11 +1133,18 @@ func ctxScmAffectedFiles(ctx context.Context, s *shacState, name string, args st
 	if err != nil {
 		return nil, err
 	}
-	files, err = filterFilesByGlob(files, argglob)
-	if err != nil {
-		return nil, err
+	c := ctxCheck(ctx)
+	if c != nil {
+		files = s.visibleFiles(c, files)
+		c.recordInput(&checkInput{
+			Kind:            inputAffectedFiles,
+			IncludeDeleted:  bool(argincludeDeleted),
+			IncludeSymlinks: bool(argincludeSymlinks),
+			Glob:            glob,
+			matcher:         matcher,
+		})
 	}
-	return ctxScmFilesReturnValue(files), nil
+	return ctxScmFilesReturnValue(filterFilesByGlob(files, matcher)), nil
 }
 
 // ctxScmAllFiles implements native function ctx.scm.all_files().
@@ -943,6 +1163,14 @@ func ctxScmAllFiles(ctx context.Context, s *shacState, name string, args starlar
 	); err != nil {
 		return nil, err
 	}
+	glob, err := globPatterns(argglob)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := newGlobMatcher(glob)
+	if err != nil {
+		return nil, err
+	}
 	files, err := s.scm.allFiles(ctx, fileFilter{
 		includeDeleted:  bool(argincludeDeleted),
 		includeSymlinks: bool(argincludeSymlinks),
@@ -950,11 +1178,30 @@ func ctxScmAllFiles(ctx context.Context, s *shacState, name string, args starlar
 	if err != nil {
 		return nil, err
 	}
-	files, err = filterFilesByGlob(files, argglob)
-	if err != nil {
-		return nil, err
+	c := ctxCheck(ctx)
+	if c != nil {
+		files = s.visibleFiles(c, files)
+		c.recordInput(&checkInput{
+			Kind:            inputAllFiles,
+			IncludeDeleted:  bool(argincludeDeleted),
+			IncludeSymlinks: bool(argincludeSymlinks),
+			Glob:            glob,
+			matcher:         matcher,
+		})
+	}
+	return ctxScmFilesReturnValue(filterFilesByGlob(files, matcher)), nil
+}
+
+// visibleFiles returns the files that the check is allowed to see, as
+// restricted by its shard and its checks block in shac.textproto.
+func (s *shacState) visibleFiles(c *registeredCheck, files []file) []file {
+	if c.shardFiles {
+		files = s.shard.filterFiles(files)
+	}
+	if c.config != nil {
+		files = filterFilesByGlob(files, c.config.files)
 	}
-	return ctxScmFilesReturnValue(files), nil
+	return files
 }
 
 // ctxScmCommits implements native function ctx.scm.commits().
@@ -970,6 +1217,9 @@ func ctxScmCommits(ctx context.Context, s *shacState, name string, args starlark
 	if err != nil {
 		return nil, err
 	}
+	if c := ctxCheck(ctx); c != nil {
+		c.recordInput(&checkInput{Kind: inputCommits})
+	}
 	res := make([]starlark.Value, 0, len(commits))
 	for _, c := range commits {
 		res = append(res, c.getMetadata())
@@ -1022,9 +1272,13 @@ func unsafeString(b []byte) string {
 	return unsafe.String(unsafe.SliceData(b), len(b))
 }
 
-func filterFilesByGlob(files []file, glob starlark.Value) ([]file, error) {
+// globPatterns returns the patterns of the glob argument of
+// ctx.scm.affected_files() and ctx.scm.all_files().
+//
+// It returns nil if no glob is specified.
+func globPatterns(glob starlark.Value) ([]string, error) {
 	if glob == nil || glob == starlark.None {
-		return files, nil
+		return nil, nil
 	}
 	var patterns []string
 	switch v := glob.(type) {
@@ -1038,7 +1292,21 @@ func filterFilesByGlob(files []file, glob starlark.Value) ([]file, error) {
 	default:
 		return nil, fmt.Errorf("\"glob\" must be string or sequence of strings")
 	}
+	for _, p := range patterns {
+		if p == "" {
+			return nil, fmt.Errorf("\"glob\" pattern cannot be empty")
+		}
+	}
+	return patterns, nil
+}
 
+// newGlobMatcher returns a matcher for glob patterns.
+//
+// It returns nil if there is no pattern.
+func newGlobMatcher(patterns []string) (gitignore.Matcher, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
 	// If all patterns are negative, we need to prepend "**" because in
 	// gitignore syntax, negative patterns only take effect if a file was also
 	// matched by a positive pattern. Otherwise no files would be matched, which
@@ -1051,23 +1319,24 @@ func filterFilesByGlob(files []file, glob starlark.Value) ([]file, error) {
 		}
 	}
 	var gitPatterns []gitignore.Pattern
-	if allNegative && len(patterns) > 0 {
+	if allNegative {
 		gitPatterns = append(gitPatterns, gitignore.ParsePattern("**", nil))
 	}
-
 	for _, p := range patterns {
-		if p == "" {
-			return nil, fmt.Errorf("\"glob\" pattern cannot be empty")
-		}
 		gitPatterns = append(gitPatterns, gitignore.ParsePattern(p, nil))
 	}
-	matcher := gitignore.NewMatcher(gitPatterns)
+	return gitignore.NewMatcher(gitPatterns), nil
+}
 
+func filterFilesByGlob(files []file, matcher gitignore.Matcher) []file {
+	if matcher == nil {
+		return files
+	}
 	var out []file
 	for _, f := range files {
 		if matcher.Match(strings.Split(f.rootedpath(), "/"), false) {
 			out = append(out, f)
 		}
 	}
-	return out, nil
+	return out
 }
@@ -48,5 +48,11 @@ func ctxVarsGet(ctx context.Context, s *shacState, funcname string, args starlar
 	if !ok {
 		return nil, fmt.Errorf("unknown variable %q", name)
 	}
+	// The checks block of shac.textproto overrides the value for this check.
+	if c := ctxCheck(ctx); c != nil && c.config != nil {
+		if v, ok := c.config.vars[name]; ok {
+			val = v
+		}
+	}
 	return starlark.String(val), nil
 }
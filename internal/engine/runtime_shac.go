@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime/debug"
+	"strings"
 
 	"go.starlark.net/starlark"
 )
@@ -69,11 +70,46 @@ func shacRegisterCheck(ctx context.Context, s *shacState, name string, args star
 	if s.doneLoading {
 		return errors.New("can't register checks after done loading")
 	}
+	if cycle := s.depsCycle(c); cycle != nil {
+		return fmt.Errorf("check dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
 	// Register the new callback.
 	s.checks = append(s.checks, &registeredCheck{check: c})
 	return nil
 }
 
+// depsCycle returns the names of the checks forming a dependency cycle if the
+// check c was registered, or nil. Dependencies not registered yet are ignored.
+func (s *shacState) depsCycle(c *check) []string {
+	byName := make(map[string]*check, len(s.checks)+1)
+	for _, r := range s.checks {
+		byName[r.name] = r.check
+	}
+	byName[c.name] = c
+	// Only the new check adds edges, so a new cycle must go through it.
+	visited := map[string]bool{}
+	var visit func(name string, path []string) []string
+	visit = func(name string, path []string) []string {
+		path = append(path, name)
+		if name == c.name && len(path) > 1 {
+			return path
+		}
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		if cur := byName[name]; cur != nil {
+			for _, d := range cur.deps {
+				if cycle := visit(d, path); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		return nil
+	}
+	return visit(c.name, nil)
+}
+
 // getCommitHash return the git commit hash that was used to build this
 // executable.
 //
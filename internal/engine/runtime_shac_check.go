@@ -21,6 +21,7 @@ import (
 	"log"
 	"slices"
 	"strings"
+	"time"
 
 	"go.starlark.net/starlark"
 )
@@ -32,13 +33,82 @@ func shacCheck(ctx context.Context, s *shacState, name string, args starlark.Tup
 	var argimpl *starlark.Function
 	var argname starlark.String
 	var argformatter starlark.Bool
+	var argdeps starlark.Value = starlark.None
+	var argtimeout starlark.Value = starlark.None
+	var argmaxSteps starlark.Value = starlark.None
+	var argshardFiles starlark.Bool
+	var argtags starlark.Value = starlark.None
 	if err := starlark.UnpackArgs(name, args, kwargs,
 		"impl", &argimpl,
 		"name?", &argname,
-		"formatter?", &argformatter); err != nil {
+		"formatter?", &argformatter,
+		"deps?", &argdeps,
+		"timeout?", &argtimeout,
+		"max_steps?", &argmaxSteps,
+		"shard_files?", &argshardFiles,
+		"tags?", &argtags); err != nil {
 		return nil, err
 	}
-	return newCheck(argimpl, string(argname), bool(argformatter))
+	c, err := newCheck(argimpl, string(argname), bool(argformatter))
+	if err != nil {
+		return nil, err
+	}
+	if c.deps, err = checkNames(argdeps); err != nil {
+		return nil, fmt.Errorf("for parameter \"deps\": %w", err)
+	}
+	if c.timeout, err = toDuration(argtimeout); err != nil {
+		return nil, fmt.Errorf("for parameter \"timeout\": %w", err)
+	}
+	if argmaxSteps != starlark.None {
+		i, ok := argmaxSteps.(starlark.Int)
+		if !ok {
+			return nil, fmt.Errorf("for parameter \"max_steps\": got %s, want int", argmaxSteps.Type())
+		}
+		if c.maxSteps, ok = i.Uint64(); !ok || c.maxSteps == 0 {
+			return nil, fmt.Errorf("for parameter \"max_steps\": must be positive, got %s", argmaxSteps)
+		}
+	}
+	c.shardFiles = bool(argshardFiles)
+	if argtags != starlark.None {
+		seq, ok := argtags.(starlark.Sequence)
+		if ok {
+			c.tags = sequenceToStrings(seq)
+		}
+		if !ok || c.tags == nil {
+			return nil, fmt.Errorf("for parameter \"tags\": got %s, want sequence of str", argtags.Type())
+		}
+		for _, tag := range c.tags {
+			if tag == "" {
+				return nil, errors.New("for parameter \"tags\": tags must not be empty")
+			}
+		}
+	}
+	return c, nil
+}
+
+// checkNames returns the names of a sequence of shac.check objects.
+func checkNames(v starlark.Value) ([]string, error) {
+	if v == starlark.None {
+		return nil, nil
+	}
+	seq, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("got %s, want sequence of shac.check", v.Type())
+	}
+	var out []string
+	it := seq.Iterate()
+	defer it.Done()
+	var x starlark.Value
+	for it.Next(&x) {
+		c, ok := x.(*check)
+		if !ok {
+			return nil, fmt.Errorf("got %s, want shac.check", x.Type())
+		}
+		if !slices.Contains(out, c.name) {
+			out = append(out, c.name)
+		}
+	}
+	return out, nil
 }
 
 func newCheck(impl starlark.Callable, name string, formatter bool) (*check, error) {
@@ -96,6 +166,21 @@ type check struct {
 	// Whether the check is an auto-formatter or not.
 	formatter bool
 	kwargs    []starlark.Tuple
+	// deps are the names of the checks that must complete successfully before
+	// this check is run.
+	deps []string
+	// timeout is the maximum duration of the check, including its
+	// subprocesses. 0 means Options.Timeout is used.
+	timeout time.Duration
+	// maxSteps is the maximum number of Starlark execution steps of the check.
+	// 0 means the max_steps of shac.textproto is used.
+	maxSteps uint64
+	// shardFiles is true if the check is run on every shard with
+	// ctx.scm.affected_files() and ctx.scm.all_files() only returning the files
+	// of the shard, instead of being assigned to a single shard.
+	shardFiles bool
+	// tags are used to select checks with --only-tag and --skip-tag.
+	tags []string
 }
 
 var _ starlark.HasAttrs = (*check)(nil)
@@ -23,6 +23,7 @@ package engine
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -65,6 +66,25 @@ type Document struct {
 	// Allowed properties for findings.
 	// To disallow any properties, explicitly specify an empty AllowedProperties.
 	AllowedFindingsProperties *AllowedProperties `protobuf:"bytes,10,opt,name=allowed_findings_properties,json=allowedFindingsProperties,proto3" json:"allowed_findings_properties,omitempty"`
+	// Directory in which to cache the results of checks, relative to the root
+	// directory or absolute. When set, a check is not run again if the Starlark
+	// code, the vars and the files it requested via ctx.scm, ctx.io.read_file()
+	// and new_lines() did not change since the previous run; its findings,
+	// artifacts and prints are replayed instead. Files that are read by
+	// subprocesses without being requested through these functions are not
+	// tracked. Checks that request no files are never cached. Caching is
+	// disabled when unset.
+	CacheDir string `protobuf:"bytes,11,opt,name=cache_dir,json=cacheDir,proto3" json:"cache_dir,omitempty"`
+	// Default resource limits for the subprocesses started by ctx.os.exec().
+	// They can be overridden per call.
+	ExecLimits *ExecLimits `protobuf:"bytes,12,opt,name=exec_limits,json=execLimits,proto3" json:"exec_limits,omitempty"`
+	// Default maximum number of Starlark execution steps of each check. A check
+	// exceeding it fails. It can be overridden with shac.check(max_steps=...).
+	// Unset or 0 means no limit.
+	MaxSteps uint64 `protobuf:"varint,13,opt,name=max_steps,json=maxSteps,proto3" json:"max_steps,omitempty"`
+	// Per-check configuration, to tune the checks of a shared library without
+	// forking it. Each check is referenced at most once by name.
+	Checks []*CheckConfig `protobuf:"bytes,14,rep,name=checks,proto3" json:"checks,omitempty"`
 }
 
 func (x *Document) Reset() {
@@ -169,6 +189,398 @@ func (x *Document) GetAllowedFindingsProperties() *AllowedProperties {
 	return nil
 }
 
+func (x *Document) GetCacheDir() string {
+	if x != nil {
+		return x.CacheDir
+	}
+	return ""
+}
+
+func (x *Document) GetExecLimits() *ExecLimits {
+	if x != nil {
+		return x.ExecLimits
+	}
+	return nil
+}
+
+func (x *Document) GetMaxSteps() uint64 {
+	if x != nil {
+		return x.MaxSteps
+	}
+	return 0
+}
+
+func (x *Document) GetChecks() []*CheckConfig {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
+// CheckConfig is the configuration of a check.
+type CheckConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the check, as registered. Required.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// When set to true, the check is not run, nor are the checks depending on it.
+	Disabled bool `protobuf:"varint,2,opt,name=disabled,proto3" json:"disabled,omitempty"`
+	// Remaps the levels of the findings emitted by the check, e.g. from "error"
+	// to "warning" during a migration.
+	Levels []*LevelMapping `protobuf:"bytes,3,rep,name=levels,proto3" json:"levels,omitempty"`
+	// Glob patterns, using the same syntax as the glob argument of
+	// ctx.scm.affected_files(), of the files returned to the check by
+	// ctx.scm.affected_files() and ctx.scm.all_files(). All the files are
+	// included when empty.
+	Include []string `protobuf:"bytes,4,rep,name=include,proto3" json:"include,omitempty"`
+	// Glob patterns of the files excluded from those returned to the check by
+	// ctx.scm.affected_files() and ctx.scm.all_files(), on top of ignore.
+	Exclude []string `protobuf:"bytes,5,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	// Values of vars that override the values returned by ctx.vars.get() in
+	// this check. The vars must be declared in vars.
+	Vars []*VarValue `protobuf:"bytes,6,rep,name=vars,proto3" json:"vars,omitempty"`
+	// Keyword arguments passed to the check, overriding the ones set with
+	// shac.check().with_args().
+	Args []*Arg `protobuf:"bytes,7,rep,name=args,proto3" json:"args,omitempty"`
+}
+
+func (x *CheckConfig) Reset() {
+	*x = CheckConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_shac_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckConfig) ProtoMessage() {}
+
+func (x *CheckConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_shac_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckConfig.ProtoReflect.Descriptor instead.
+func (*CheckConfig) Descriptor() ([]byte, []int) {
+	return file_shac_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckConfig) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CheckConfig) GetDisabled() bool {
+	if x != nil {
+		return x.Disabled
+	}
+	return false
+}
+
+func (x *CheckConfig) GetLevels() []*LevelMapping {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+func (x *CheckConfig) GetInclude() []string {
+	if x != nil {
+		return x.Include
+	}
+	return nil
+}
+
+func (x *CheckConfig) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+func (x *CheckConfig) GetVars() []*VarValue {
+	if x != nil {
+		return x.Vars
+	}
+	return nil
+}
+
+func (x *CheckConfig) GetArgs() []*Arg {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+// LevelMapping remaps the level of findings.
+type LevelMapping struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Level to remap, one of "notice", "warning" or "error".
+	From string `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	// New level, one of "notice", "warning" or "error".
+	To string `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+}
+
+func (x *LevelMapping) Reset() {
+	*x = LevelMapping{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_shac_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LevelMapping) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LevelMapping) ProtoMessage() {}
+
+func (x *LevelMapping) ProtoReflect() protoreflect.Message {
+	mi := &file_shac_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LevelMapping.ProtoReflect.Descriptor instead.
+func (*LevelMapping) Descriptor() ([]byte, []int) {
+	return file_shac_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LevelMapping) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *LevelMapping) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+// VarValue is the value of a var.
+type VarValue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *VarValue) Reset() {
+	*x = VarValue{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_shac_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VarValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VarValue) ProtoMessage() {}
+
+func (x *VarValue) ProtoReflect() protoreflect.Message {
+	mi := &file_shac_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VarValue.ProtoReflect.Descriptor instead.
+func (*VarValue) Descriptor() ([]byte, []int) {
+	return file_shac_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *VarValue) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *VarValue) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// Arg is a keyword argument.
+type Arg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the parameter of the check's implementation function.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Value of the argument. Numbers without a fractional part are converted to
+	// int, lists to list and structs to dict.
+	Value *structpb.Value `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Arg) Reset() {
+	*x = Arg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_shac_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Arg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Arg) ProtoMessage() {}
+
+func (x *Arg) ProtoReflect() protoreflect.Message {
+	mi := &file_shac_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Arg.ProtoReflect.Descriptor instead.
+func (*Arg) Descriptor() ([]byte, []int) {
+	return file_shac_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Arg) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Arg) GetValue() *structpb.Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+// ExecLimits are resource limits applied to a subprocess and its children.
+// They are only enforced on Linux amd64 and arm64. Unset or 0 means no limit.
+// memory_mb and processes are enforced with a cgroup, created under the cgroup
+// of shac on cgroup v2 systems, which must be writable, e.g. delegated by
+// systemd.
+type ExecLimits struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Maximum memory used by all the processes together, in MiB.
+	MemoryMb uint64 `protobuf:"varint,1,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	// Maximum CPU time of each process, in seconds.
+	CpuSeconds uint64 `protobuf:"varint,2,opt,name=cpu_seconds,json=cpuSeconds,proto3" json:"cpu_seconds,omitempty"`
+	// Maximum number of open file descriptors of each process.
+	OpenFiles uint64 `protobuf:"varint,3,opt,name=open_files,json=openFiles,proto3" json:"open_files,omitempty"`
+	// Maximum number of processes running at once, counting only the
+	// processes of the subprocess, unlike RLIMIT_NPROC.
+	Processes uint64 `protobuf:"varint,4,opt,name=processes,proto3" json:"processes,omitempty"`
+}
+
+func (x *ExecLimits) Reset() {
+	*x = ExecLimits{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_shac_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExecLimits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecLimits) ProtoMessage() {}
+
+func (x *ExecLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_shac_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecLimits.ProtoReflect.Descriptor instead.
+func (*ExecLimits) Descriptor() ([]byte, []int) {
+	return file_shac_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ExecLimits) GetMemoryMb() uint64 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *ExecLimits) GetCpuSeconds() uint64 {
+	if x != nil {
+		return x.CpuSeconds
+	}
+	return 0
+}
+
+func (x *ExecLimits) GetOpenFiles() uint64 {
+	if x != nil {
+		return x.OpenFiles
+	}
+	return 0
+}
+
+func (x *ExecLimits) GetProcesses() uint64 {
+	if x != nil {
+		return x.Processes
+	}
+	return 0
+}
+
 // Var specifies a variable that may be passed into checks at runtime by the
 // --var flag and accessed via `ctx.vars.get(name)`.
 //
@@ -193,7 +605,7 @@ type Var struct {
 func (x *Var) Reset() {
 	*x = Var{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[1]
+		mi := &file_shac_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -206,7 +618,7 @@ func (x *Var) String() string {
 func (*Var) ProtoMessage() {}
 
 func (x *Var) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[1]
+	mi := &file_shac_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -219,7 +631,7 @@ func (x *Var) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Var.ProtoReflect.Descriptor instead.
 func (*Var) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{1}
+	return file_shac_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Var) GetName() string {
@@ -262,7 +674,7 @@ type PassthroughEnv struct {
 func (x *PassthroughEnv) Reset() {
 	*x = PassthroughEnv{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[2]
+		mi := &file_shac_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -275,7 +687,7 @@ func (x *PassthroughEnv) String() string {
 func (*PassthroughEnv) ProtoMessage() {}
 
 func (x *PassthroughEnv) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[2]
+	mi := &file_shac_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -288,7 +700,7 @@ func (x *PassthroughEnv) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PassthroughEnv.ProtoReflect.Descriptor instead.
 func (*PassthroughEnv) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{2}
+	return file_shac_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *PassthroughEnv) GetName() string {
@@ -322,13 +734,17 @@ type Requirements struct {
 	// direct are packages referenced by the starlark code via a load() statement.
 	Direct []*Dependency `protobuf:"bytes,1,rep,name=direct,proto3" json:"direct,omitempty"`
 	// indirect are packages referenced by direct dependencies or transitively.
+	//
+	// Listing them is optional: the requirements declared in the shac.textproto
+	// of each package are resolved transitively. A package must be required at
+	// a single version and every package of the closure must be in sum.
 	Indirect []*Dependency `protobuf:"bytes,2,rep,name=indirect,proto3" json:"indirect,omitempty"`
 }
 
 func (x *Requirements) Reset() {
 	*x = Requirements{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[3]
+		mi := &file_shac_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -341,7 +757,7 @@ func (x *Requirements) String() string {
 func (*Requirements) ProtoMessage() {}
 
 func (x *Requirements) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[3]
+	mi := &file_shac_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -354,7 +770,7 @@ func (x *Requirements) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Requirements.ProtoReflect.Descriptor instead.
 func (*Requirements) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{3}
+	return file_shac_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *Requirements) GetDirect() []*Dependency {
@@ -378,8 +794,18 @@ type Dependency struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// url is the URL to the resource without the schema, e.g.
-	// "github.com/shac/generic-checks".
+	// url is the location of the package. It is one of:
+	//   - the URL to a git repository without the schema, e.g.
+	//     "github.com/shac/generic-checks".
+	//   - a local directory, starting with "./", "../" or "/", e.g.
+	//     "../generic-checks". Relative paths are relative to the root of the
+	//     repository. Only allowed in the root shac.textproto.
+	//   - a .tar.gz, .tgz or .zip archive, either with the https:// or file://
+	//     schema or without schema for https, e.g.
+	//     "file:///srv/generic-checks-1.0.tar.gz". If all its files are in a
+	//     single top level directory, this directory is the package.
+	// Packages not in a git repository are verified against sum the same way.
+	// Set an alias to load them.
 	Url string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
 	// alias is an optional shorthand alias. This is how this is referenced to in
 	// load() statements.
@@ -391,7 +817,7 @@ type Dependency struct {
 func (x *Dependency) Reset() {
 	*x = Dependency{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[4]
+		mi := &file_shac_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -404,7 +830,7 @@ func (x *Dependency) String() string {
 func (*Dependency) ProtoMessage() {}
 
 func (x *Dependency) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[4]
+	mi := &file_shac_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -417,7 +843,7 @@ func (x *Dependency) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Dependency.ProtoReflect.Descriptor instead.
 func (*Dependency) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{4}
+	return file_shac_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *Dependency) GetUrl() string {
@@ -453,7 +879,7 @@ type Sum struct {
 func (x *Sum) Reset() {
 	*x = Sum{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[5]
+		mi := &file_shac_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -466,7 +892,7 @@ func (x *Sum) String() string {
 func (*Sum) ProtoMessage() {}
 
 func (x *Sum) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[5]
+	mi := &file_shac_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -479,7 +905,7 @@ func (x *Sum) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Sum.ProtoReflect.Descriptor instead.
 func (*Sum) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{5}
+	return file_shac_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *Sum) GetKnown() []*Known {
@@ -502,7 +928,7 @@ type Known struct {
 func (x *Known) Reset() {
 	*x = Known{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[6]
+		mi := &file_shac_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -515,7 +941,7 @@ func (x *Known) String() string {
 func (*Known) ProtoMessage() {}
 
 func (x *Known) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[6]
+	mi := &file_shac_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -528,7 +954,7 @@ func (x *Known) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Known.ProtoReflect.Descriptor instead.
 func (*Known) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{6}
+	return file_shac_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *Known) GetUrl() string {
@@ -561,7 +987,7 @@ type VersionDigest struct {
 func (x *VersionDigest) Reset() {
 	*x = VersionDigest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[7]
+		mi := &file_shac_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -574,7 +1000,7 @@ func (x *VersionDigest) String() string {
 func (*VersionDigest) ProtoMessage() {}
 
 func (x *VersionDigest) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[7]
+	mi := &file_shac_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -587,7 +1013,7 @@ func (x *VersionDigest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VersionDigest.ProtoReflect.Descriptor instead.
 func (*VersionDigest) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{7}
+	return file_shac_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *VersionDigest) GetVersion() string {
@@ -616,7 +1042,7 @@ type Property struct {
 func (x *Property) Reset() {
 	*x = Property{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[8]
+		mi := &file_shac_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -629,7 +1055,7 @@ func (x *Property) String() string {
 func (*Property) ProtoMessage() {}
 
 func (x *Property) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[8]
+	mi := &file_shac_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -642,7 +1068,7 @@ func (x *Property) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Property.ProtoReflect.Descriptor instead.
 func (*Property) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{8}
+	return file_shac_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *Property) GetName() string {
@@ -664,7 +1090,7 @@ type AllowedProperties struct {
 func (x *AllowedProperties) Reset() {
 	*x = AllowedProperties{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_shac_proto_msgTypes[9]
+		mi := &file_shac_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -677,7 +1103,7 @@ func (x *AllowedProperties) String() string {
 func (*AllowedProperties) ProtoMessage() {}
 
 func (x *AllowedProperties) ProtoReflect() protoreflect.Message {
-	mi := &file_shac_proto_msgTypes[9]
+	mi := &file_shac_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -690,7 +1116,7 @@ func (x *AllowedProperties) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AllowedProperties.ProtoReflect.Descriptor instead.
 func (*AllowedProperties) Descriptor() ([]byte, []int) {
-	return file_shac_proto_rawDescGZIP(), []int{9}
+	return file_shac_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *AllowedProperties) GetProperties() []*Property {
@@ -704,36 +1130,82 @@ var File_shac_proto protoreflect.FileDescriptor
 
 var file_shac_proto_rawDesc = []byte{
 	0x0a, 0x0a, 0x73, 0x68, 0x61, 0x63, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x65, 0x6e,
-	0x67, 0x69, 0x6e, 0x65, 0x22, 0xcd, 0x03, 0x0a, 0x08, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e,
-	0x74, 0x12, 0x28, 0x0a, 0x10, 0x6d, 0x69, 0x6e, 0x5f, 0x73, 0x68, 0x61, 0x63, 0x5f, 0x76, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x6d, 0x69, 0x6e,
-	0x53, 0x68, 0x61, 0x63, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x61,
-	0x6c, 0x6c, 0x6f, 0x77, 0x5f, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x08, 0x52, 0x0c, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
-	0x12, 0x38, 0x0a, 0x0c, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e,
-	0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x0c, 0x72, 0x65,
-	0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1d, 0x0a, 0x03, 0x73, 0x75,
-	0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65,
-	0x2e, 0x53, 0x75, 0x6d, 0x52, 0x03, 0x73, 0x75, 0x6d, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x65, 0x6e,
-	0x64, 0x6f, 0x72, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
-	0x76, 0x65, 0x6e, 0x64, 0x6f, 0x72, 0x50, 0x61, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x67,
-	0x6e, 0x6f, 0x72, 0x65, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x69, 0x67, 0x6e, 0x6f,
-	0x72, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x77, 0x72, 0x69, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x72,
-	0x6f, 0x6f, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x77, 0x72, 0x69, 0x74, 0x61,
-	0x62, 0x6c, 0x65, 0x52, 0x6f, 0x6f, 0x74, 0x12, 0x1f, 0x0a, 0x04, 0x76, 0x61, 0x72, 0x73, 0x18,
-	0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x56,
-	0x61, 0x72, 0x52, 0x04, 0x76, 0x61, 0x72, 0x73, 0x12, 0x3f, 0x0a, 0x0f, 0x70, 0x61, 0x73, 0x73,
-	0x74, 0x68, 0x72, 0x6f, 0x75, 0x67, 0x68, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x09, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x16, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x50, 0x61, 0x73, 0x73, 0x74,
-	0x68, 0x72, 0x6f, 0x75, 0x67, 0x68, 0x45, 0x6e, 0x76, 0x52, 0x0e, 0x70, 0x61, 0x73, 0x73, 0x74,
-	0x68, 0x72, 0x6f, 0x75, 0x67, 0x68, 0x45, 0x6e, 0x76, 0x12, 0x59, 0x0a, 0x1b, 0x61, 0x6c, 0x6c,
-	0x6f, 0x77, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x5f, 0x70, 0x72,
-	0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19,
-	0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x50,
-	0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x52, 0x19, 0x61, 0x6c, 0x6c, 0x6f, 0x77,
-	0x65, 0x64, 0x46, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72,
-	0x74, 0x69, 0x65, 0x73, 0x22, 0x55, 0x0a, 0x03, 0x56, 0x61, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x67, 0x69, 0x6e, 0x65, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0xe9, 0x04, 0x0a, 0x08, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12,
+	0x28, 0x0a, 0x10, 0x6d, 0x69, 0x6e, 0x5f, 0x73, 0x68, 0x61, 0x63, 0x5f, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x6d, 0x69, 0x6e, 0x53, 0x68,
+	0x61, 0x63, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x61, 0x6c, 0x6c,
+	0x6f, 0x77, 0x5f, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0c, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x4e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x38,
+	0x0a, 0x0c, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x52, 0x65,
+	0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x0c, 0x72, 0x65, 0x71, 0x75,
+	0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1d, 0x0a, 0x03, 0x73, 0x75, 0x6d, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x53,
+	0x75, 0x6d, 0x52, 0x03, 0x73, 0x75, 0x6d, 0x12, 0x1f, 0x0a, 0x0b, 0x76, 0x65, 0x6e, 0x64, 0x6f,
+	0x72, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x76, 0x65,
+	0x6e, 0x64, 0x6f, 0x72, 0x50, 0x61, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x67, 0x6e, 0x6f,
+	0x72, 0x65, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x65,
+	0x12, 0x23, 0x0a, 0x0d, 0x77, 0x72, 0x69, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x72, 0x6f, 0x6f,
+	0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x77, 0x72, 0x69, 0x74, 0x61, 0x62, 0x6c,
+	0x65, 0x52, 0x6f, 0x6f, 0x74, 0x12, 0x1f, 0x0a, 0x04, 0x76, 0x61, 0x72, 0x73, 0x18, 0x08, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x56, 0x61, 0x72,
+	0x52, 0x04, 0x76, 0x61, 0x72, 0x73, 0x12, 0x3f, 0x0a, 0x0f, 0x70, 0x61, 0x73, 0x73, 0x74, 0x68,
+	0x72, 0x6f, 0x75, 0x67, 0x68, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x16, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x50, 0x61, 0x73, 0x73, 0x74, 0x68, 0x72,
+	0x6f, 0x75, 0x67, 0x68, 0x45, 0x6e, 0x76, 0x52, 0x0e, 0x70, 0x61, 0x73, 0x73, 0x74, 0x68, 0x72,
+	0x6f, 0x75, 0x67, 0x68, 0x45, 0x6e, 0x76, 0x12, 0x59, 0x0a, 0x1b, 0x61, 0x6c, 0x6c, 0x6f, 0x77,
+	0x65, 0x64, 0x5f, 0x66, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x5f, 0x70, 0x72, 0x6f, 0x70,
+	0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x65,
+	0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x50, 0x72, 0x6f,
+	0x70, 0x65, 0x72, 0x74, 0x69, 0x65, 0x73, 0x52, 0x19, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64,
+	0x46, 0x69, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x69,
+	0x65, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x61, 0x63, 0x68, 0x65, 0x5f, 0x64, 0x69, 0x72, 0x18,
+	0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x61, 0x63, 0x68, 0x65, 0x44, 0x69, 0x72, 0x12,
+	0x33, 0x0a, 0x0b, 0x65, 0x78, 0x65, 0x63, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x0c,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x45, 0x78,
+	0x65, 0x63, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x61, 0x78, 0x5f, 0x73, 0x74, 0x65, 0x70,
+	0x73, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x6d, 0x61, 0x78, 0x53, 0x74, 0x65, 0x70,
+	0x73, 0x12, 0x2b, 0x0a, 0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x18, 0x0e, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x22, 0xe6,
+	0x01, 0x0a, 0x0b, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x12, 0x2c,
+	0x0a, 0x06, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x4d, 0x61, 0x70,
+	0x70, 0x69, 0x6e, 0x67, 0x52, 0x06, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07,
+	0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x69,
+	0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64,
+	0x65, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x64, 0x65,
+	0x12, 0x24, 0x0a, 0x04, 0x76, 0x61, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x56, 0x61, 0x72, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x52, 0x04, 0x76, 0x61, 0x72, 0x73, 0x12, 0x1f, 0x0a, 0x04, 0x61, 0x72, 0x67, 0x73, 0x18, 0x07,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2e, 0x41, 0x72,
+	0x67, 0x52, 0x04, 0x61, 0x72, 0x67, 0x73, 0x22, 0x32, 0x0a, 0x0c, 0x4c, 0x65, 0x76, 0x65, 0x6c,
+	0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74,
+	0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74, 0x6f, 0x22, 0x34, 0x0a, 0x08, 0x56,
+	0x61, 0x72, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x22, 0x47, 0x0a, 0x03, 0x41, 0x72, 0x67, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x87, 0x01, 0x0a, 0x0a, 0x45,
+	0x78, 0x65, 0x63, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x65, 0x6d,
+	0x6f, 0x72, 0x79, 0x5f, 0x6d, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x6d, 0x65,
+	0x6d, 0x6f, 0x72, 0x79, 0x4d, 0x62, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x70, 0x75, 0x5f, 0x73, 0x65,
+	0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x63, 0x70, 0x75,
+	0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x6f, 0x70, 0x65, 0x6e, 0x5f,
+	0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6f, 0x70, 0x65,
+	0x6e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73,
+	0x73, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x70, 0x72, 0x6f, 0x63, 0x65,
+	0x73, 0x73, 0x65, 0x73, 0x22, 0x55, 0x0a, 0x03, 0x56, 0x61, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e,
 	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
 	0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f,
@@ -792,35 +1264,47 @@ func file_shac_proto_rawDescGZIP() []byte {
 	return file_shac_proto_rawDescData
 }
 
-var file_shac_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_shac_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_shac_proto_goTypes = []interface{}{
 	(*Document)(nil),          // 0: engine.Document
-	(*Var)(nil),               // 1: engine.Var
-	(*PassthroughEnv)(nil),    // 2: engine.PassthroughEnv
-	(*Requirements)(nil),      // 3: engine.Requirements
-	(*Dependency)(nil),        // 4: engine.Dependency
-	(*Sum)(nil),               // 5: engine.Sum
-	(*Known)(nil),             // 6: engine.Known
-	(*VersionDigest)(nil),     // 7: engine.VersionDigest
-	(*Property)(nil),          // 8: engine.Property
-	(*AllowedProperties)(nil), // 9: engine.AllowedProperties
+	(*CheckConfig)(nil),       // 1: engine.CheckConfig
+	(*LevelMapping)(nil),      // 2: engine.LevelMapping
+	(*VarValue)(nil),          // 3: engine.VarValue
+	(*Arg)(nil),               // 4: engine.Arg
+	(*ExecLimits)(nil),        // 5: engine.ExecLimits
+	(*Var)(nil),               // 6: engine.Var
+	(*PassthroughEnv)(nil),    // 7: engine.PassthroughEnv
+	(*Requirements)(nil),      // 8: engine.Requirements
+	(*Dependency)(nil),        // 9: engine.Dependency
+	(*Sum)(nil),               // 10: engine.Sum
+	(*Known)(nil),             // 11: engine.Known
+	(*VersionDigest)(nil),     // 12: engine.VersionDigest
+	(*Property)(nil),          // 13: engine.Property
+	(*AllowedProperties)(nil), // 14: engine.AllowedProperties
+	(*structpb.Value)(nil),    // 15: google.protobuf.Value
 }
 var file_shac_proto_depIdxs = []int32{
-	3,  // 0: engine.Document.requirements:type_name -> engine.Requirements
-	5,  // 1: engine.Document.sum:type_name -> engine.Sum
-	1,  // 2: engine.Document.vars:type_name -> engine.Var
-	2,  // 3: engine.Document.passthrough_env:type_name -> engine.PassthroughEnv
-	9,  // 4: engine.Document.allowed_findings_properties:type_name -> engine.AllowedProperties
-	4,  // 5: engine.Requirements.direct:type_name -> engine.Dependency
-	4,  // 6: engine.Requirements.indirect:type_name -> engine.Dependency
-	6,  // 7: engine.Sum.known:type_name -> engine.Known
-	7,  // 8: engine.Known.seen:type_name -> engine.VersionDigest
-	8,  // 9: engine.AllowedProperties.properties:type_name -> engine.Property
-	10, // [10:10] is the sub-list for method output_type
-	10, // [10:10] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	8,  // 0: engine.Document.requirements:type_name -> engine.Requirements
+	10, // 1: engine.Document.sum:type_name -> engine.Sum
+	6,  // 2: engine.Document.vars:type_name -> engine.Var
+	7,  // 3: engine.Document.passthrough_env:type_name -> engine.PassthroughEnv
+	14, // 4: engine.Document.allowed_findings_properties:type_name -> engine.AllowedProperties
+	5,  // 5: engine.Document.exec_limits:type_name -> engine.ExecLimits
+	1,  // 6: engine.Document.checks:type_name -> engine.CheckConfig
+	2,  // 7: engine.CheckConfig.levels:type_name -> engine.LevelMapping
+	3,  // 8: engine.CheckConfig.vars:type_name -> engine.VarValue
+	4,  // 9: engine.CheckConfig.args:type_name -> engine.Arg
+	15, // 10: engine.Arg.value:type_name -> google.protobuf.Value
+	9,  // 11: engine.Requirements.direct:type_name -> engine.Dependency
+	9,  // 12: engine.Requirements.indirect:type_name -> engine.Dependency
+	11, // 13: engine.Sum.known:type_name -> engine.Known
+	12, // 14: engine.Known.seen:type_name -> engine.VersionDigest
+	13, // 15: engine.AllowedProperties.properties:type_name -> engine.Property
+	16, // [16:16] is the sub-list for method output_type
+	16, // [16:16] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
 }
 
 func init() { file_shac_proto_init() }
@@ -842,7 +1326,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Var); i {
+			switch v := v.(*CheckConfig); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -854,7 +1338,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PassthroughEnv); i {
+			switch v := v.(*LevelMapping); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -866,7 +1350,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Requirements); i {
+			switch v := v.(*VarValue); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -878,7 +1362,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Dependency); i {
+			switch v := v.(*Arg); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -890,7 +1374,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Sum); i {
+			switch v := v.(*ExecLimits); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -902,7 +1386,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Known); i {
+			switch v := v.(*Var); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -914,7 +1398,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*VersionDigest); i {
+			switch v := v.(*PassthroughEnv); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -926,7 +1410,7 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Property); i {
+			switch v := v.(*Requirements); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -938,6 +1422,66 @@ func file_shac_proto_init() {
 			}
 		}
 		file_shac_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Dependency); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_shac_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Sum); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_shac_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Known); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_shac_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VersionDigest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_shac_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Property); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_shac_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*AllowedProperties); i {
 			case 0:
 				return &v.state
@@ -956,7 +1500,7 @@ func file_shac_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_shac_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,115 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// shard is the part of the work done by this run when the work is partitioned
+// across multiple runs, e.g. on different CI workers.
+type shard struct {
+	index int
+	count int
+}
+
+func newShard(index, count int) (shard, error) {
+	if count < 0 {
+		return shard{}, fmt.Errorf("invalid shard count %d, must not be negative", count)
+	}
+	if count == 0 {
+		count = 1
+	}
+	if index < 0 || index >= count {
+		return shard{}, fmt.Errorf("invalid shard index %d, must be in [0, %d)", index, count)
+	}
+	return shard{index: index, count: count}, nil
+}
+
+// owns returns true if key is assigned to this shard.
+func (sh shard) owns(key string) bool {
+	if sh.count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32()%uint32(sh.count)) == sh.index // #nosec G115
+}
+
+// filterFiles returns the files assigned to this shard.
+func (sh shard) filterFiles(files []file) []file {
+	if sh.count <= 1 {
+		return files
+	}
+	var out []file
+	for _, f := range files {
+		if sh.owns(f.rootedpath()) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// filterChecks returns the checks to run on this shard, keeping the order.
+//
+// A check is always run on the same shard as the checks it depends on, so
+// that no check is run on multiple shards. Checks that shard their files, and
+// the checks connected to them, are run on every shard.
+func (sh shard) filterChecks(subdir string, checks []*registeredCheck) []*registeredCheck {
+	if sh.count <= 1 {
+		return checks
+	}
+	// Group the checks connected by dependencies.
+	parent := make(map[*registeredCheck]*registeredCheck, len(checks))
+	var find func(c *registeredCheck) *registeredCheck
+	find = func(c *registeredCheck) *registeredCheck {
+		p, ok := parent[c]
+		if !ok || p == c {
+			return c
+		}
+		r := find(p)
+		parent[c] = r
+		return r
+	}
+	for _, c := range checks {
+		for _, d := range c.deps {
+			if a, b := find(c), find(d); a != b {
+				parent[a] = b
+			}
+		}
+	}
+	// The key of a group is the smallest check name, so it doesn't depend on
+	// the order of the checks.
+	keys := map[*registeredCheck]string{}
+	allShards := map[*registeredCheck]bool{}
+	for _, c := range checks {
+		r := find(c)
+		if k, ok := keys[r]; !ok || c.name < k {
+			keys[r] = c.name
+		}
+		if c.shardFiles {
+			allShards[r] = true
+		}
+	}
+	var out []*registeredCheck
+	for _, c := range checks {
+		r := find(c)
+		if allShards[r] || sh.owns(subdir+"\x00"+keys[r]) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
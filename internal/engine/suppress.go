@@ -0,0 +1,269 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SuppressionReport is optionally implemented by a Report to receive the
+// findings suppressed by a shac:ignore directive.
+type SuppressionReport interface {
+	// EmitSuppressedFinding is called instead of EmitFinding for a finding
+	// suppressed by a shac:ignore directive. justification is the reason
+	// given in the directive, if any.
+	EmitSuppressedFinding(ctx context.Context, check string, level Level, message, root, file string, s Span, justification string) error
+}
+
+// ignoreDirective matches a directive suppressing the findings of checks on
+// its line and on the following line, e.g.:
+//
+//	foo()  # shac:ignore(check_a, check_b): reason
+var ignoreDirective = regexp.MustCompile(`shac:ignore\(([^)]*)\)(?::\s*(.*))?`)
+
+// directive is a shac:ignore directive in a file.
+type directive struct {
+	// line is 1-based.
+	line   int
+	checks []string
+	reason string
+}
+
+// fileLines caches the lines of the files with findings, to fingerprint them
+// for the baseline.
+type fileLines struct {
+	root string
+	// scm provides the content of the file being edited, if any, which is
+	// used instead of the file on disk.
+	scm scmCheckout
+
+	mu sync.Mutex
+	// lines are keyed by path relative to root, POSIX style. A file that
+	// can't be read has no lines.
+	lines map[string][]string
+}
+
+// get returns the lines of a file relative to root, POSIX style.
+func (f *fileLines) get(file string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lines == nil {
+		f.lines = map[string][]string{}
+	}
+	lines, ok := f.lines[file]
+	if !ok {
+		p := filepath.Join(f.root, filepath.FromSlash(file))
+		if b, ok := inMemoryContent(f.scm, p); ok {
+			lines = strings.Split(string(b), "\n")
+		} else if b, err := os.ReadFile(p); err == nil {
+			lines = strings.Split(string(b), "\n")
+		}
+		f.lines[file] = lines
+	}
+	return lines
+}
+
+// line returns the content of a 1-based line of a file, or an empty string if
+// it can't be read.
+func (f *fileLines) line(file string, line int) string {
+	if file == "" || line <= 0 {
+		return ""
+	}
+	lines := f.get(file)
+	if line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+// reset clears the cache, as the files may have been modified.
+func (f *fileLines) reset() {
+	f.mu.Lock()
+	f.lines = nil
+	f.mu.Unlock()
+}
+
+// suppressions tracks the shac:ignore directives that suppressed findings.
+type suppressions struct {
+	root string
+	// scm provides the content of the file being edited, if any, which is
+	// used instead of the file on disk.
+	scm scmCheckout
+
+	mu sync.Mutex
+	// directives are the shac:ignore directives of the files scanned so far,
+	// keyed by path relative to root, POSIX style. Only the directives are
+	// kept since every visible file is scanned for unused directives.
+	directives map[string][]directive
+	// used are the directives that suppressed at least one finding, keyed by
+	// "check\x00file\x00line".
+	used map[string]bool
+}
+
+// reset prepares for a new run of the checks.
+func (s *suppressions) reset() {
+	s.mu.Lock()
+	s.directives = nil
+	s.used = map[string]bool{}
+	s.mu.Unlock()
+}
+
+// get returns the directives of a file relative to root, POSIX style. A file
+// that can't be read has no directives.
+func (s *suppressions) get(file string) []directive {
+	s.mu.Lock()
+	d, ok := s.directives[file]
+	s.mu.Unlock()
+	if ok {
+		return d
+	}
+	p := filepath.Join(s.root, filepath.FromSlash(file))
+	if b, ok := inMemoryContent(s.scm, p); ok {
+		d = scanDirectives(bytes.NewReader(b))
+	} else if f, err := os.Open(p); err == nil {
+		d = scanDirectives(f)
+		_ = f.Close()
+	}
+	s.mu.Lock()
+	if s.directives == nil {
+		s.directives = map[string][]directive{}
+	}
+	s.directives[file] = d
+	s.mu.Unlock()
+	return d
+}
+
+// scanDirectives returns the shac:ignore directives read from r.
+//
+// Only the lines containing a directive are kept in memory, unless they don't
+// fit in the read buffer.
+func scanDirectives(r io.Reader) []directive {
+	var out []directive
+	br := bufio.NewReader(r)
+	var long []byte
+	for line := 1; ; {
+		b, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			long = append(long, b...)
+			continue
+		}
+		if long != nil {
+			b = append(long, b...)
+			long = nil
+		}
+		if bytes.Contains(b, []byte("shac:ignore(")) {
+			if d, ok := parseDirective(string(b), line); ok {
+				out = append(out, d)
+			}
+		}
+		if err != nil {
+			return out
+		}
+		line++
+	}
+}
+
+// match returns true and the reason of the directive suppressing a finding of
+// the check starting at line in file, if any.
+//
+// file is relative to the root, POSIX style.
+func (s *suppressions) match(check, file string, line int) (string, bool) {
+	if file == "" || line <= 0 {
+		return "", false
+	}
+	for _, d := range s.get(file) {
+		if (d.line != line && d.line != line-1) || !slices.Contains(d.checks, check) {
+			continue
+		}
+		s.mu.Lock()
+		s.used[check+"\x00"+file+"\x00"+fmt.Sprint(d.line)] = true
+		s.mu.Unlock()
+		return d.reason, true
+	}
+	return "", false
+}
+
+// unused returns the directives in file naming the check that didn't suppress
+// any of its findings.
+func (s *suppressions) unused(check, file string) []directive {
+	var out []directive
+	for _, d := range s.get(file) {
+		if !slices.Contains(d.checks, check) {
+			continue
+		}
+		s.mu.Lock()
+		used := s.used[check+"\x00"+file+"\x00"+fmt.Sprint(d.line)]
+		s.mu.Unlock()
+		if !used {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// parseDirective returns the shac:ignore directive on a line, if any.
+func parseDirective(l string, line int) (directive, bool) {
+	if !strings.Contains(l, "shac:ignore(") {
+		return directive{}, false
+	}
+	m := ignoreDirective.FindStringSubmatch(l)
+	if m == nil {
+		return directive{}, false
+	}
+	d := directive{line: line, reason: strings.TrimSpace(m[2])}
+	for _, c := range strings.Split(m[1], ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			d.checks = append(d.checks, c)
+		}
+	}
+	if len(d.checks) == 0 {
+		return directive{}, false
+	}
+	return d, true
+}
+
+// flagUnusedSuppressions emits a finding at the given level for each
+// shac:ignore directive naming the check in the files visible to it that
+// didn't suppress any of its findings.
+func (s *shacState) flagUnusedSuppressions(ctx context.Context, c *registeredCheck, level Level) error {
+	files, err := s.scm.affectedFiles(ctx, fileFilter{})
+	if err != nil {
+		return err
+	}
+	root := filepath.Join(s.root, s.subdir)
+	for _, f := range s.visibleFiles(c, files) {
+		for _, d := range s.suppressions.unused(c.name, f.rootedpath()) {
+			if c.highestLevel == "" || level == Error || (level == Warning && c.highestLevel != Error) {
+				c.highestLevel = level
+			}
+			msg := fmt.Sprintf("Unused shac:ignore directive for check %q.", c.name)
+			if err = s.r.EmitFinding(ctx, c.name, level, msg, root, f.relpath(), Span{Start: Cursor{Line: d.line}}, nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
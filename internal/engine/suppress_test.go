@@ -0,0 +1,176 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun_Suppressions(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "a.txt",
+		"foo  # shac:ignore(lint): known issue",
+		"# shac:ignore(other, lint)",
+		"foo",
+		"foo",
+		"# shac:ignore(lint): stale",
+		"bar")
+	writeFile(t, root, "shac.star",
+		"def lint(ctx):",
+		"    for f in ctx.scm.all_files(glob = \"*.txt\"):",
+		"        for i, l in enumerate(str(ctx.io.read_file(f)).splitlines()):",
+		"            if l.startswith(\"foo\"):",
+		"                ctx.emit.finding(level = \"error\", message = \"no foo\", filepath = f, line = i + 1)",
+		"shac.register_check(lint)")
+
+	r := &reportSuppressed{reportEmitNoPrint: reportEmitNoPrint{reportNoPrint: reportNoPrint{t: t}}}
+	o := Options{Report: r, Dir: root, UnusedSuppressions: Warning}
+	if err := Run(context.Background(), &o); !errors.Is(err, ErrCheckFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []finding{
+		{
+			Check:   "lint",
+			Level:   Error,
+			Message: "no foo",
+			Root:    root,
+			File:    "a.txt",
+			Span:    Span{Start: Cursor{Line: 4}},
+		},
+		{
+			Check:   "lint",
+			Level:   Warning,
+			Message: "Unused shac:ignore directive for check \"lint\".",
+			Root:    root,
+			File:    "a.txt",
+			Span:    Span{Start: Cursor{Line: 5}},
+		},
+	}
+	if diff := cmp.Diff(want, r.findings); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	wantSuppressed := []suppressedFinding{
+		{Line: 1, Justification: "known issue"},
+		{Line: 3},
+	}
+	if diff := cmp.Diff(wantSuppressed, r.suppressed); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRun_Suppressions_Stdin(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "a.txt", "foo", "foo")
+	writeFile(t, root, "shac.star",
+		"def lint(ctx):",
+		"    for f in ctx.scm.all_files(glob = \"*.txt\"):",
+		"        for i, l in enumerate(str(ctx.io.read_file(f)).splitlines()):",
+		"            if l.startswith(\"foo\"):",
+		"                ctx.emit.finding(level = \"error\", message = \"no foo\", filepath = f, line = i + 1)",
+		"shac.register_check(lint)")
+
+	// The directives and the lines fingerprinted by the baseline are read from
+	// the buffer, not from the file on disk.
+	b := &Baseline{Findings: []*BaselineFinding{
+		{Fingerprint: fingerprint("lint", "a.txt", "no foo", "foo baseline"), Check: "lint", File: "a.txt", Message: "no foo"},
+	}}
+	r := &reportSuppressed{reportEmitNoPrint: reportEmitNoPrint{reportNoPrint: reportNoPrint{t: t}}}
+	o := Options{
+		Report:   r,
+		Dir:      root,
+		Files:    []string{filepath.Join(root, "a.txt")},
+		Stdin:    []byte("foo  # shac:ignore(lint)\nbar\nfoo baseline\n"),
+		Baseline: b,
+	}
+	if err := Run(context.Background(), &o); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.findings) != 0 {
+		t.Errorf("unexpected findings: %+v", r.findings)
+	}
+	if diff := cmp.Diff([]suppressedFinding{{Line: 1}}, r.suppressed); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(BaselineStats{Suppressed: 1}, b.Stats()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	t.Parallel()
+	data := []struct {
+		in   string
+		want directive
+		ok   bool
+	}{
+		{"foo()", directive{}, false},
+		{"# shac:ignore()", directive{}, false},
+		{"# shac:ignore(a)", directive{line: 1, checks: []string{"a"}}, true},
+		{"// shac:ignore( a ,b ): the reason ", directive{line: 1, checks: []string{"a", "b"}, reason: "the reason"}, true},
+	}
+	for i, l := range data {
+		got, ok := parseDirective(l.in, 1)
+		if ok != l.ok {
+			t.Errorf("#%d: expected %t, got %t", i, l.ok, ok)
+		}
+		if diff := cmp.Diff(l.want, got, cmp.AllowUnexported(directive{})); diff != "" {
+			t.Errorf("#%d: mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+type suppressedFinding struct {
+	Line          int
+	Justification string
+}
+
+type reportSuppressed struct {
+	reportEmitNoPrint
+	suppressed []suppressedFinding
+}
+
+func (r *reportSuppressed) EmitSuppressedFinding(ctx context.Context, check string, level Level, message, root, file string, s Span, justification string) error {
+	r.mu.Lock()
+	r.suppressed = append(r.suppressed, suppressedFinding{Line: s.Start.Line, Justification: justification})
+	r.mu.Unlock()
+	return nil
+}
+
+func TestScanDirectives(t *testing.T) {
+	t.Parallel()
+	in := "foo\n" +
+		"# shac:ignore(a): first\n" +
+		// Longer than the read buffer.
+		strings.Repeat("x", 10000) + "  # shac:ignore(b)\n" +
+		"\x00\x01binary\n" +
+		"# shac:ignore(c)"
+	want := []directive{
+		{line: 2, checks: []string{"a"}, reason: "first"},
+		{line: 3, checks: []string{"b"}},
+		{line: 5, checks: []string{"c"}},
+	}
+	got := scanDirectives(strings.NewReader(in))
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(directive{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
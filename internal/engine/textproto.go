@@ -0,0 +1,438 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tpToken is a token of a textproto document.
+type tpToken struct {
+	// kind is one of '#' for a comment, '"' for a string, 'a' for an
+	// identifier or a number, or the punctuation character itself.
+	kind byte
+	text string
+	// start and end are the offsets of the token in the document.
+	start, end int
+}
+
+// tpNode is a field of a textproto document, with its location so that the
+// document can be edited in place without losing the comments.
+//
+// The elements of a list field, e.g. `foo: [{...}, {...}]`, are each a node.
+type tpNode struct {
+	name string
+	// comments are the comment lines immediately preceding the field.
+	comments []string
+	// start and end are the offsets of the field in the document, from its
+	// name to the end of its value, including a trailing separator.
+	start, end int
+	// value is the raw token of a scalar field.
+	value string
+	// children are the fields of a message field, nil for a scalar field.
+	children []*tpNode
+	// trailing are the comment lines after the last field of a message field.
+	trailing []string
+	// inList is true for the elements of a list field, whose span doesn't
+	// cover the field name.
+	inList bool
+}
+
+// str returns the value of the scalar field named name, unquoted.
+func (n *tpNode) str(name string) string {
+	for _, c := range n.children {
+		if c.name == name {
+			if s, err := strconv.Unquote(c.value); err == nil {
+				return s
+			}
+			return strings.Trim(c.value, "'\"")
+		}
+	}
+	return ""
+}
+
+// parseTextproto returns the top level fields of a textproto document and the
+// comments after the last one.
+func parseTextproto(b []byte) ([]*tpNode, []string, error) {
+	toks, err := tokenizeTextproto(string(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	p := tpParser{toks: toks}
+	nodes, trailing, err := p.fields(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.i != len(p.toks) {
+		return nil, nil, fmt.Errorf("unexpected %q at offset %d", p.toks[p.i].text, p.toks[p.i].start)
+	}
+	return nodes, trailing, nil
+}
+
+func tokenizeTextproto(s string) ([]tpToken, error) {
+	var out []tpToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#':
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				j = len(s) - i
+			}
+			out = append(out, tpToken{kind: '#', text: strings.TrimRight(s[i:i+j], " \t\r"), start: i, end: i + j})
+			i += j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' {
+					j++
+				} else if s[j] == '\n' {
+					break
+				}
+			}
+			if j >= len(s) || s[j] != c {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			out = append(out, tpToken{kind: '"', text: s[i : j+1], start: i, end: j + 1})
+			i = j + 1
+		case strings.IndexByte("{}[]<>:;,", c) >= 0:
+			out = append(out, tpToken{kind: c, text: s[i : i+1], start: i, end: i + 1})
+			i++
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t\r\n#\"'{}[]<>:;,", s[j]) < 0 {
+				j++
+			}
+			out = append(out, tpToken{kind: 'a', text: s[i:j], start: i, end: j})
+			i = j
+		}
+	}
+	return out, nil
+}
+
+type tpParser struct {
+	toks []tpToken
+	i    int
+}
+
+// comments consumes the comment tokens at the current position.
+func (p *tpParser) comments() []string {
+	var out []string
+	for ; p.i < len(p.toks) && p.toks[p.i].kind == '#'; p.i++ {
+		out = append(out, p.toks[p.i].text)
+	}
+	return out
+}
+
+// peek returns the kind of the current token, or 0 at the end.
+func (p *tpParser) peek() byte {
+	if p.i < len(p.toks) {
+		return p.toks[p.i].kind
+	}
+	return 0
+}
+
+// fields parses fields until the closing character, or the end of the
+// document if it is 0.
+func (p *tpParser) fields(closing byte) ([]*tpNode, []string, error) {
+	var out []*tpNode
+	for {
+		comments := p.comments()
+		switch k := p.peek(); {
+		case k == closing:
+			return out, comments, nil
+		case k != 'a':
+			if k == 0 {
+				return nil, nil, fmt.Errorf("expected %q, got the end of the document", closing)
+			}
+			return nil, nil, fmt.Errorf("expected a field name at offset %d, got %q", p.toks[p.i].start, p.toks[p.i].text)
+		}
+		name := p.toks[p.i]
+		p.i++
+		colon := p.peek() == ':'
+		if colon {
+			p.i++
+		}
+		if p.peek() == '[' {
+			p.i++
+			for first := true; ; first = false {
+				elemComments := p.comments()
+				if p.peek() == ']' {
+					p.i++
+					break
+				}
+				if !first {
+					if p.peek() != ',' {
+						return nil, nil, fmt.Errorf("expected ',' in list %s", name.text)
+					}
+					p.i++
+					elemComments = append(elemComments, p.comments()...)
+				}
+				if p.i == len(p.toks) {
+					return nil, nil, fmt.Errorf("unterminated list %s", name.text)
+				}
+				n := &tpNode{name: name.text, comments: elemComments, start: p.toks[p.i].start, inList: true}
+				if err := p.value(n, true); err != nil {
+					return nil, nil, err
+				}
+				out = append(out, n)
+			}
+			if k := p.peek(); k == ';' || k == ',' {
+				p.i++
+			}
+			continue
+		}
+		n := &tpNode{name: name.text, comments: comments, start: name.start}
+		if err := p.value(n, colon); err != nil {
+			return nil, nil, err
+		}
+		if k := p.peek(); k == ';' || k == ',' {
+			n.end = p.toks[p.i].end
+			p.i++
+		}
+		out = append(out, n)
+	}
+}
+
+// value parses the value of a field into n.
+func (p *tpParser) value(n *tpNode, colon bool) error {
+	switch k := p.peek(); k {
+	case '{', '<':
+		p.i++
+		closing := byte('}')
+		if k == '<' {
+			closing = '>'
+		}
+		var err error
+		if n.children, n.trailing, err = p.fields(closing); err != nil {
+			return err
+		}
+		n.end = p.toks[p.i].end
+		p.i++
+		if n.children == nil {
+			n.children = []*tpNode{}
+		}
+		return nil
+	case 'a', '"':
+		if !colon {
+			return fmt.Errorf("expected ':' after %s", n.name)
+		}
+		t := p.toks[p.i]
+		n.value = t.text
+		n.end = t.end
+		p.i++
+		// Adjacent strings are concatenated.
+		for t.kind == '"' && p.peek() == '"' {
+			n.value = n.value[:len(n.value)-1] + p.toks[p.i].text[1:]
+			n.end = p.toks[p.i].end
+			p.i++
+		}
+		return nil
+	default:
+		return fmt.Errorf("expected a value for %s", n.name)
+	}
+}
+
+// setPackages returns the textproto document b with its requirements and sum
+// fields replaced by req and sum, or removed if they are empty.
+//
+// The rest of the document is kept as is. The comments in the replaced fields
+// are kept when the field they precede is still present.
+func setPackages(b []byte, req *Requirements, sum *Sum) ([]byte, error) {
+	nodes, _, err := parseTextproto(b)
+	if err != nil {
+		return nil, err
+	}
+	w := tpWriter{comments: map[string][]string{}}
+	var reqNodes, sumNodes []*tpNode
+	for _, n := range nodes {
+		switch n.name {
+		case "requirements":
+			reqNodes = append(reqNodes, n)
+		case "sum":
+			sumNodes = append(sumNodes, n)
+		default:
+			continue
+		}
+		if n.inList || n.children == nil {
+			return nil, fmt.Errorf("%s must be a message field to be edited", n.name)
+		}
+		w.collect(n.name, n)
+	}
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	var appended strings.Builder
+	for _, f := range []struct {
+		nodes []*tpNode
+		text  string
+	}{
+		{reqNodes, w.requirements(req)},
+		{sumNodes, w.sum(sum)},
+	} {
+		for i, n := range f.nodes {
+			if i == 0 && f.text != "" {
+				edits = append(edits, edit{n.start, n.end, f.text})
+				continue
+			}
+			// Remove the field and the rest of its line, and the line itself if
+			// it becomes empty.
+			start, end := n.start, n.end
+			for end < len(b) && (b[end] == ' ' || b[end] == '\t') {
+				end++
+			}
+			if end < len(b) && b[end] == '\n' {
+				end++
+				for start > 0 && (b[start-1] == ' ' || b[start-1] == '\t') {
+					start--
+				}
+			}
+			edits = append(edits, edit{start, end, ""})
+		}
+		if len(f.nodes) == 0 && f.text != "" {
+			appended.WriteString(f.text + "\n")
+		}
+	}
+	out := append([]byte{}, b...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	if appended.Len() != 0 {
+		if len(out) != 0 && out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		out = append(out, appended.String()...)
+	}
+	return out, nil
+}
+
+// tpWriter formats the requirements and sum fields, reusing the comments of
+// the fields it replaces.
+type tpWriter struct {
+	b strings.Builder
+	// comments are the comments preceding each field, keyed by the path of the
+	// field. The trailing comments of a message field are keyed with a "}"
+	// suffix.
+	comments map[string][]string
+}
+
+// collect records the comments of n and its children.
+func (w *tpWriter) collect(key string, n *tpNode) {
+	w.comments[key] = append(w.comments[key], n.comments...)
+	if len(n.trailing) != 0 {
+		w.comments[key+"}"] = append(w.comments[key+"}"], n.trailing...)
+	}
+	for _, c := range n.children {
+		w.collect(key+"/"+tpKey(c), c)
+	}
+}
+
+// tpKey identifies a field amongst its siblings.
+func tpKey(n *tpNode) string {
+	switch n.name {
+	case "direct", "indirect", "known":
+		return n.name + "[" + n.str("url") + "]"
+	case "seen":
+		return n.name + "[" + n.str("version") + "]"
+	}
+	return n.name
+}
+
+func (w *tpWriter) writeComments(key string, depth int) {
+	for _, c := range w.comments[key] {
+		w.b.WriteString(strings.Repeat("  ", depth) + c + "\n")
+	}
+}
+
+func (w *tpWriter) open(key, name string, depth int) {
+	if depth != 0 {
+		w.writeComments(key, depth)
+	}
+	w.b.WriteString(strings.Repeat("  ", depth) + name + " {\n")
+}
+
+func (w *tpWriter) close(key string, depth int) {
+	w.writeComments(key+"}", depth+1)
+	w.b.WriteString(strings.Repeat("  ", depth) + "}")
+	if depth != 0 {
+		w.b.WriteString("\n")
+	}
+}
+
+func (w *tpWriter) str(key, name, value string, depth int) {
+	if value == "" {
+		return
+	}
+	key += "/" + name
+	w.writeComments(key, depth)
+	w.b.WriteString(strings.Repeat("  ", depth) + name + ": " + strconv.Quote(value) + "\n")
+}
+
+// requirements returns req formatted, without the comments preceding it nor
+// a trailing newline.
+func (w *tpWriter) requirements(req *Requirements) string {
+	if req == nil || len(req.Direct)+len(req.Indirect) == 0 {
+		return ""
+	}
+	w.b.Reset()
+	w.open("requirements", "requirements", 0)
+	for _, l := range []struct {
+		name string
+		deps []*Dependency
+	}{{"direct", req.Direct}, {"indirect", req.Indirect}} {
+		for _, d := range l.deps {
+			key := "requirements/" + l.name + "[" + d.Url + "]"
+			w.open(key, l.name, 1)
+			w.str(key, "url", d.Url, 2)
+			w.str(key, "alias", d.Alias, 2)
+			w.str(key, "version", d.Version, 2)
+			w.close(key, 1)
+		}
+	}
+	w.close("requirements", 0)
+	return w.b.String()
+}
+
+// sum returns sum formatted, without the comments preceding it nor a trailing
+// newline.
+func (w *tpWriter) sum(sum *Sum) string {
+	if sum == nil || len(sum.Known) == 0 {
+		return ""
+	}
+	w.b.Reset()
+	w.open("sum", "sum", 0)
+	for _, k := range sum.Known {
+		key := "sum/known[" + k.Url + "]"
+		w.open(key, "known", 1)
+		w.str(key, "url", k.Url, 2)
+		for _, s := range k.Seen {
+			skey := key + "/seen[" + s.Version + "]"
+			w.open(skey, "seen", 2)
+			w.str(skey, "version", s.Version, 3)
+			w.str(skey, "digest", s.Digest, 3)
+			w.close(skey, 2)
+		}
+		w.close(key, 1)
+	}
+	w.close("sum", 0)
+	return w.b.String()
+}
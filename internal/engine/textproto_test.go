@@ -0,0 +1,156 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetPackages(t *testing.T) {
+	t.Parallel()
+	const digest = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	req := &Requirements{
+		Direct: []*Dependency{
+			{Url: "example.com/foo", Alias: "foo", Version: "2"},
+			{Url: "example.com/bar", Version: "1"},
+		},
+	}
+	sum := &Sum{
+		Known: []*Known{
+			{Url: "example.com/foo", Seen: []*VersionDigest{{Version: "2", Digest: digest}}},
+			{Url: "example.com/bar", Seen: []*VersionDigest{{Version: "1", Digest: digest}}},
+		},
+	}
+	data := []struct {
+		name string
+		in   string
+		req  *Requirements
+		sum  *Sum
+		want string
+	}{
+		{
+			"append",
+			"# Header.\nmin_shac_version: \"0.1\"",
+			req,
+			nil,
+			"# Header.\nmin_shac_version: \"0.1\"\n" +
+				"requirements {\n" +
+				"  direct {\n" +
+				"    url: \"example.com/foo\"\n" +
+				"    alias: \"foo\"\n" +
+				"    version: \"2\"\n" +
+				"  }\n" +
+				"  direct {\n" +
+				"    url: \"example.com/bar\"\n" +
+				"    version: \"1\"\n" +
+				"  }\n" +
+				"}\n",
+		},
+		{
+			"replace",
+			"# Packages.\n" +
+				"requirements: {\n" +
+				"  # The foo package.\n" +
+				"  direct { url: \"example.com/foo\" alias: \"foo\" version: \"1\" }\n" +
+				"  # Gone.\n" +
+				"  direct { url: \"example.com/gone\" version: \"1\" }\n" +
+				"  # Trailing.\n" +
+				"}\n" +
+				"ignore: \"/vendor/\"  # Vendored.\n" +
+				"sum <\n" +
+				"  known {\n" +
+				"    url: 'example.com/foo'\n" +
+				"    seen [{version: \"1\", digest: \"" + digest + "\"}]\n" +
+				"  }\n" +
+				"> # After sum.\n",
+			req,
+			sum,
+			"# Packages.\n" +
+				"requirements {\n" +
+				"  # The foo package.\n" +
+				"  direct {\n" +
+				"    url: \"example.com/foo\"\n" +
+				"    alias: \"foo\"\n" +
+				"    version: \"2\"\n" +
+				"  }\n" +
+				"  direct {\n" +
+				"    url: \"example.com/bar\"\n" +
+				"    version: \"1\"\n" +
+				"  }\n" +
+				"  # Trailing.\n" +
+				"}\n" +
+				"ignore: \"/vendor/\"  # Vendored.\n" +
+				"sum {\n" +
+				"  known {\n" +
+				"    url: \"example.com/foo\"\n" +
+				"    seen {\n" +
+				"      version: \"2\"\n" +
+				"      digest: \"" + digest + "\"\n" +
+				"    }\n" +
+				"  }\n" +
+				"  known {\n" +
+				"    url: \"example.com/bar\"\n" +
+				"    seen {\n" +
+				"      version: \"1\"\n" +
+				"      digest: \"" + digest + "\"\n" +
+				"    }\n" +
+				"  }\n" +
+				"} # After sum.\n",
+		},
+		{
+			"remove",
+			"requirements {\n  direct { url: \"example.com/foo\" version: \"1\" }\n}\nsum {}\nignore: \"x\"\n",
+			nil,
+			&Sum{},
+			"ignore: \"x\"\n",
+		},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			got, err := setPackages([]byte(data[i].in), data[i].req, data[i].sum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(data[i].want, string(got)); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetPackages_Err(t *testing.T) {
+	t.Parallel()
+	data := []struct {
+		in   string
+		want string
+	}{
+		{"requirements {", "expected '}', got the end of the document"},
+		{"url: \"foo", "unterminated string at offset 5"},
+		{"requirements: [{}]", "requirements must be a message field to be edited"},
+		{"requirements: \"foo\"", "requirements must be a message field to be edited"},
+		{"} foo", "expected a field name at offset 0, got \"}\""},
+		{"foo {} }", "expected a field name at offset 7, got \"}\""},
+		{"foo 1", "expected ':' after foo"},
+	}
+	for i, l := range data {
+		_, err := setPackages([]byte(l.in), nil, nil)
+		if err == nil || err.Error() != l.want {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+	}
+}
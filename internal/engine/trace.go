@@ -0,0 +1,114 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// tracer records spans in the Chrome trace event format, which can be
+// opened in chrome://tracing or https://ui.perfetto.dev.
+//
+// See
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+// for the format.
+type tracer struct {
+	start time.Time
+
+	mu     sync.Mutex
+	events []traceEvent
+	// lanes tracks which lanes are in use. Each span is put in the lowest
+	// free lane, which is exposed as a thread id, so that concurrent spans
+	// don't overlap in the viewer.
+	lanes []bool
+}
+
+type traceEvent struct {
+	Name     string            `json:"name"`
+	Category string            `json:"cat"`
+	Phase    string            `json:"ph"`
+	TS       int64             `json:"ts"`
+	Duration int64             `json:"dur"`
+	PID      int               `json:"pid"`
+	TID      int               `json:"tid"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+func newTracer() *tracer {
+	return &tracer{start: time.Now()}
+}
+
+// begin starts a span and returns the function to call to end it.
+//
+// It is safe to call on a nil tracer, in which case nothing is recorded.
+func (t *tracer) begin(category, name string, args map[string]string) func() {
+	if t == nil {
+		return func() {}
+	}
+	t.mu.Lock()
+	lane := slices.Index(t.lanes, false)
+	if lane == -1 {
+		lane = len(t.lanes)
+		t.lanes = append(t.lanes, true)
+	} else {
+		t.lanes[lane] = true
+	}
+	t.mu.Unlock()
+	start := time.Now()
+	return func() {
+		end := time.Now()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.lanes[lane] = false
+		t.events = append(t.events, traceEvent{
+			Name:     name,
+			Category: category,
+			Phase:    "X",
+			TS:       start.Sub(t.start).Microseconds(),
+			Duration: end.Sub(start).Microseconds(),
+			PID:      os.Getpid(),
+			TID:      lane + 1,
+			Args:     args,
+		})
+	}
+}
+
+// write writes the recorded spans as JSON.
+func (t *tracer) write(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	events := slices.Clone(t.events)
+	slices.SortStableFunc(events, func(a, b traceEvent) int {
+		return int(a.TS - b.TS)
+	})
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
+
+var tracerCtxKey = "shac.tracer"
+
+// ctxTracer returns the tracer of the run, or nil if tracing is disabled.
+func ctxTracer(ctx context.Context) *tracer {
+	t, _ := ctx.Value(&tracerCtxKey).(*tracer)
+	return t
+}
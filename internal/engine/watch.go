@@ -0,0 +1,448 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the file tree must be left unmodified before the
+// modifications are processed. Overridden in unit tests.
+var watchDebounce = 500 * time.Millisecond
+
+// Watch runs the checks like Run() does, then keeps the parsed Starlark code
+// resident and runs the checks again every time files are modified, until ctx
+// is canceled.
+//
+// Only the checks that requested modified files via ctx.scm.affected_files() or
+// ctx.scm.all_files() are run again. All the checks are run again when a commit
+// is made or checked out, since it changes the affected files. The Starlark
+// code is parsed again when a loaded .star file is modified and everything is
+// reinitialized when shac.textproto is modified.
+//
+// Modifications are detected with file system notifications. The directories
+// matched by the ignore patterns in shac.textproto and the ones ignored by the
+// SCM, e.g. build output listed in .gitignore, are not watched. Modifications
+// of the files ignored by the SCM don't run the checks again.
+//
+// Errors that happen after the first run are written to w, they do not stop
+// watching.
+func Watch(ctx context.Context, o *Options, w io.Writer) error {
+	tmpdir, err := os.MkdirTemp("", "shac")
+	if err != nil {
+		return err
+	}
+	err = watchInner(ctx, o, tmpdir, w)
+	if err2 := os.RemoveAll(tmpdir); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func watchInner(ctx context.Context, o *Options, tmpdir string, w io.Writer) error {
+	var r *runner
+	gen := 0
+	// newGeneration creates a new runner in its own temporary directory, so
+	// packages are fetched again.
+	newGeneration := func() error {
+		d := filepath.Join(tmpdir, strconv.Itoa(gen))
+		gen++
+		if err := os.Mkdir(d, 0o700); err != nil {
+			return err
+		}
+		nr, err := newRunner(ctx, o, d)
+		if err != nil {
+			_ = os.RemoveAll(d)
+			return err
+		}
+		if r != nil {
+			r.close()
+			_ = os.RemoveAll(r.tmpdir)
+		}
+		r = nr
+		return nil
+	}
+	if err := newGeneration(); err != nil {
+		return err
+	}
+	defer func() {
+		r.close()
+	}()
+	config := r.config
+	if filepath.IsAbs(config) {
+		rel, err := filepath.Rel(r.root, config)
+		if err != nil {
+			return err
+		}
+		config = rel
+	}
+	config = filepath.ToSlash(config)
+	t, err := r.newTreeWatcher(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = t.Close()
+	}()
+
+	needRunner := false
+	needLoad := true
+	needAll := false
+	var changed []string
+	for {
+		if needRunner {
+			if err = newGeneration(); err == nil {
+				// The ignore patterns may have changed.
+				var nt *treeWatcher
+				if nt, err = r.newTreeWatcher(ctx, config); err == nil {
+					_ = t.Close()
+					t = nt
+				}
+			}
+		} else if needAll {
+			err = reinitSCM(ctx, r.scm)
+		}
+		if err == nil {
+			needRunner = false
+			if needLoad {
+				err = r.load(ctx)
+			}
+		}
+		if err == nil {
+			var include func(*registeredCheck) bool
+			if !needLoad && !needAll {
+				include = func(c *registeredCheck) bool {
+					return c.dependsOn(r.root, changed)
+				}
+			}
+			needLoad, needAll = false, false
+			err = r.runChecks(ctx, include)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, ErrCheckFailed) {
+			fmt.Fprintf(w, "%s\n", err)
+		}
+
+		changed = nil
+		all := false
+		for len(changed) == 0 && !all {
+			if changed, all, err = t.wait(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			changed = r.filterChanged(ctx, changed, config)
+		}
+		if all {
+			needAll = true
+			fmt.Fprintf(w, "Detected changes in the checkout\n")
+		} else {
+			noun := "file"
+			if len(changed) != 1 {
+				noun += "s"
+			}
+			fmt.Fprintf(w, "Detected changes in %d %s\n", len(changed), noun)
+		}
+		invalidateSCM(r.scm)
+		if slices.Contains(changed, config) {
+			needRunner = true
+			needLoad = true
+		} else if r.loads(changed) {
+			needLoad = true
+		}
+		err = nil
+	}
+}
+
+// filterChanged removes the files matched by the ignore patterns in
+// shac.textproto, the files in the cache directory and the files ignored by
+// the SCM, except for the config file itself.
+func (r *runner) filterChanged(ctx context.Context, changed []string, config string) []string {
+	m, err := r.doc.ignoreMatcher()
+	if err != nil {
+		// Can't happen since the matcher was already created once.
+		return changed
+	}
+	var out []string
+	for _, f := range changed {
+		if f == config || m == nil || !m.Match(strings.Split(f, "/"), false) {
+			out = append(out, f)
+		}
+	}
+	ignored, err := ignoredFiles(ctx, r.scm, out)
+	if err != nil || len(ignored) == 0 {
+		// When the SCM can't be queried, err on the side of running the checks.
+		return out
+	}
+	return slices.DeleteFunc(out, func(f string) bool {
+		return f != config && slices.Contains(ignored, f)
+	})
+}
+
+// loads returns true if any of the files is a Starlark file that was loaded or
+// that could be discovered as a new entry point.
+func (r *runner) loads(files []string) bool {
+	if r.env == nil {
+		return true
+	}
+	r.env.mu.Lock()
+	defer r.env.mu.Unlock()
+	for _, f := range files {
+		if r.o.Recurse && path.Base(f) == r.entryPoint {
+			return true
+		}
+		k := sourceKey{pkg: "__main__", relpath: f}
+		if _, ok := r.env.sources[k.String()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newTreeWatcher watches the root directory, skipping the directories matched
+// by the ignore patterns and the directories ignored by the SCM, unless they
+// contain the config file.
+func (r *runner) newTreeWatcher(ctx context.Context, config string) (*treeWatcher, error) {
+	m, err := r.doc.ignoreMatcher()
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := ignoredDirs(ctx, r.scm)
+	if err != nil {
+		return nil, err
+	}
+	ignored := make(map[string]struct{}, len(dirs))
+	for _, d := range dirs {
+		ignored[d] = struct{}{}
+	}
+	started := false
+	t, err := newTreeWatcher(r.root, func(rel string) bool {
+		if strings.HasPrefix(config, rel+"/") {
+			return false
+		}
+		if m != nil && m.Match(strings.Split(rel, "/"), true) {
+			return true
+		}
+		if _, ok := ignored[rel]; ok {
+			return true
+		}
+		if !started {
+			// The directories ignored by the SCM were all listed above.
+			return false
+		}
+		// The directory was created since, e.g. build output.
+		l, err := ignoredFiles(ctx, r.scm, []string{rel})
+		return err == nil && len(l) != 0
+	})
+	started = true
+	return t, err
+}
+
+// treeWatcher reports the modifications of the files in a tree.
+//
+// The .git directories are not watched, except for the files of the root one
+// that change when a commit is made or checked out.
+type treeWatcher struct {
+	root string
+	// skip returns true for the directories that must not be watched, as
+	// paths relative to root, POSIX style.
+	skip func(rel string) bool
+	w    *fsnotify.Watcher
+	// gitDir is the path of the .git directory of root, if any.
+	gitDir string
+	// debounce is how long the tree must be left unmodified before wait
+	// returns.
+	debounce time.Duration
+}
+
+func newTreeWatcher(root string, skip func(rel string) bool) (*treeWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	t := &treeWatcher{root: root, skip: skip, w: w, debounce: watchDebounce}
+	if _, err = t.addTree(root); err == nil {
+		err = t.addGit()
+	}
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Close stops watching.
+func (t *treeWatcher) Close() error {
+	return t.w.Close()
+}
+
+// rel returns the path of p relative to the root, POSIX style.
+func (t *treeWatcher) rel(p string) string {
+	if p == t.root {
+		return ""
+	}
+	return filepath.ToSlash(p[len(t.root)+1:])
+}
+
+// addTree watches dir and its subdirectories, and returns the files found in
+// them.
+func (t *treeWatcher) addTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The file may have been deleted while walking.
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, t.rel(p))
+			return nil
+		}
+		if p != t.root && (d.Name() == ".git" || t.skip(t.rel(p))) {
+			return fs.SkipDir
+		}
+		if err = t.w.Add(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	return files, err
+}
+
+// addGit watches the files that change when a commit is made or checked out:
+// HEAD, packed-refs and the branches in refs/heads.
+func (t *treeWatcher) addGit() error {
+	d := filepath.Join(t.root, ".git")
+	if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+		return nil
+	}
+	t.gitDir = d
+	if err := t.w.Add(d); err != nil {
+		return err
+	}
+	return filepath.WalkDir(filepath.Join(d, "refs", "heads"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return t.w.Add(p)
+		}
+		return nil
+	})
+}
+
+// wait blocks until files are added, modified or deleted, and returns their
+// paths relative to the root, POSIX style. all is true when any file may have
+// been modified, because a commit was made or checked out or because
+// notifications were lost.
+//
+// It only returns once no modification happened for t.debounce, so that a
+// burst of modifications is returned at once.
+func (t *treeWatcher) wait(ctx context.Context) (changed []string, all bool, err error) {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-debounce:
+			slices.Sort(changed)
+			return slices.Compact(changed), all, nil
+		case err = <-t.w.Errors:
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return nil, false, err
+			}
+			all = true
+		case ev := <-t.w.Events:
+			files, git, err := t.handle(ev)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(files) == 0 && !git {
+				continue
+			}
+			changed = append(changed, files...)
+			all = all || git
+		}
+		debounce = time.After(t.debounce)
+	}
+}
+
+// handle returns the files modified according to the event, or true if a
+// commit was made or checked out.
+func (t *treeWatcher) handle(ev fsnotify.Event) ([]string, bool, error) {
+	if ev.Op == fsnotify.Chmod {
+		// The content didn't change.
+		return nil, false, nil
+	}
+	if t.gitDir != "" && strings.HasPrefix(ev.Name, t.gitDir+string(filepath.Separator)) {
+		return nil, t.handleGit(ev), nil
+	}
+	rel := t.rel(ev.Name)
+	if ev.Has(fsnotify.Create) {
+		if fi, err := os.Lstat(ev.Name); err == nil && fi.IsDir() {
+			if path.Base(rel) == ".git" || t.skip(rel) {
+				return nil, false, nil
+			}
+			// Files may have been created before the directory was watched.
+			files, err := t.addTree(ev.Name)
+			return files, false, err
+		}
+	}
+	return []string{rel}, false, nil
+}
+
+// handleGit returns true if the event in the .git directory is caused by a
+// commit being made or checked out.
+func (t *treeWatcher) handleGit(ev fsnotify.Event) bool {
+	rel := filepath.ToSlash(ev.Name[len(t.gitDir)+1:])
+	if strings.HasSuffix(rel, ".lock") {
+		// Git writes to a lock file first, then renames it.
+		return false
+	}
+	if rel == "HEAD" || rel == "packed-refs" {
+		return true
+	}
+	if !strings.HasPrefix(rel, "refs/heads/") {
+		return false
+	}
+	if ev.Has(fsnotify.Create) {
+		// A new directory of branches, e.g. for "feature/foo".
+		if fi, err := os.Lstat(ev.Name); err == nil && fi.IsDir() {
+			_ = t.w.Add(ev.Name)
+		}
+	}
+	return true
+}
@@ -0,0 +1,235 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatch(t *testing.T) {
+	old := watchDebounce
+	watchDebounce = 10 * time.Millisecond
+	t.Cleanup(func() {
+		watchDebounce = old
+	})
+
+	root := resolvedTempDir(t)
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "b.md", "b")
+	writeFile(t, root, "lib.star", "SUFFIX = \"v1\"")
+	writeFile(t, root, "shac.star",
+		"load(\"//lib.star\", \"SUFFIX\")",
+		"def txt(ctx):",
+		"    print(\"txt \" + \" \".join(ctx.scm.affected_files(glob = \"*.txt\")) + \" \" + SUFFIX)",
+		"def md(ctx):",
+		"    print(\"md \" + \" \".join(ctx.scm.all_files(glob = \"*.md\")) + \" \" + SUFFIX)",
+		"shac.register_check(txt)",
+		"shac.register_check(md)")
+
+	r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+	o := Options{Report: &r, Dir: root}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, &o, io.Discard)
+	}()
+
+	waitForPrint(t, &r, "[//shac.star:3] txt a.txt v1\n"+
+		"[//shac.star:5] md b.md v1\n")
+
+	// Only the check looking at .txt files runs again.
+	writeFile(t, root, "c.txt", "c")
+	waitForPrint(t, &r, "[//shac.star:3] txt a.txt c.txt v1\n")
+
+	// Modifying a loaded file runs all the checks again.
+	writeFile(t, root, "lib.star", "SUFFIX = \"v2\"")
+	waitForPrint(t, &r, "[//shac.star:3] txt a.txt c.txt v2\n"+
+		"[//shac.star:5] md b.md v2\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if s := r.b.String(); strings.TrimSpace(s) != "" {
+		t.Fatalf("unexpected output: %q", s)
+	}
+}
+
+func TestWatch_GitIgnored(t *testing.T) {
+	old := watchDebounce
+	watchDebounce = 10 * time.Millisecond
+	t.Cleanup(func() {
+		watchDebounce = old
+	})
+
+	root := resolvedTempDir(t)
+	initGit(t, root)
+	writeFile(t, root, ".gitignore", "out/\n*.log\n")
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "out/gen.txt", "gen")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(\"files: \" + \" \".join(ctx.scm.all_files()))",
+		"shac.register_check(cb)")
+	runGit(t, root, "add", ".gitignore", "shac.star")
+	runGit(t, root, "commit", "-m", "first")
+	runGit(t, root, "add", "a.txt")
+	runGit(t, root, "commit", "-m", "second")
+
+	r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+	o := Options{Report: &r, Dir: root}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, &o, io.Discard)
+	}()
+	waitForPrint(t, &r, "[//shac.star:2] files: .gitignore a.txt shac.star\n")
+
+	// Writing to files ignored by git, e.g. build output, doesn't run the
+	// checks again. Otherwise a check writing into the tree would run forever.
+	writeFile(t, root, "out/gen.txt", "modified")
+	writeFile(t, root, "out/new/gen.txt", "new")
+	writeFile(t, root, "x.log", "log")
+	time.Sleep(200 * time.Millisecond)
+	writeFile(t, root, "b.txt", "b")
+	waitForPrint(t, &r, "[//shac.star:2] files: .gitignore a.txt b.txt shac.star\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch_Commit(t *testing.T) {
+	old := watchDebounce
+	watchDebounce = 10 * time.Millisecond
+	t.Cleanup(func() {
+		watchDebounce = old
+	})
+
+	root := resolvedTempDir(t)
+	initGit(t, root)
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "shac.star",
+		"def cb(ctx):",
+		"    print(\"files: \" + \" \".join(ctx.scm.affected_files(glob = \"*.txt\")))",
+		"shac.register_check(cb)")
+	runGit(t, root, "add", "a.txt", "shac.star")
+	runGit(t, root, "commit", "-m", "first")
+	writeFile(t, root, "b.txt", "b")
+	runGit(t, root, "add", "b.txt")
+	runGit(t, root, "commit", "-m", "second")
+
+	r := reportPrint{reportNoPrint: reportNoPrint{t: t}}
+	o := Options{Report: &r, Dir: root}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, &o, io.Discard)
+	}()
+	waitForPrint(t, &r, "[//shac.star:2] files: b.txt\n")
+
+	// A commit that doesn't modify any file changes the affected files.
+	runGit(t, root, "commit", "--allow-empty", "-m", "third")
+	waitForPrint(t, &r, "[//shac.star:2] files: \n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTreeWatcher(t *testing.T) {
+	t.Parallel()
+	root := resolvedTempDir(t)
+	writeFile(t, root, "a.txt", "a")
+	writeFile(t, root, "b.txt", "b")
+	writeFile(t, root, "node_modules/x.js", "x")
+	writeFile(t, root, ".git/HEAD", "ref: refs/heads/main")
+	writeFile(t, root, ".git/refs/heads/main", "1")
+	tw, err := newTreeWatcher(root, func(rel string) bool {
+		return rel == "node_modules"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tw.Close()
+	tw.debounce = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	wait := func(wantChanged string, wantAll bool) {
+		t.Helper()
+		changed, all, err := tw.wait(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(wantChanged, strings.Join(changed, "\n")); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+		if all != wantAll {
+			t.Fatalf("got all=%t, want %t", all, wantAll)
+		}
+	}
+
+	// Files in new directories are reported, the ignored directories and the
+	// .git files not related to commits are not.
+	writeFile(t, root, "a.txt", "modified")
+	writeFile(t, root, "c/d/e.txt", "new")
+	writeFile(t, root, "node_modules/x.js", "modified")
+	writeFile(t, root, "node_modules/y/z.js", "new")
+	writeFile(t, root, ".git/index", "modified")
+	if err = os.Remove(filepath.Join(root, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	wait("a.txt\nb.txt\nc/d/e.txt", false)
+
+	writeFile(t, root, "c/d/e.txt", "modified")
+	wait("c/d/e.txt", false)
+
+	// A commit or a checkout.
+	writeFile(t, root, ".git/refs/heads/main", "2")
+	wait("", true)
+	writeFile(t, root, ".git/HEAD", "ref: refs/heads/other")
+	wait("", true)
+}
+
+// waitForPrint waits until want was printed, then resets the output.
+func waitForPrint(t *testing.T, r *reportPrint, want string) {
+	t.Helper()
+	start := time.Now()
+	for {
+		r.mu.Lock()
+		got := r.b.String()
+		if sortLines(got) == sortLines(want) {
+			r.b.Reset()
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("mismatch (-want +got):\n%s", cmp.Diff(want, got))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,535 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lsp implements a Language Server Protocol server that runs shac
+// checks on the documents opened in an editor and publishes the findings as
+// diagnostics.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
+)
+
+// Serve reads requests from r and writes responses and notifications to w
+// until the client sends the "exit" notification, r is closed or ctx is
+// canceled.
+//
+// o is used as a template for every run. Dir is overridden by the workspace
+// root sent by the client, Files, Stdin and Report are set for each run.
+//
+// Checks are run every time a document is opened, modified or saved, with the
+// content of the editor buffer instead of the file on disk. Modifications are
+// debounced, so the checks don't run on every keystroke.
+func Serve(ctx context.Context, o *engine.Options, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &server{
+		ctx:  ctx,
+		c:    newConn(r, w),
+		opts: *o,
+		docs: map[string]*document{},
+	}
+	err := s.serve()
+	cancel()
+	s.wg.Wait()
+	return err
+}
+
+// changeDebounce is how long a document must be left unmodified before the
+// checks run on it.
+var changeDebounce = 250 * time.Millisecond
+
+// document is a text document opened in the editor.
+type document struct {
+	text string
+	// findings are the findings from the last completed run.
+	findings []finding
+	// gen is incremented at every run, so the result of a stale run is
+	// discarded.
+	gen    int
+	cancel context.CancelFunc
+}
+
+// finding is a finding emitted for a document.
+type finding struct {
+	check        string
+	level        engine.Level
+	message      string
+	span         engine.Span
+	replacements []string
+}
+
+type server struct {
+	ctx  context.Context
+	c    *conn
+	opts engine.Options
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	docs map[string]*document
+}
+
+func (s *server) serve() error {
+	for {
+		m, err := s.c.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) || s.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+		if err = s.handle(m); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a request or a notification. Only errors writing to the
+// client are returned.
+func (s *server) handle(m *message) error {
+	var result any
+	var err error
+	switch m.Method {
+	case "initialize":
+		result, err = s.initialize(m.Params)
+	case "initialized":
+	case "shutdown":
+	case "textDocument/didOpen":
+		p := didOpenParams{}
+		if err = json.Unmarshal(m.Params, &p); err == nil {
+			s.open(p.TextDocument.URI, p.TextDocument.Text, 0)
+		}
+	case "textDocument/didChange":
+		p := didChangeParams{}
+		if err = json.Unmarshal(m.Params, &p); err == nil && len(p.ContentChanges) != 0 {
+			// Only full document synchronization is supported, so the last
+			// change is the whole document.
+			s.open(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text, changeDebounce)
+		}
+	case "textDocument/didSave":
+		p := didSaveParams{}
+		if err = json.Unmarshal(m.Params, &p); err == nil {
+			s.check(p.TextDocument.URI, 0)
+		}
+	case "textDocument/didClose":
+		p := didCloseParams{}
+		if err = json.Unmarshal(m.Params, &p); err == nil {
+			err = s.close(p.TextDocument.URI)
+		}
+	case "textDocument/codeAction":
+		p := codeActionParams{}
+		if err = json.Unmarshal(m.Params, &p); err == nil {
+			result = s.codeActions(p.TextDocument.URI, p.Range)
+		}
+	case "textDocument/formatting":
+		p := documentFormattingParams{}
+		if err = json.Unmarshal(m.Params, &p); err == nil && m.ID != nil {
+			// Formatting runs the formatters, don't block the other requests
+			// in the meantime.
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				edits, err := s.format(p.TextDocument.URI)
+				_ = s.reply(m.ID, edits, err, codeInvalidParams)
+			}()
+			return nil
+		}
+	default:
+		if m.ID == nil {
+			// Unknown notifications are ignored.
+			return nil
+		}
+		return s.reply(m.ID, nil, fmt.Errorf("method not found: %s", m.Method), codeMethodNotFound)
+	}
+	if m.ID == nil {
+		if err != nil {
+			return s.logf(messageError, "%s: %s", m.Method, err)
+		}
+		return nil
+	}
+	return s.reply(m.ID, result, err, codeInvalidParams)
+}
+
+// reply sends the response to a request.
+func (s *server) reply(id *json.RawMessage, result any, err error, code int) error {
+	m := &message{ID: id}
+	if err != nil {
+		m.Error = &responseError{Code: code, Message: err.Error()}
+	} else if result == nil {
+		// "result" must be present on success, even if null.
+		m.Result = json.RawMessage("null")
+	} else {
+		m.Result = result
+	}
+	return s.c.write(m)
+}
+
+// logf sends a message to be logged by the client.
+func (s *server) logf(typ int, format string, a ...any) error {
+	return s.c.notify("window/logMessage", &logMessageParams{Type: typ, Message: fmt.Sprintf(format, a...)})
+}
+
+func (s *server) initialize(params json.RawMessage) (any, error) {
+	p := initializeParams{}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RootURI != "" {
+		root, err := uriToPath(p.RootURI)
+		if err != nil {
+			return nil, err
+		}
+		s.opts.Dir = root
+	}
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:           textDocumentSyncOptions{OpenClose: true, Change: 1, Save: true},
+			CodeActionProvider:         codeActionOptions{CodeActionKinds: []string{"quickfix"}},
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: serverInfo{Name: "shac", Version: engine.Version.String()},
+	}, nil
+}
+
+// open records the content of a document and runs the checks on it after
+// delay.
+func (s *server) open(uri, text string, delay time.Duration) {
+	s.mu.Lock()
+	d := s.docs[uri]
+	if d == nil {
+		d = &document{}
+		s.docs[uri] = d
+	}
+	d.text = text
+	s.mu.Unlock()
+	s.check(uri, delay)
+}
+
+func (s *server) close(uri string) error {
+	s.mu.Lock()
+	if d := s.docs[uri]; d != nil {
+		if d.cancel != nil {
+			d.cancel()
+		}
+		delete(s.docs, uri)
+	}
+	s.mu.Unlock()
+	return s.c.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{URI: uri, Diagnostics: []diagnostic{}})
+}
+
+// check runs the checks on a document in the background after delay,
+// canceling the previous run if still pending or in progress, then publishes
+// the diagnostics.
+func (s *server) check(uri string, delay time.Duration) {
+	s.mu.Lock()
+	d := s.docs[uri]
+	if d == nil {
+		s.mu.Unlock()
+		return
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	d.cancel = cancel
+	d.gen++
+	gen := d.gen
+	text := d.text
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+		findings, err := s.run(ctx, uri, text, engine.AllChecks)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			_ = s.logf(messageError, "%s", err)
+			return
+		}
+		s.mu.Lock()
+		if s.docs[uri] != d || d.gen != gen {
+			s.mu.Unlock()
+			return
+		}
+		d.findings = findings
+		diags := make([]diagnostic, 0, len(findings))
+		for _, f := range findings {
+			diags = append(diags, f.diagnostic(text))
+		}
+		s.mu.Unlock()
+		_ = s.c.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+	}()
+}
+
+// run runs the checks on the content of a document and returns the findings
+// for this document.
+func (s *server) run(ctx context.Context, uri, text string, ff engine.FormatterFiltering) ([]finding, error) {
+	p, err := uriToPath(uri)
+	if err != nil {
+		return nil, err
+	}
+	r := &collector{s: s, path: p}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		r.path = resolved
+	}
+	o := s.opts
+	o.Report = r
+	o.Files = []string{p}
+	// Never nil, even for an empty buffer, so the content on disk is not used.
+	o.Stdin = append([]byte{}, text...)
+	o.Filter.FormatterFiltering = ff
+	if err = engine.Run(ctx, &o); err != nil && !errors.Is(err, engine.ErrCheckFailed) {
+		return nil, err
+	}
+	return r.findings, nil
+}
+
+func (s *server) codeActions(uri string, rng lspRange) []codeAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []codeAction{}
+	d := s.docs[uri]
+	if d == nil {
+		return out
+	}
+	for _, f := range d.findings {
+		if len(f.replacements) == 0 {
+			continue
+		}
+		// Use the range to replace instead of the diagnostic range, so fixes
+		// for the whole file are always available.
+		start, end := f.offsets(d.text)
+		editRange := lspRange{Start: offsetToPosition(d.text, start), End: offsetToPosition(d.text, end)}
+		if !editRange.overlaps(rng) {
+			continue
+		}
+		diag := f.diagnostic(d.text)
+		for _, repl := range f.replacements {
+			title := "Apply fix: " + f.message
+			if len(f.replacements) > 1 {
+				title = fmt.Sprintf("Replace with %q", repl)
+			}
+			out = append(out, codeAction{
+				Title:       title,
+				Kind:        "quickfix",
+				Diagnostics: []diagnostic{diag},
+				Edit: workspaceEdit{
+					Changes: map[string][]textEdit{uri: {{Range: editRange, NewText: repl}}},
+				},
+			})
+		}
+	}
+	return out
+}
+
+// format runs the formatter checks on a document and returns the edits.
+//
+// Like `shac fmt`, only findings with a single replacement are applied and
+// findings overlapping with previous ones are skipped.
+func (s *server) format(uri string) ([]textEdit, error) {
+	s.mu.Lock()
+	d := s.docs[uri]
+	if d == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("document not opened: %s", uri)
+	}
+	text := d.text
+	s.mu.Unlock()
+	findings, err := s.run(s.ctx, uri, text, engine.OnlyFormatters)
+	if err != nil {
+		return nil, err
+	}
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, f := range findings {
+		if len(f.replacements) == 1 {
+			start, end := f.offsets(text)
+			edits = append(edits, edit{start: start, end: end, text: f.replacements[0]})
+		}
+	}
+	slices.SortStableFunc(edits, func(a, b edit) int {
+		return a.start - b.start
+	})
+	out := []textEdit{}
+	last := -1
+	for _, e := range edits {
+		if e.start < last {
+			continue
+		}
+		last = e.end
+		out = append(out, textEdit{
+			Range:   lspRange{Start: offsetToPosition(text, e.start), End: offsetToPosition(text, e.end)},
+			NewText: e.text,
+		})
+	}
+	return out, nil
+}
+
+func (f *finding) diagnostic(text string) diagnostic {
+	d := diagnostic{Severity: severityInformation, Source: "shac", Code: f.check, Message: f.message}
+	switch f.level {
+	case engine.Error:
+		d.Severity = severityError
+	case engine.Warning:
+		d.Severity = severityWarning
+	}
+	// A finding for the whole file is shown at the beginning of the file.
+	if f.span.Start.Line != 0 {
+		start, end := f.offsets(text)
+		d.Range = lspRange{Start: offsetToPosition(text, start), End: offsetToPosition(text, end)}
+	}
+	return d
+}
+
+// offsets returns the byte offsets of the span in text, applying the same
+// defaults as `shac fmt`.
+func (f *finding) offsets(text string) (int, int) {
+	lines := strings.SplitAfter(text, "\n")
+	// lineStart returns the offset of the beginning of the 1 based line.
+	lineStart := func(l int) int {
+		o := 0
+		for i := 0; i < l-1 && i < len(lines); i++ {
+			o += len(lines[i])
+		}
+		return o
+	}
+	sp := f.span
+	if sp.Start.Line == 0 {
+		return 0, len(text)
+	}
+	if sp.End.Line == 0 {
+		sp.End.Line = sp.Start.Line
+	}
+	if sp.Start.Col == 0 {
+		sp.Start.Col = 1
+	}
+	if sp.End.Col == 0 {
+		if sp.End.Line <= len(lines) {
+			sp.End.Col = len(lines[sp.End.Line-1]) + 1
+		} else {
+			sp.End.Col = 1
+		}
+	}
+	start := min(lineStart(sp.Start.Line)+sp.Start.Col-1, len(text))
+	end := min(lineStart(sp.End.Line)+sp.End.Col-1, len(text))
+	return start, max(start, end)
+}
+
+// offsetToPosition converts a byte offset in text to a LSP position, which
+// counts characters in UTF-16 code units.
+func offsetToPosition(text string, offset int) position {
+	p := position{}
+	lineStart := 0
+	if i := strings.LastIndexByte(text[:offset], '\n'); i != -1 {
+		p.Line = strings.Count(text[:i+1], "\n")
+		lineStart = i + 1
+	}
+	for _, r := range text[lineStart:offset] {
+		if n := utf16.RuneLen(r); n > 0 {
+			p.Character += n
+		} else {
+			// Invalid UTF-8 is decoded as utf8.RuneError.
+			p.Character += utf16.RuneLen(utf8.RuneError)
+		}
+	}
+	return p
+}
+
+// uriToPath converts a "file://" URI to a local path.
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme: %s", uri)
+	}
+	p := u.Path
+	// On Windows, paths look like "/C:/foo".
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), nil
+}
+
+// collector is an engine.Report that collects the findings for a document.
+type collector struct {
+	s *server
+	// path is the absolute path of the document.
+	path string
+
+	mu       sync.Mutex
+	findings []finding
+}
+
+var _ engine.Report = (*collector)(nil)
+
+func (c *collector) EmitFinding(ctx context.Context, check string, level engine.Level, message, root, file string, s engine.Span, replacements []string, props map[string]string) error {
+	if file == "" || filepath.Join(root, filepath.FromSlash(file)) != c.path {
+		return nil
+	}
+	c.mu.Lock()
+	c.findings = append(c.findings, finding{check: check, level: level, message: message, span: s, replacements: replacements})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *collector) EmitCommitMessageFinding(ctx context.Context, check string, level engine.Level, message string, commitHash string, commitMessage string, s engine.Span, props map[string]string) error {
+	return nil
+}
+
+func (c *collector) EmitArtifact(context.Context, string, string, string, []byte) error {
+	return nil
+}
+
+func (c *collector) CheckCompleted(ctx context.Context, check string, start time.Time, d time.Duration, level engine.Level, err error) {
+	if err != nil {
+		_ = c.s.logf(messageError, "%s: %s", check, err)
+	}
+}
+
+func (c *collector) Print(ctx context.Context, check, file string, line int, message string) {
+	_ = c.s.logf(messageLog, "[%s:%d] %s", file, line, message)
+}
@@ -0,0 +1,314 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "shac.star", strings.Join([]string{
+		"def bad(ctx):",
+		"    for f in ctx.scm.affected_files():",
+		"        for i, l in enumerate(str(ctx.io.read_file(f)).splitlines()):",
+		"            idx = l.find(\"bad\")",
+		"            if idx != -1:",
+		"                ctx.emit.finding(",
+		"                    level = \"error\",",
+		"                    message = \"no bad\",",
+		"                    filepath = f,",
+		"                    line = i + 1,",
+		"                    col = idx + 1,",
+		"                    end_col = idx + 4,",
+		"                    replacements = [\"good\"])",
+		"def upper(ctx):",
+		"    for f in ctx.scm.affected_files():",
+		"        c = str(ctx.io.read_file(f))",
+		"        if c != c.upper():",
+		"            ctx.emit.finding(level = \"error\", message = \"not upper\", filepath = f, replacements = [c.upper()])",
+		"shac.register_check(bad)",
+		"shac.register_check(shac.check(upper, formatter = True))",
+	}, "\n"))
+	// The content on disk is ignored, the content of the buffer is used.
+	writeFile(t, root, "a.txt", "unrelated\n")
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(root, "a.txt"))}).String()
+
+	c := startServer(t)
+	var res initializeResult
+	c.call(t, "initialize", &initializeParams{RootURI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(root)}).String()}, &res)
+	if !res.Capabilities.DocumentFormattingProvider || res.ServerInfo.Name != "shac" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	c.notify(t, "initialized", struct{}{})
+
+	c.notify(t, "textDocument/didOpen", &didOpenParams{TextDocument: textDocumentItem{URI: uri, Text: "ok\nnot bad\n"}})
+	want := publishDiagnosticsParams{
+		URI: uri,
+		Diagnostics: []diagnostic{
+			{
+				Range:    lspRange{Start: position{Line: 1, Character: 4}, End: position{Line: 1, Character: 7}},
+				Severity: severityError,
+				Source:   "shac",
+				Code:     "bad",
+				Message:  "no bad",
+			},
+			{
+				Severity: severityError,
+				Source:   "shac",
+				Code:     "upper",
+				Message:  "not upper",
+			},
+		},
+	}
+	got := c.diagnostics(t)
+	// Checks run concurrently.
+	if len(got.Diagnostics) == 2 && got.Diagnostics[0].Code == "upper" {
+		got.Diagnostics[0], got.Diagnostics[1] = got.Diagnostics[1], got.Diagnostics[0]
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	var actions []codeAction
+	c.call(t, "textDocument/codeAction", &codeActionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Range:        lspRange{Start: position{Line: 1, Character: 5}, End: position{Line: 1, Character: 5}},
+	}, &actions)
+	wantActions := []codeAction{
+		{
+			Title:       "Apply fix: no bad",
+			Kind:        "quickfix",
+			Diagnostics: want.Diagnostics[:1],
+			Edit: workspaceEdit{Changes: map[string][]textEdit{
+				uri: {{Range: want.Diagnostics[0].Range, NewText: "good"}},
+			}},
+		},
+		{
+			Title:       "Apply fix: not upper",
+			Kind:        "quickfix",
+			Diagnostics: want.Diagnostics[1:],
+			Edit: workspaceEdit{Changes: map[string][]textEdit{
+				uri: {{Range: lspRange{End: position{Line: 2}}, NewText: "OK\nNOT BAD\n"}},
+			}},
+		},
+	}
+	if len(actions) == 2 && actions[0].Title != wantActions[0].Title {
+		actions[0], actions[1] = actions[1], actions[0]
+	}
+	if diff := cmp.Diff(wantActions, actions); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Only formatters are used for formatting.
+	var edits []textEdit
+	c.call(t, "textDocument/formatting", &documentFormattingParams{TextDocument: textDocumentIdentifier{URI: uri}}, &edits)
+	wantEdits := []textEdit{{Range: lspRange{End: position{Line: 2}}, NewText: "OK\nNOT BAD\n"}}
+	if diff := cmp.Diff(wantEdits, edits); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// Modifications are debounced, only the last one is checked.
+	start := time.Now()
+	for _, text := range []string{"bad\n", "OK\n"} {
+		c.notify(t, "textDocument/didChange", &didChangeParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			ContentChanges: []struct {
+				Text string `json:"text"`
+			}{{Text: text}},
+		})
+	}
+	want = publishDiagnosticsParams{URI: uri, Diagnostics: []diagnostic{}}
+	if diff := cmp.Diff(want, c.diagnostics(t)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if d := time.Since(start); d < changeDebounce {
+		t.Fatalf("the checks ran after %s, before the debounce", d)
+	}
+
+	// An empty buffer is used too, instead of the content on disk.
+	c.notify(t, "textDocument/didChange", &didChangeParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		ContentChanges: []struct {
+			Text string `json:"text"`
+		}{{Text: ""}},
+	})
+	if diff := cmp.Diff(want, c.diagnostics(t)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	var m message
+	c.call(t, "unknown", struct{}{}, &m)
+	c.call(t, "shutdown", nil, nil)
+	c.notify(t, "exit", nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOffsetToPosition(t *testing.T) {
+	t.Parallel()
+	text := "a\nhé😀x\n"
+	data := []struct {
+		offset int
+		want   position
+	}{
+		{0, position{}},
+		{1, position{Character: 1}},
+		{2, position{Line: 1}},
+		{5, position{Line: 1, Character: 2}},
+		{9, position{Line: 1, Character: 4}},
+		{10, position{Line: 1, Character: 5}},
+		{11, position{Line: 2}},
+	}
+	for _, d := range data {
+		if got := offsetToPosition(text, d.offset); got != d.want {
+			t.Errorf("offsetToPosition(%d) = %+v, want %+v", d.offset, got, d.want)
+		}
+	}
+}
+
+// client is a test LSP client.
+type client struct {
+	c    *conn
+	id   int
+	done chan error
+	// notifications are the notifications sent by the server, except logs.
+	notifications chan *message
+	responses     chan *message
+}
+
+func startServer(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{
+		c:             newConn(outR, inW),
+		done:          make(chan error, 1),
+		notifications: make(chan *message, 10),
+		responses:     make(chan *message, 10),
+	}
+	go func() {
+		c.done <- Serve(context.Background(), &engine.Options{}, inR, outW)
+		outW.Close()
+	}()
+	go func() {
+		for {
+			m, err := c.c.read()
+			if err != nil {
+				close(c.notifications)
+				close(c.responses)
+				return
+			}
+			if m.Method == "window/logMessage" {
+				t.Logf("log: %s", m.Params)
+			} else if m.Method != "" {
+				c.notifications <- m
+			} else {
+				c.responses <- m
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+	})
+	return c
+}
+
+func (c *client) notify(t *testing.T, method string, params any) {
+	t.Helper()
+	if err := c.c.notify(method, params); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// call sends a request and decodes the result into out. If out is a
+// *message, the response error is expected.
+func (c *client) call(t *testing.T, method string, params any, out any) {
+	t.Helper()
+	c.id++
+	id := json.RawMessage(strings.Repeat("1", c.id))
+	b, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.c.write(&message{ID: &id, Method: method, Params: b}); err != nil {
+		t.Fatal(err)
+	}
+	m := <-c.responses
+	if m == nil {
+		t.Fatal("connection closed")
+	}
+	if string(*m.ID) != string(id) {
+		t.Fatalf("unexpected id %s", *m.ID)
+	}
+	if _, ok := out.(*message); ok {
+		if m.Error == nil || m.Error.Code != codeMethodNotFound {
+			t.Fatalf("expected error, got %+v", m)
+		}
+		return
+	}
+	if m.Error != nil {
+		t.Fatal(m.Error.Message)
+	}
+	if out != nil {
+		// Result was decoded as a generic value, round trip it.
+		b, err = json.Marshal(m.Result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(b, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (c *client) diagnostics(t *testing.T) publishDiagnosticsParams {
+	t.Helper()
+	m := <-c.notifications
+	if m == nil {
+		t.Fatal("connection closed")
+	}
+	if m.Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("unexpected notification %s", m.Method)
+	}
+	p := publishDiagnosticsParams{}
+	if err := json.Unmarshal(m.Params, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func writeFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, path), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -16,6 +16,7 @@ package reporting
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"go.fuchsia.dev/shac-project/shac/internal/engine"
@@ -26,9 +27,14 @@ import (
 // tees output to all of them.
 type MultiReport struct {
 	Reporters []Report
+
+	// suppressed is the number of findings suppressed by shac:ignore
+	// directives.
+	suppressed atomic.Int64
 }
 
 var _ Report = (*MultiReport)(nil)
+var _ engine.SuppressionReport = (*MultiReport)(nil)
 
 func (t *MultiReport) EmitFinding(ctx context.Context, check string, level engine.Level, message, root, file string, s engine.Span, replacements []string, props map[string]string) error {
 	return t.do(func(r Report) error {
@@ -42,6 +48,24 @@ func (t *MultiReport) EmitCommitMessageFinding(ctx context.Context, check string
 	})
 }
 
+// EmitSuppressedFinding counts the suppressed finding and forwards it to the
+// reporters implementing engine.SuppressionReport.
+func (t *MultiReport) EmitSuppressedFinding(ctx context.Context, check string, level engine.Level, message, root, file string, s engine.Span, justification string) error {
+	t.suppressed.Add(1)
+	return t.do(func(r Report) error {
+		if sr, ok := r.(engine.SuppressionReport); ok {
+			return sr.EmitSuppressedFinding(ctx, check, level, message, root, file, s, justification)
+		}
+		return nil
+	})
+}
+
+// Suppressed returns the number of findings suppressed by shac:ignore
+// directives so far.
+func (t *MultiReport) Suppressed() int {
+	return int(t.suppressed.Load())
+}
+
 func (t *MultiReport) EmitArtifact(ctx context.Context, check, root, file string, content []byte) error {
 	return t.do(func(r Report) error {
 		return r.EmitArtifact(ctx, check, root, file, content)
@@ -45,7 +45,10 @@ type Report interface {
 
 // Get returns the right reporting implementation based on the current
 // environment.
-func Get(ctx context.Context) (*MultiReport, error) {
+//
+// The findings below minLevel are not printed. They are still sent to ResultDB
+// and to the reporters added later, e.g. SarifReport.
+func Get(ctx context.Context, minLevel engine.Level) (*MultiReport, error) {
 	r := &MultiReport{}
 
 	// On LUCI/Swarming. ResultDB!
@@ -78,10 +81,34 @@ func Get(ctx context.Context) (*MultiReport, error) {
 		// Anything else, e.g. redirected output.
 		r.Reporters = append(r.Reporters, &synchronized{r: &basic{out: os.Stdout}})
 	}
+	if minLevel != engine.Nothing && minLevel != engine.Notice {
+		last := len(r.Reporters) - 1
+		r.Reporters[last] = &levelFilter{Report: r.Reporters[last], minLevel: minLevel}
+	}
 
 	return r, nil
 }
 
+// levelFilter wraps a Report object and drops the findings below a level.
+type levelFilter struct {
+	Report
+	minLevel engine.Level
+}
+
+func (l *levelFilter) EmitFinding(ctx context.Context, check string, level engine.Level, message, root, file string, s engine.Span, replacements []string, props map[string]string) error {
+	if !level.AtLeast(l.minLevel) {
+		return nil
+	}
+	return l.Report.EmitFinding(ctx, check, level, message, root, file, s, replacements, props)
+}
+
+func (l *levelFilter) EmitCommitMessageFinding(ctx context.Context, check string, level engine.Level, message string, commitHash string, commitMessage string, s engine.Span, props map[string]string) error {
+	if !level.AtLeast(l.minLevel) {
+		return nil
+	}
+	return l.Report.EmitCommitMessageFinding(ctx, check, level, message, commitHash, commitMessage, s, props)
+}
+
 // synchronized wraps a Report object and adds synchronization of calls to
 // ensure that checks cannot emit potentially multi-line data simultaneously.
 // For example, we don't want two checks to simultaneously emit multi-line
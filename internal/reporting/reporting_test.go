@@ -19,7 +19,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,7 +35,7 @@ import (
 )
 
 func TestGet(t *testing.T) {
-	r, err := Get(context.Background())
+	r, err := Get(context.Background(), engine.Notice)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -89,6 +91,29 @@ func TestBasic(t *testing.T) {
 	}
 }
 
+func TestLevelFilter(t *testing.T) {
+	buf := bytes.Buffer{}
+	r := levelFilter{Report: &basic{out: &buf}, minLevel: engine.Warning}
+	ctx := context.Background()
+	for _, l := range []engine.Level{engine.Notice, engine.Warning, engine.Error} {
+		if err := r.EmitFinding(ctx, "mycheck", l, "message", "", "", engine.Span{}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.EmitCommitMessageFinding(ctx, "mycheck", l, "message", "0123456789", "", engine.Span{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.CheckCompleted(ctx, "mycheck", time.Now(), time.Millisecond, engine.Notice, nil)
+	want := "[mycheck/warning] message\n" +
+		"[mycheck/warning] Commit 01234567: message\n" +
+		"[mycheck/error] message\n" +
+		"[mycheck/error] Commit 01234567: message\n" +
+		"- mycheck (success in 1ms)\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestGitHub(t *testing.T) {
 	buf := bytes.Buffer{}
 	r := github{out: &buf}
@@ -455,6 +480,11 @@ func TestSARIF(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	start := time.Now()
+	r.CheckCompleted(ctx, "check1", start, time.Millisecond, engine.Error, nil)
+	r.CheckCompleted(ctx, "check3", start, time.Millisecond, engine.Nothing, errors.New("bad"))
+	r.CheckCompleted(ctx, "check4", start, time.Second, engine.Nothing, fmt.Errorf("check \"check4\" %w after 1s", engine.ErrTimeout))
+
 	if err := r.Close(); err != nil {
 		t.Fatal(err)
 	}
@@ -582,6 +612,32 @@ func TestSARIF(t *testing.T) {
 					},
 				},
 			},
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "check3"}},
+				Invocations: []*sarif.Invocation{
+					{
+						ExecutionSuccessful: structpb.NewBoolValue(false),
+						ToolExecutionNotifications: []*sarif.Notification{
+							{Level: sarif.Error, Message: &sarif.Message{Text: "bad"}},
+						},
+					},
+				},
+			},
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "check4"}},
+				Invocations: []*sarif.Invocation{
+					{
+						ExecutionSuccessful: structpb.NewBoolValue(false),
+						ToolExecutionNotifications: []*sarif.Notification{
+							{
+								Level:       sarif.Error,
+								Message:     &sarif.Message{Text: "check \"check4\" timed out after 1s"},
+								Descriptor_: &sarif.ReportingDescriptorReference{Id: "timeout"},
+							},
+						},
+					},
+				},
+			},
 		},
 	}
 
@@ -590,6 +646,82 @@ func TestSARIF(t *testing.T) {
 	}
 }
 
+func TestMergeSarif(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	// Each shard runs a different check, and both run "shared".
+	shards := [][]string{{"check1", "shared"}, {"check2", "shared"}}
+	var in []io.Reader
+	for i, checks := range shards {
+		var buf bytes.Buffer
+		r := SarifReport{Out: &buf}
+		for _, c := range checks {
+			if err := r.EmitFinding(ctx, c, engine.Error, "issue", root, "foo.c", engine.Span{}, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.CheckCompleted(ctx, "shared", time.Now(), 0, engine.Error, fmt.Errorf("failed on shard %d", i))
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		in = append(in, &buf)
+	}
+
+	var out bytes.Buffer
+	if err := MergeSarif(&out, in...); err != nil {
+		t.Fatal(err)
+	}
+	got := &sarif.Document{}
+	if err := protojson.Unmarshal(out.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	result := func() []*sarif.Result {
+		return []*sarif.Result{
+			{
+				Level:   sarif.Error,
+				Message: &sarif.Message{Text: "issue"},
+				Locations: []*sarif.Location{
+					{
+						PhysicalLocation: &sarif.PhysicalLocation{
+							ArtifactLocation: &sarif.ArtifactLocation{Uri: "foo.c"},
+							Region:           &sarif.Region{},
+						},
+					},
+				},
+			},
+		}
+	}
+	invocation := func(i int) *sarif.Invocation {
+		return &sarif.Invocation{
+			ExecutionSuccessful: structpb.NewBoolValue(false),
+			ToolExecutionNotifications: []*sarif.Notification{
+				{Level: sarif.Error, Message: &sarif.Message{Text: fmt.Sprintf("failed on shard %d", i)}},
+			},
+		}
+	}
+	want := &sarif.Document{
+		Version: sarif.Version,
+		Runs: []*sarif.Run{
+			{Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "check1"}}, Results: result()},
+			{Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "check2"}}, Results: result()},
+			{
+				Tool:        &sarif.Tool{Driver: &sarif.ToolComponent{Name: "shared"}},
+				Results:     result(),
+				Invocations: []*sarif.Invocation{invocation(0), invocation(1)},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := MergeSarif(&out, strings.NewReader(`{"version": "1.0.0"}`)); err == nil || err.Error() != `unsupported SARIF version "1.0.0"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func init() {
 	// Mutate the running environment to make the test deterministic.
 	os.Unsetenv("LUCI_CONTEXT")
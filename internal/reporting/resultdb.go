@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"io"
@@ -217,7 +218,12 @@ func (l *luci) CheckCompleted(ctx context.Context, check string, start time.Time
 	r := l.getTestResult(check)
 	r.StartTime = timestamppb.New(start)
 	r.Duration = durationpb.New(d)
-	if err != nil {
+	if errors.Is(err, engine.ErrTimeout) {
+		// Distinguish a hung check from a crashing one.
+		r.Status = resultpb.TestStatus_ABORT
+		msg := maybeTruncateMessage(err.Error(), "", resultDBMaxFailureReasonLength)
+		r.FailureReason = &resultpb.FailureReason{PrimaryErrorMessage: msg}
+	} else if err != nil {
 		r.Status = resultpb.TestStatus_CRASH
 		msg := maybeTruncateMessage(err.Error(), "", resultDBMaxFailureReasonLength)
 		r.FailureReason = &resultpb.FailureReason{PrimaryErrorMessage: msg}
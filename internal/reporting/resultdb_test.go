@@ -111,6 +111,8 @@ func TestResultDBReporter(t *testing.T) {
 		4*time.Second,
 		engine.Nothing,
 		errors.New(strings.Repeat("a", resultDBMaxFailureReasonLength+1)))
+	r.CheckCompleted(
+		ctx, "hung-check", startTime.Add(25*time.Second), time.Minute, engine.Nothing, fmt.Errorf("check \"hung-check\" %w after 1m0s", engine.ErrTimeout))
 
 	if err := r.Close(); err != nil {
 		t.Fatal(err)
@@ -163,6 +165,13 @@ func TestResultDBReporter(t *testing.T) {
 						PrimaryErrorMessage: strings.Repeat("a", resultDBMaxFailureReasonLength-15) + "... (truncated)",
 					},
 				},
+				{
+					TestId:        "shac/hung-check",
+					Status:        resultpb.TestStatus_ABORT,
+					StartTime:     timestamppb.New(startTime.Add(25 * time.Second)),
+					Duration:      durationpb.New(time.Minute),
+					FailureReason: &resultpb.FailureReason{PrimaryErrorMessage: "check \"hung-check\" timed out after 1m0s"},
+				},
 			},
 		},
 	}
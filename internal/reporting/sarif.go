@@ -16,10 +16,14 @@ package reporting
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -29,6 +33,7 @@ import (
 	"go.fuchsia.dev/shac-project/shac/internal/engine"
 	"go.fuchsia.dev/shac-project/shac/internal/sarif"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -39,6 +44,8 @@ type SarifReport struct {
 
 	mu             sync.Mutex
 	resultsByCheck map[string][]*sarif.Result
+	// failedChecks are the invocations of the checks that returned an error.
+	failedChecks map[string]*sarif.Invocation
 }
 
 var levelMap = map[engine.Level]string{
@@ -48,6 +55,27 @@ var levelMap = map[engine.Level]string{
 }
 
 func (sr *SarifReport) EmitFinding(ctx context.Context, check string, level engine.Level, message, root, file string, s engine.Span, replacements []string, props map[string]string) error {
+	result, err := sr.newResult(level, message, root, file, s, replacements, props)
+	if err != nil {
+		return err
+	}
+	sr.addResult(check, result)
+	return nil
+}
+
+// EmitSuppressedFinding implements engine.SuppressionReport. The finding is
+// included in the output so consumers can audit the suppressions.
+func (sr *SarifReport) EmitSuppressedFinding(ctx context.Context, check string, level engine.Level, message, root, file string, s engine.Span, justification string) error {
+	result, err := sr.newResult(level, message, root, file, s, nil, nil)
+	if err != nil {
+		return err
+	}
+	result.Suppressions = []*sarif.Suppression{{Kind: sarif.InSource, Justification: justification}}
+	sr.addResult(check, result)
+	return nil
+}
+
+func (sr *SarifReport) newResult(level engine.Level, message, root, file string, s engine.Span, replacements []string, props map[string]string) (*sarif.Result, error) {
 	region := &sarif.Region{
 		StartLine:   int32(s.Start.Line), // #nosec G115
 		EndLine:     int32(s.End.Line),   // #nosec G115
@@ -87,11 +115,11 @@ func (sr *SarifReport) EmitFinding(ctx context.Context, check string, level engi
 		var err error
 		propsProto, err = structpb.NewStruct(p)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	result := &sarif.Result{
+	return &sarif.Result{
 		// TODO(olivernewman): Set RuleId field. The SARIF specification states
 		// that ruleId "SHALL" be set, and "Not all existing analysis tools emit
 		// the equivalent of a ruleId in their output. A SARIF converter which
@@ -111,16 +139,16 @@ func (sr *SarifReport) EmitFinding(ctx context.Context, check string, level engi
 		},
 		Fixes:      fixes,
 		Properties: propsProto,
-	}
+	}, nil
+}
 
+func (sr *SarifReport) addResult(check string, result *sarif.Result) {
 	sr.mu.Lock()
 	if sr.resultsByCheck == nil {
 		sr.resultsByCheck = make(map[string][]*sarif.Result)
 	}
 	sr.resultsByCheck[check] = append(sr.resultsByCheck[check], result)
 	sr.mu.Unlock()
-
-	return nil
 }
 
 func (sr *SarifReport) EmitCommitMessageFinding(ctx context.Context, check string, level engine.Level, message string, commitHash string, commitMessage string, s engine.Span, props map[string]string) error {
@@ -198,7 +226,29 @@ func (sr *SarifReport) EmitArtifact(ctx context.Context, root, check, file strin
 	return nil
 }
 
+// timeoutNotificationID identifies the notification of a check that timed out.
+const timeoutNotificationID = "timeout"
+
 func (sr *SarifReport) CheckCompleted(ctx context.Context, check string, start time.Time, d time.Duration, level engine.Level, err error) {
+	if err == nil {
+		return
+	}
+	n := &sarif.Notification{
+		Level:   sarif.Error,
+		Message: &sarif.Message{Text: err.Error()},
+	}
+	if errors.Is(err, engine.ErrTimeout) {
+		n.Descriptor_ = &sarif.ReportingDescriptorReference{Id: timeoutNotificationID}
+	}
+	sr.mu.Lock()
+	if sr.failedChecks == nil {
+		sr.failedChecks = make(map[string]*sarif.Invocation)
+	}
+	sr.failedChecks[check] = &sarif.Invocation{
+		ExecutionSuccessful:        structpb.NewBoolValue(false),
+		ToolExecutionNotifications: []*sarif.Notification{n},
+	}
+	sr.mu.Unlock()
 }
 
 func (sr *SarifReport) Print(context.Context, string, string, int, string) {}
@@ -210,18 +260,30 @@ func (sr *SarifReport) Close() error {
 	for check := range sr.resultsByCheck {
 		sortedChecks = append(sortedChecks, check)
 	}
+	for check := range sr.failedChecks {
+		if _, ok := sr.resultsByCheck[check]; !ok {
+			sortedChecks = append(sortedChecks, check)
+		}
+	}
 	sort.Strings(sortedChecks)
 
 	for _, check := range sortedChecks {
 		results := sr.resultsByCheck[check]
-		doc.Runs = append(doc.Runs, &sarif.Run{
+		run := &sarif.Run{
 			Tool: &sarif.Tool{
 				Driver: &sarif.ToolComponent{Name: check},
 			},
 			Results: results,
-		})
+		}
+		if inv := sr.failedChecks[check]; inv != nil {
+			run.Invocations = []*sarif.Invocation{inv}
+		}
+		doc.Runs = append(doc.Runs, run)
 	}
+	return writeSarif(sr.Out, doc)
+}
 
+func writeSarif(w io.Writer, doc *sarif.Document) error {
 	b, err := protojson.MarshalOptions{
 		Multiline:     true,
 		UseProtoNames: false,
@@ -229,10 +291,58 @@ func (sr *SarifReport) Close() error {
 	if err != nil {
 		return err
 	}
-	_, err = sr.Out.Write(b)
+	_, err = w.Write(b)
 	return err
 }
 
+// MergeSarif merges SARIF documents written by SarifReport, e.g. by the
+// shards of a run, into a single document with one run per check.
+//
+// Identical results of a check are only kept once, since a check may run on
+// multiple shards.
+func MergeSarif(w io.Writer, in ...io.Reader) error {
+	runs := map[string]*sarif.Run{}
+	seen := map[string]map[string]bool{}
+	for _, r := range in {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		doc := &sarif.Document{}
+		if err = protojson.Unmarshal(b, doc); err != nil {
+			return err
+		}
+		if doc.Version != sarif.Version {
+			return fmt.Errorf("unsupported SARIF version %q", doc.Version)
+		}
+		for _, run := range doc.Runs {
+			name := run.GetTool().GetDriver().GetName()
+			merged := runs[name]
+			if merged == nil {
+				merged = &sarif.Run{Tool: run.Tool}
+				runs[name] = merged
+				seen[name] = map[string]bool{}
+			}
+			for _, res := range run.Results {
+				k, err := proto.MarshalOptions{Deterministic: true}.Marshal(res)
+				if err != nil {
+					return err
+				}
+				if !seen[name][string(k)] {
+					seen[name][string(k)] = true
+					merged.Results = append(merged.Results, res)
+				}
+			}
+			merged.Invocations = append(merged.Invocations, run.Invocations...)
+		}
+	}
+	doc := &sarif.Document{Version: sarif.Version}
+	for _, name := range slices.Sorted(maps.Keys(runs)) {
+		doc.Runs = append(doc.Runs, runs[name])
+	}
+	return writeSarif(w, doc)
+}
+
 // replacementsForDiff takes a diff between oldLines and newLines and converts
 // it to corresponding SARIF replacement objects.
 func replacementsForDiff(oldLines, newLines []string) []*sarif.Replacement {
@@ -15,12 +15,16 @@
 package reporting
 
 import (
+	"bytes"
+	"context"
 	"slices"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"go.fuchsia.dev/shac-project/shac/internal/engine"
 	"go.fuchsia.dev/shac-project/shac/internal/sarif"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/testing/protocmp"
 )
 
@@ -108,3 +112,58 @@ func TestReplacementsForDiff(t *testing.T) {
 		t.Errorf("Wrong parsed diff (-want +got):\n%s", d)
 	}
 }
+
+func TestSarifReport_Suppressed(t *testing.T) {
+	var buf bytes.Buffer
+	r := &MultiReport{Reporters: []Report{&SarifReport{Out: &buf}}}
+	ctx := context.Background()
+	span := engine.Span{Start: engine.Cursor{Line: 2}}
+	if err := r.EmitFinding(ctx, "lint", engine.Error, "bad", "", "a.txt", span, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EmitSuppressedFinding(ctx, "lint", engine.Warning, "meh", "", "a.txt", span, "known issue"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Suppressed(); got != 1 {
+		t.Errorf("expected 1 suppressed finding, got %d", got)
+	}
+	got := &sarif.Document{}
+	if err := protojson.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	location := []*sarif.Location{
+		{
+			PhysicalLocation: &sarif.PhysicalLocation{
+				ArtifactLocation: &sarif.ArtifactLocation{Uri: "a.txt"},
+				Region:           &sarif.Region{StartLine: 2},
+			},
+		},
+	}
+	want := &sarif.Document{
+		Version: sarif.Version,
+		Runs: []*sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "lint"}},
+				Results: []*sarif.Result{
+					{
+						Level:     sarif.Error,
+						Message:   &sarif.Message{Text: "bad"},
+						Locations: location,
+					},
+					{
+						Level:        sarif.Warning,
+						Message:      &sarif.Message{Text: "meh"},
+						Locations:    location,
+						Suppressions: []*sarif.Suppression{{Kind: sarif.InSource, Justification: "known issue"}},
+					},
+				},
+			},
+		},
+	}
+	if d := cmp.Diff(want, got, protocmp.Transform()); d != "" {
+		t.Errorf("mismatch (-want +got):\n%s", d)
+	}
+}
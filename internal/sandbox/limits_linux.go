@@ -0,0 +1,251 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Exceeded returns a description of the limit that was exceeded by a
+// subprocess, according to its status as returned by Sandbox.Exit, or an empty
+// string if it can't be determined.
+//
+// The kernel sends SIGXCPU at the CPU time limit. The memory and process
+// limits are reported by the events of the cgroup of the subprocess, only on
+// cgroup v2. The kernel kills the process with SIGKILL at the hard CPU time
+// limit, and the open files limit makes system calls fail, which can't be told
+// apart from other causes.
+func (l *Limits) Exceeded(st ExitStatus) string {
+	switch {
+	case st.OOMKilled && l.MemoryMB != 0:
+		return fmt.Sprintf("the memory limit of %d MiB", l.MemoryMB)
+	case st.Signal == syscall.SIGXCPU && l.CPUSeconds != 0:
+		return fmt.Sprintf("the CPU time limit of %ds", l.CPUSeconds)
+	case st.ProcessesExceeded && l.Processes != 0:
+		return fmt.Sprintf("the limit of %d processes", l.Processes)
+	}
+	return ""
+}
+
+// Describe returns a description of the limits that are set, or an empty
+// string if none is.
+func (l *Limits) Describe() string {
+	var out []string
+	if l.MemoryMB != 0 {
+		out = append(out, fmt.Sprintf("memory of %d MiB", l.MemoryMB))
+	}
+	if l.CPUSeconds != 0 {
+		out = append(out, fmt.Sprintf("CPU time of %ds per process", l.CPUSeconds))
+	}
+	if l.OpenFiles != 0 {
+		out = append(out, fmt.Sprintf("%d open files per process", l.OpenFiles))
+	}
+	if l.Processes != 0 {
+		out = append(out, fmt.Sprintf("%d processes", l.Processes))
+	}
+	return strings.Join(out, ", ")
+}
+
+// cgroupArgs returns the nsjail arguments to enforce the limits that require
+// a cgroup on cgroup v1, or nil if there is none.
+func cgroupArgs(l *Limits) []string {
+	var args []string
+	if l.MemoryMB != 0 {
+		args = append(args, "--cgroup_mem_max", strconv.FormatUint(l.MemoryMB*1024*1024, 10))
+	}
+	if l.Processes != 0 {
+		args = append(args, "--cgroup_pids_max", strconv.FormatUint(l.Processes, 10))
+	}
+	return args
+}
+
+// cgroupV2Dir returns the directory of the cgroup of the current process when
+// the system uses cgroup v2, or an empty string.
+func cgroupV2Dir() string {
+	const root = "/sys/fs/cgroup"
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return ""
+	}
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	for l := range strings.Lines(string(b)) {
+		if p, ok := strings.CutPrefix(strings.TrimSpace(l), "0::"); ok {
+			return filepath.Join(root, p)
+		}
+	}
+	return ""
+}
+
+// jailCgroupParent returns the cgroup v2 owned by shac under which the cgroups
+// of the jails are created, or an empty string if the system doesn't use
+// cgroup v2.
+//
+// It is the cgroup of shac. Unlike the root, it is writable by an unprivileged
+// user when it was delegated, e.g. by systemd. A cgroup that has processes
+// can't enable controllers for its children, so shac moves itself to a child
+// cgroup first if needed, like nsjail does.
+var jailCgroupParent = sync.OnceValues(func() (string, error) {
+	d := cgroupV2Dir()
+	if d == "" {
+		return "", nil
+	}
+	err := enableCgroupControllers(d)
+	if errors.Is(err, syscall.EBUSY) {
+		self := filepath.Join(d, "shac")
+		if err = os.Mkdir(self, 0o755); err == nil || errors.Is(err, fs.ErrExist) {
+			if err = writeCgroupFile(self, "cgroup.procs", strconv.Itoa(os.Getpid())); err == nil {
+				err = enableCgroupControllers(d)
+			}
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to enable the memory and pids controllers of %s: %w", d, err)
+	}
+	return d, nil
+})
+
+// enableCgroupControllers enables the controllers enforcing the limits in the
+// children of the cgroup d.
+func enableCgroupControllers(d string) error {
+	return writeCgroupFile(d, "cgroup.subtree_control", "+memory +pids")
+}
+
+// jailCgroups are the cgroups of the commands that were started, until
+// Sandbox.Exit is called.
+var jailCgroups sync.Map // map[*exec.Cmd]*jailCgroup
+
+// jailCgroup is a cgroup v2 enforcing the memory and process limits, in which
+// nsjail runs along with the jailed processes.
+//
+// nsjail can create the cgroup itself, but it removes it when it exits, while
+// its events tell whether a limit was reached. The events are counted in the
+// cgroup of the processes and not propagated to its parent on older kernels.
+type jailCgroup struct {
+	dir string
+	f   *os.File
+}
+
+// newJailCgroup creates the cgroup enforcing the limits l, or returns nil if
+// none of them requires a cgroup or the system doesn't use cgroup v2.
+func newJailCgroup(l *Limits) (*jailCgroup, error) {
+	if l.MemoryMB == 0 && l.Processes == 0 {
+		return nil, nil
+	}
+	parent, err := jailCgroupParent()
+	if parent == "" || err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(parent, "shac-exec")
+	if err != nil {
+		return nil, err
+	}
+	c := &jailCgroup{dir: dir}
+	if l.MemoryMB != 0 {
+		err = writeCgroupFile(dir, "memory.max", strconv.FormatUint(l.MemoryMB*1024*1024, 10))
+	}
+	if l.Processes != 0 && err == nil {
+		// nsjail itself is in the cgroup.
+		err = writeCgroupFile(dir, "pids.max", strconv.FormatUint(l.Processes+1, 10))
+	}
+	if err == nil {
+		c.f, err = os.Open(dir)
+	}
+	if err != nil {
+		_ = os.Remove(dir)
+		return nil, err
+	}
+	return c, nil
+}
+
+// attach makes cmd start in the cgroup.
+func (c *jailCgroup) attach(cmd *exec.Cmd) {
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.f.Fd())
+	jailCgroups.Store(cmd, c)
+}
+
+// events returns whether a process was killed for exceeding the memory limit
+// and whether the creation of a process failed because of the process limit.
+func (c *jailCgroup) events() (bool, bool) {
+	return cgroupEvent(filepath.Join(c.dir, "memory.events"), "oom_kill") != 0,
+		cgroupEvent(filepath.Join(c.dir, "pids.events"), "max") != 0
+}
+
+// remove kills the processes left in the cgroup and removes it.
+func (c *jailCgroup) remove() {
+	_ = c.f.Close()
+	_ = writeCgroupFile(c.dir, "cgroup.kill", "1")
+	// The killed processes leave the cgroup asynchronously.
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if err := os.Remove(c.dir); !errors.Is(err, syscall.EBUSY) || time.Since(start) > time.Second {
+			return
+		}
+	}
+}
+
+// releaseJailCgroup removes the cgroup of cmd, if any, and returns its
+// events.
+func releaseJailCgroup(cmd *exec.Cmd) (bool, bool) {
+	v, ok := jailCgroups.LoadAndDelete(cmd)
+	if !ok {
+		return false, false
+	}
+	c := v.(*jailCgroup)
+	oomKilled, processesExceeded := c.events()
+	c.remove()
+	return oomKilled, processesExceeded
+}
+
+// cgroupEvent returns the counter of an event in a cgroup events file, e.g.
+// memory.events, or 0 if it can't be read.
+func cgroupEvent(path, name string) uint64 {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	for l := range strings.Lines(string(b)) {
+		if k, v, ok := strings.Cut(strings.TrimSpace(l), " "); ok && k == name {
+			n, _ := strconv.ParseUint(v, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// writeCgroupFile writes to an interface file of the cgroup d. The files
+// always exist, they are not created.
+func writeCgroupFile(d, name, v string) error {
+	f, err := os.OpenFile(filepath.Join(d, name), os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(v)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNsjailLimits(t *testing.T) {
+	t.Parallel()
+	s := nsjailSandbox{nsjailPath: "nsjail", tempDir: t.TempDir()}
+	cmd := s.Command(context.Background(), &Config{Cmd: []string{"true"}, Cwd: "/"})
+	if !slices.Contains(cmd.Args, "--disable_rlimits") || !slices.Contains(cmd.Args, "--disable_clone_newcgroup") {
+		t.Fatalf("expected --disable_rlimits and --disable_clone_newcgroup: %s", cmd.Args)
+	}
+	cmd = s.Command(context.Background(), &Config{Cmd: []string{"true"}, Cwd: "/", Limits: Limits{MemoryMB: 512, Processes: 8}})
+	i := slices.Index(cmd.Args, "--rlimit_as")
+	if i == -1 {
+		t.Fatalf("expected --rlimit_as: %s", cmd.Args)
+	}
+	want := []string{
+		"--rlimit_as", "soft",
+		"--rlimit_core", "soft",
+		"--rlimit_cpu", "soft",
+		"--rlimit_fsize", "soft",
+		"--rlimit_nofile", "soft",
+		"--rlimit_nproc", "soft",
+		"--rlimit_stack", "soft",
+	}
+	if diff := cmp.Diff(want, cmd.Args[i:i+len(want)]); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	// The memory and the processes are limited by a cgroup, in a cgroup
+	// namespace. On cgroup v2, nsjail is started in a cgroup created by shac.
+	if cgroupV2Dir() != "" {
+		t.Cleanup(func() { s.Exit(cmd) })
+		if cmd.Err == nil && !cmd.SysProcAttr.UseCgroupFD {
+			t.Fatal("expected a cgroup")
+		}
+		if slices.Contains(cmd.Args, "--cgroup_mem_max") {
+			t.Fatalf("unexpected --cgroup_mem_max: %s", cmd.Args)
+		}
+	} else {
+		i = slices.Index(cmd.Args, "--cgroup_mem_max")
+		if i == -1 {
+			t.Fatalf("expected --cgroup_mem_max: %s", cmd.Args)
+		}
+		want = []string{
+			"--cgroup_mem_max", "536870912",
+			"--cgroup_pids_max", "8",
+		}
+		if diff := cmp.Diff(want, cmd.Args[i:i+len(want)]); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if slices.Contains(cmd.Args, "--disable_rlimits") || slices.Contains(cmd.Args, "--disable_clone_newcgroup") {
+		t.Fatalf("unexpected --disable_rlimits or --disable_clone_newcgroup: %s", cmd.Args)
+	}
+	// Limits that don't require a cgroup.
+	cmd = s.Command(context.Background(), &Config{Cmd: []string{"true"}, Cwd: "/", Limits: Limits{CPUSeconds: 10}})
+	if !slices.Contains(cmd.Args, "--disable_clone_newcgroup") || slices.Contains(cmd.Args, "--cgroup_mem_max") {
+		t.Fatalf("unexpected cgroup arguments: %s", cmd.Args)
+	}
+}
+
+func TestLimitsExceeded(t *testing.T) {
+	t.Parallel()
+	all := Limits{MemoryMB: 512, CPUSeconds: 10, OpenFiles: 64, Processes: 8}
+	data := []struct {
+		name   string
+		limits Limits
+		script string
+		// events are the events of the cgroup.
+		oomKilled         bool
+		processesExceeded bool
+		want              string
+	}{
+		{"signal", all, "kill -XCPU $$", false, false, "the CPU time limit of 10s"},
+		// An exit code of 128+SIGXCPU is not evidence of a signal.
+		{"exit code", all, "exit 152", false, false, ""},
+		// Killed at the hard CPU time limit, or by the OOM killer without
+		// cgroup v2, which can't be told apart.
+		{"killed", all, "kill -KILL $$", false, false, ""},
+		{"oom", all, "kill -KILL $$", true, false, "the memory limit of 512 MiB"},
+		{"processes", all, "exit 1", false, true, "the limit of 8 processes"},
+		{"no cpu limit", Limits{MemoryMB: 1}, "kill -XCPU $$", false, false, ""},
+		{"no memory limit", Limits{CPUSeconds: 10}, "kill -KILL $$", true, false, ""},
+		{"failure", all, "exit 1", false, false, ""},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			err := exec.Command("sh", "-c", data[i].script).Run()
+			errExit, ok := errors.AsType[*exec.ExitError](err)
+			if !ok {
+				t.Fatalf("expected an exit error, got %v", err)
+			}
+			st := ExitStatus{OOMKilled: data[i].oomKilled, ProcessesExceeded: data[i].processesExceeded}
+			st.Signal, st.CoreDumped = exitSignal(errExit.ProcessState)
+			if got := data[i].limits.Exceeded(st); got != data[i].want {
+				t.Fatalf("got %q, want %q", got, data[i].want)
+			}
+		})
+	}
+}
+
+func TestLimitsDescribe(t *testing.T) {
+	t.Parallel()
+	l := Limits{MemoryMB: 512, CPUSeconds: 10, OpenFiles: 64, Processes: 8}
+	want := "memory of 512 MiB, CPU time of 10s per process, 64 open files per process, 8 processes"
+	if diff := cmp.Diff(want, l.Describe()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	l = Limits{}
+	if got := l.Describe(); got != "" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestJailCgroupEvents(t *testing.T) {
+	t.Parallel()
+	data := []struct {
+		name              string
+		memory            string
+		pids              string
+		oomKilled         bool
+		processesExceeded bool
+	}{
+		{"none", "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n", "max 0\n", false, false},
+		// The memory limit was reached, but the kernel reclaimed enough.
+		{"memory max", "low 0\nhigh 0\nmax 12\noom 0\noom_kill 0\n", "max 0\n", false, false},
+		{"oom", "low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\noom_group_kill 0\n", "max 0\n", true, false},
+		{"processes", "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n", "max 3\n", false, true},
+		// Without the pids controller.
+		{"missing", "oom_kill 2\n", "", true, false},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			c := jailCgroup{dir: t.TempDir()}
+			if err := os.WriteFile(filepath.Join(c.dir, "memory.events"), []byte(data[i].memory), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if data[i].pids != "" {
+				if err := os.WriteFile(filepath.Join(c.dir, "pids.events"), []byte(data[i].pids), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			oomKilled, processesExceeded := c.events()
+			if oomKilled != data[i].oomKilled || processesExceeded != data[i].processesExceeded {
+				t.Fatalf("got %t, %t, want %t, %t", oomKilled, processesExceeded, data[i].oomKilled, data[i].processesExceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package sandbox
+
+import "os/exec"
+
+// Exceeded returns a description of the limit that was exceeded by a
+// subprocess, or an empty string if it can't be determined.
+//
+// Limits are not enforced on this platform.
+func (l *Limits) Exceeded(st ExitStatus) string {
+	return ""
+}
+
+// Describe returns a description of the limits that are set, or an empty
+// string if none is.
+//
+// Limits are not enforced on this platform.
+func (l *Limits) Describe() string {
+	return ""
+}
+
+// cgroupArgs returns the nsjail arguments to enforce the limits that require
+// a cgroup on cgroup v1, or nil if there is none.
+func cgroupArgs(l *Limits) []string {
+	return nil
+}
+
+// jailCgroup is a cgroup enforcing the limits. cgroups only exist on Linux.
+type jailCgroup struct{}
+
+func newJailCgroup(l *Limits) (*jailCgroup, error) {
+	return nil, nil
+}
+
+func (c *jailCgroup) attach(cmd *exec.Cmd) {
+}
+
+func releaseJailCgroup(cmd *exec.Cmd) (bool, bool) {
+	return false, false
+}
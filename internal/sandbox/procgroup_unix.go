@@ -0,0 +1,37 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package sandbox
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killTreeOnCancel runs the command in its own process group so that the whole
+// process tree is killed when the command's context is canceled, not only the
+// direct child.
+func killTreeOnCancel(cmd *exec.Cmd) *exec.Cmd {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Don't wait forever for the output pipes to be closed if an orphaned
+	// process inherited them.
+	cmd.WaitDelay = time.Second
+	return cmd
+}
@@ -0,0 +1,31 @@
+// Copyright 2026 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package sandbox
+
+import (
+	"os/exec"
+	"time"
+)
+
+// killTreeOnCancel makes sure Wait() returns even if an orphaned process
+// inherited the output pipes once the command's context is canceled.
+//
+// Only the direct child is killed on Windows.
+func killTreeOnCancel(cmd *exec.Cmd) *exec.Cmd {
+	cmd.WaitDelay = time.Second
+	return cmd
+}
@@ -18,11 +18,14 @@ package sandbox
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -44,6 +47,24 @@ type Mount struct {
 	Writable bool
 }
 
+// Limits are resource limits applied to a sandboxed subprocess and its
+// children. A zero value means the limit is inherited from shac.
+//
+// They are only enforced by nsjail, i.e. on Linux amd64 and arm64. MemoryMB and
+// Processes are enforced with a cgroup, which must be writable. On cgroup v2,
+// the cgroup of shac must be delegated, e.g. by systemd.
+type Limits struct {
+	// MemoryMB is the maximum memory used by all the processes, in MiB.
+	MemoryMB uint64
+	// CPUSeconds is the maximum CPU time of each process, in seconds.
+	CPUSeconds uint64
+	// OpenFiles is the maximum number of open file descriptors of each
+	// process.
+	OpenFiles uint64
+	// Processes is the maximum number of processes running at once.
+	Processes uint64
+}
+
 // Config represents the configuration for a sandboxed subprocess.
 type Config struct {
 	// The subprocess command line.
@@ -52,13 +73,36 @@ type Config struct {
 	AllowNetwork bool
 	Env          map[string]string
 	Mounts       []Mount
+	Limits       Limits
 
 	// Require keyed arguments.
 	_ struct{}
 }
 
+// ExitStatus describes how a command returned by Sandbox.Command terminated.
+type ExitStatus struct {
+	// Signal is the signal that terminated the command, or 0 if it exited
+	// normally.
+	Signal syscall.Signal
+	// CoreDumped is whether the command dumped core.
+	CoreDumped bool
+	// OOMKilled is whether a process was killed for exceeding
+	// Limits.MemoryMB.
+	OOMKilled bool
+	// ProcessesExceeded is whether the creation of a process failed because of
+	// Limits.Processes.
+	ProcessesExceeded bool
+}
+
 type Sandbox interface {
 	Command(context.Context, *Config) *exec.Cmd
+	// Exit returns how a command returned by Command terminated.
+	//
+	// It must be called once the command completed, to release the resources
+	// associated with it.
+	Exit(*exec.Cmd) ExitStatus
+	// EnforcesLimits returns whether Config.Limits are enforced by Command.
+	EnforcesLimits() bool
 }
 
 // New constructs a platform-appropriate sandbox.
@@ -85,7 +129,7 @@ func New(tempDir string) (Sandbox, error) {
 		if err != nil {
 			return nil, err
 		}
-		return nsjailSandbox{nsjailPath: nsjailPath}, nil
+		return nsjailSandbox{nsjailPath: nsjailPath, tempDir: tempDir}, nil
 	} else if runtime.GOOS == "darwin" {
 		return macSandbox{}, nil
 	}
@@ -99,19 +143,61 @@ func New(tempDir string) (Sandbox, error) {
 // depending on a prebuilt nsjail executable.
 type nsjailSandbox struct {
 	nsjailPath string
+	// tempDir is where the nsjail logs are written.
+	tempDir string
 }
 
 func (s nsjailSandbox) Command(ctx context.Context, config *Config) *exec.Cmd {
+	// The log of nsjail tells whether the jailed process was killed by a
+	// signal, since nsjail exits with 128+N in that case, which can't be told
+	// apart from a process exiting with 128+N.
+	//
+	// The file is unlinked right away, it is only accessed through the file
+	// descriptor.
+	log, err := os.CreateTemp(s.tempDir, "nsjail*.log")
+	if err == nil && os.Remove(log.Name()) != nil {
+		_ = log.Close()
+		log = nil
+	}
 	args := []string{
-		"--quiet",
 		"--forward_signals",
-		// Limits on file read sizes are not useful.
-		"--disable_rlimits",
-		"--disable_clone_newcgroup",
-		// Time limits are not useful.
+		// Timeouts are enforced by the caller through the context.
 		"--time_limit", "0",
 		"--cwd", config.Cwd,
 	}
+	// The memory and process limits are enforced with a cgroup, which
+	// accounts for all the processes in the jail. On cgroup v2, nsjail is
+	// started in a cgroup created by shac, otherwise nsjail creates it.
+	cgroup, cgroupErr := newJailCgroup(&config.Limits)
+	if cgroup == nil && cgroupErr == nil {
+		if a := cgroupArgs(&config.Limits); len(a) != 0 {
+			args = append(args, a...)
+		} else {
+			args = append(args, "--disable_clone_newcgroup")
+		}
+	}
+	if config.Limits == (Limits{}) {
+		args = append(args, "--disable_rlimits")
+	} else {
+		// nsjail has restrictive defaults for the limits that are not
+		// specified, e.g. on file read sizes, which are not useful. Inherit the
+		// current ones instead.
+		rlimit := func(v uint64) string {
+			if v == 0 {
+				return "soft"
+			}
+			return strconv.FormatUint(v, 10)
+		}
+		args = append(args,
+			"--rlimit_as", "soft",
+			"--rlimit_core", "soft",
+			"--rlimit_cpu", rlimit(config.Limits.CPUSeconds),
+			"--rlimit_fsize", "soft",
+			"--rlimit_nofile", rlimit(config.Limits.OpenFiles),
+			"--rlimit_nproc", "soft",
+			"--rlimit_stack", "soft",
+		)
+	}
 	if config.AllowNetwork {
 		args = append(args, "--disable_clone_newnet")
 	}
@@ -151,9 +237,83 @@ func (s nsjailSandbox) Command(ctx context.Context, config *Config) *exec.Cmd {
 		}
 		args = append(args, flag, val)
 	}
+	if log != nil {
+		// The first extra file is file descriptor 3.
+		args = append(args, "--log_fd", "3")
+	} else {
+		args = append(args, "--quiet")
+	}
 	args = append(args, "--")
 	args = append(args, config.Cmd...)
-	return exec.CommandContext(ctx, s.nsjailPath, args...)
+	// nsjail kills the jailed processes when it dies.
+	cmd := killTreeOnCancel(exec.CommandContext(ctx, s.nsjailPath, args...))
+	if log != nil {
+		cmd.ExtraFiles = []*os.File{log}
+	}
+	if cgroupErr != nil {
+		// Start() returns it.
+		cmd.Err = fmt.Errorf("failed to create the cgroup enforcing the limits: %w", cgroupErr)
+	} else if cgroup != nil {
+		cgroup.attach(cmd)
+	}
+	return cmd
+}
+
+func (s nsjailSandbox) Exit(cmd *exec.Cmd) ExitStatus {
+	var st ExitStatus
+	st.OOMKilled, st.ProcessesExceeded = releaseJailCgroup(cmd)
+	if len(cmd.ExtraFiles) != 0 {
+		log := cmd.ExtraFiles[0]
+		// The file offset is shared with nsjail, read from the start.
+		b, _ := io.ReadAll(io.NewSectionReader(log, 0, maxNsjailLog))
+		_ = log.Close()
+		var w io.Writer = io.Discard
+		if cmd.Stderr != nil {
+			w = cmd.Stderr
+		}
+		st.Signal = parseNsjailLog(b, w)
+	}
+	if nsjailSig, coreDumped := exitSignal(cmd.ProcessState); nsjailSig != 0 {
+		// nsjail itself was killed.
+		st.Signal, st.CoreDumped = nsjailSig, coreDumped
+	}
+	// Whether the jailed process dumped core is not logged.
+	return st
+}
+
+func (s nsjailSandbox) EnforcesLimits() bool {
+	return true
+}
+
+// maxNsjailLog is the maximum size of the nsjail log that is read.
+const maxNsjailLog = 1024 * 1024
+
+// nsjailSignalRe matches the line logged by nsjail when the jailed process is
+// terminated by a signal.
+var nsjailSignalRe = regexp.MustCompile(`terminated with signal: \S+ \((\d+)\)`)
+
+// parseNsjailLog returns the signal that terminated the jailed process
+// according to the log of nsjail, or 0 if it exited normally.
+//
+// The fatal errors of nsjail are written to w, like with --quiet. The warnings
+// and non fatal errors are dropped so they don't end up in the output of the
+// subprocess.
+func parseNsjailLog(b []byte, w io.Writer) syscall.Signal {
+	var sig syscall.Signal
+	for l := range strings.Lines(string(b)) {
+		// Lines start with the level, e.g. "[I]" for info.
+		switch {
+		case strings.HasPrefix(l, "[F]"):
+			_, _ = io.WriteString(w, l)
+		case strings.HasPrefix(l, "[I]"):
+			if m := nsjailSignalRe.FindStringSubmatch(l); m != nil {
+				if n, err := strconv.Atoi(m[1]); err == nil {
+					sig = syscall.Signal(n)
+				}
+			}
+		}
+	}
+	return sig
 }
 
 // macSandbox provides a sandbox specific to macOS using the preinstalled
@@ -173,34 +333,69 @@ func (s macSandbox) Command(ctx context.Context, config *Config) *exec.Cmd {
 	}
 
 	args := append([]string{"-p", strings.Join(profile, "\n")}, config.Cmd...)
-	cmd := exec.CommandContext(ctx, "/usr/bin/sandbox-exec", args...)
+	cmd := killTreeOnCancel(exec.CommandContext(ctx, "/usr/bin/sandbox-exec", args...))
 	cmd.Dir = config.Cwd
 
 	for k, v := range config.Env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// config.Mounts intentionally ignored.
+	// config.Mounts intentionally ignored, config.Limits is not enforced, see
+	// EnforcesLimits.
 	// TODO(olivernewman): Also restrict filesystem access, note that it may not
 	// be possible to mount a file at a different path.
 
 	return cmd
 }
 
+func (s macSandbox) Exit(cmd *exec.Cmd) ExitStatus {
+	var st ExitStatus
+	st.Signal, st.CoreDumped = exitSignal(cmd.ProcessState)
+	return st
+}
+
+func (s macSandbox) EnforcesLimits() bool {
+	return false
+}
+
 // genericSandbox provides a limited sandbox that works on any OS.
 //
 // Filesystem and network access restrictions are not supported.
 type genericSandbox struct{}
 
 func (s genericSandbox) Command(ctx context.Context, config *Config) *exec.Cmd {
-	cmd := exec.CommandContext(ctx, config.Cmd[0], config.Cmd[1:]...)
+	cmd := killTreeOnCancel(exec.CommandContext(ctx, config.Cmd[0], config.Cmd[1:]...))
 	cmd.Dir = config.Cwd
 
 	for k, v := range config.Env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// config.Mounts and config.AllowNetwork intentionally ignored.
+	// config.Mounts and config.AllowNetwork intentionally ignored,
+	// config.Limits is not enforced, see EnforcesLimits.
 
 	return cmd
 }
+
+func (s genericSandbox) Exit(cmd *exec.Cmd) ExitStatus {
+	var st ExitStatus
+	st.Signal, st.CoreDumped = exitSignal(cmd.ProcessState)
+	return st
+}
+
+func (s genericSandbox) EnforcesLimits() bool {
+	return false
+}
+
+// exitSignal returns the signal that terminated the process, or 0 if it exited
+// normally, and whether it dumped core.
+func exitSignal(state *os.ProcessState) (syscall.Signal, bool) {
+	if state == nil {
+		return 0, false
+	}
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return 0, false
+	}
+	return ws.Signal(), ws.CoreDump()
+}
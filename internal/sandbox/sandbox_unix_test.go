@@ -0,0 +1,121 @@
+// Copyright 2023 The Shac Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExit(t *testing.T) {
+	t.Parallel()
+	sb, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mounts []Mount
+	for _, p := range []string{"/bin", "/lib", "/lib64", "/usr"} {
+		if _, err = os.Stat(p); err == nil {
+			mounts = append(mounts, Mount{Path: p})
+		}
+	}
+	data := []struct {
+		name string
+		sb   Sandbox
+		// direct runs the script without going through the sandbox, to
+		// simulate its exit status.
+		direct bool
+		// log is the simulated nsjail log, when direct is true.
+		log    string
+		script string
+		want   syscall.Signal
+	}{
+		{"killed", sb, false, "", "kill -TERM $$", syscall.SIGTERM},
+		{"failure", sb, false, "", "exit 1", 0},
+		{"nsjail killed", nsjailSandbox{}, true, "", "kill -TERM $$", syscall.SIGTERM},
+		// nsjail exits with 128+N when the jailed process is killed, and logs
+		// it.
+		{"nsjail exit", nsjailSandbox{}, true, nsjailLogSIGTERM, "exit 143", syscall.SIGTERM},
+		// A process can exit with 128+N by itself.
+		{"nsjail exit without log", nsjailSandbox{}, true, "", "exit 143", 0},
+		{"nsjail exit 255", nsjailSandbox{}, true, nsjailLogExit255, "exit 255", 0},
+		{"nsjail failure", nsjailSandbox{}, true, "", "exit 1", 0},
+	}
+	for i := range data {
+		t.Run(data[i].name, func(t *testing.T) {
+			t.Parallel()
+			cmd := exec.Command("/bin/sh", "-c", data[i].script)
+			if !data[i].direct {
+				cmd = data[i].sb.Command(context.Background(), &Config{Cmd: cmd.Args, Cwd: "/", Mounts: mounts})
+			} else if data[i].log != "" {
+				cmd.ExtraFiles = []*os.File{writeLog(t, data[i].log)}
+			}
+			err := cmd.Run()
+			if _, ok := errors.AsType[*exec.ExitError](err); !ok {
+				t.Fatalf("expected an exit error, got %v", err)
+			}
+			if got := data[i].sb.Exit(cmd).Signal; got != data[i].want {
+				t.Fatalf("got %v, want %v", got, data[i].want)
+			}
+		})
+	}
+}
+
+func TestParseNsjailLog(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	log := "[I][2026-01-02T03:04:05+0000] Mode: STANDALONE_ONCE\n" +
+		"[W][2026-01-02T03:04:05+0000][42] initCloneNs():123 Couldn't do something\n" +
+		"[E][2026-01-02T03:04:05+0000][42] initCloneNs():456 Couldn't do something else\n" +
+		nsjailLogSIGTERM +
+		"[F][2026-01-02T03:04:05+0000][42] main():789 Couldn't continue\n"
+	if got := parseNsjailLog([]byte(log), &b); got != syscall.SIGTERM {
+		t.Fatalf("got %v", got)
+	}
+	// Only the fatal errors are forwarded, like with --quiet.
+	want := "[F][2026-01-02T03:04:05+0000][42] main():789 Couldn't continue\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if got := parseNsjailLog([]byte(nsjailLogExit255), &b); got != 0 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+const (
+	nsjailLogSIGTERM = "[I][2026-01-02T03:04:05+0000] pid=42 ([STANDALONE MODE]) terminated with signal: SIGTERM (15), (PIDs left: 0)\n"
+	nsjailLogExit255 = "[I][2026-01-02T03:04:05+0000] pid=42 ([STANDALONE MODE]) exited with status: 255, (PIDs left: 0)\n"
+)
+
+// writeLog returns a file containing log, like the log written by nsjail.
+func writeLog(t *testing.T, log string) *os.File {
+	f, err := os.CreateTemp(t.TempDir(), "nsjail*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(log); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
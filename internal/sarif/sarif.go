@@ -30,3 +30,7 @@ const (
 	// A minor problem or an opportunity to improve the code was found.
 	Note = "note"
 )
+
+// InSource is the kind of a suppression requested by a comment in the source
+// code.
+const InSource = "inSource"